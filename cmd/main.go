@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -13,9 +17,15 @@ import (
 	"github.com/lmittmann/tint"
 	slogmulti "github.com/samber/slog-multi"
 
+	"kurohelper/internal/autocomplete"
 	"kurohelper/internal/bot"
 	"kurohelper/internal/cache"
+	"kurohelper/internal/cid"
+	"kurohelper/internal/health"
+	"kurohelper/internal/metrics"
+	"kurohelper/internal/provider"
 	"kurohelper/internal/store"
+	"kurohelper/internal/userdata"
 	"kurohelper/internal/utils"
 	service "kurohelperservice"
 	"kurohelperservice/db"
@@ -24,6 +34,9 @@ import (
 	"kurohelperservice/provider/ymgal"
 )
 
+// 無色彩log檔，關閉時需要關閉
+var logFile *os.File
+
 // 專案前置初始化
 func init() {
 	// load .env
@@ -44,7 +57,7 @@ func init() {
 	}
 
 	// make a no color log
-	logFile, err := os.OpenFile(filepath.Join(logDir, "kurohelper-nocolor.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	logFile, err = os.OpenFile(filepath.Join(logDir, "kurohelper-nocolor.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
 	if err != nil {
 		panic(err)
 	}
@@ -75,8 +88,12 @@ func init() {
 func main() {
 	// ----初始化專案作業開始----
 
+	// 監控與健康檢查，初始化期間 /readyz 會回傳503
+	httpServer := httpServerInit(os.Getenv("HTTP_ADDR"))
+
 	// 資料庫初始化
 	dbInit()
+	health.Register("database", health.Database(db.Dbs))
 	// 初始化白名單存成快取
 	store.InitAllowList()
 	// init ZhtwToJp var
@@ -85,32 +102,83 @@ func main() {
 	store.InitUser()
 	// 初始化快取時間
 	cache.InitCacheLostTime(utils.GetEnvInt("COMMAND_CACHE_LOST_HOURS", 4))
-	// Seiya初始化
-	seiya.InitSeiyaCorrespond()
-	err := seiya.Init()
+	cache.InitNegativeCacheTime(utils.GetEnvInt("COMMAND_NEGATIVE_CACHE_MINUTES", 5))
+	cache.InitStaleTime(utils.GetEnvInt("COMMAND_CACHE_STALE_HOURS", 24))
+	// 快取容量上限與硬碟快照
+	cache.InitStorage(cache.StorageConfig{
+		MaxEntries:       utils.GetEnvInt("CACHE_MAX_ENTRIES", 0),
+		PersistDir:       os.Getenv("CACHE_PERSIST_DIR"),
+		SnapshotInterval: time.Duration(utils.GetEnvInt("CACHE_SNAPSHOT_INTERVAL_MINUTES", 10)) * time.Minute,
+	})
+	// 還原Autocomplete上次從查詢結果學到的名稱
+	autocomplete.RestoreLearned()
+	// CustomID簽章金鑰與舊版CID相容性
+	err := cid.Init(cid.Config{
+		Secret:        os.Getenv("CID_SECRET"),
+		RequireSecret: os.Getenv("CACHE_PERSIST_DIR") != "",
+		AcceptLegacy:  !strings.EqualFold(os.Getenv("CID_ACCEPT_LEGACY"), "false"),
+	})
 	if err != nil {
 		slog.Error(err.Error())
 		os.Exit(1)
 	}
+	// 離線模式：查詢指令改用本地假資料，不連線任何上游服務
+	offline := strings.EqualFold(os.Getenv("OFFLINE_MODE"), "true")
+	if offline {
+		p, err := provider.LoadFixtures(os.Getenv("OFFLINE_FIXTURE_DIR"))
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		bot.UseProviders(p)
+		slog.Warn("KuroHelper is running in offline mode", "fixtureDir", os.Getenv("OFFLINE_FIXTURE_DIR"))
+	}
+	// Seiya初始化
+	seiya.InitSeiyaCorrespond()
+	if !offline {
+		err := seiya.Init()
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+	}
 	// erogs init
 	erogs.InitRateLimit(time.Duration(utils.GetEnvInt("EROGS_RATE_LIMIT_RESET_TIME", 10)))
-	erogs.InitErogsGameAutoComplete(os.Getenv("EROGS_GAME_AUTOCOMPLETE_FILE"))
-	erogs.InitErogsBrandAutoComplete(os.Getenv("EROGS_BRAND_AUTOCOMPLETE_FILE"))
-	erogs.InitErogsMusicAutoComplete(os.Getenv("EROGS_MUSIC_AUTOCOMPLETE_FILE"))
+	autocompleteDicts := autocomplete.ErogsDictionaries(
+		os.Getenv("EROGS_GAME_AUTOCOMPLETE_FILE"),
+		os.Getenv("EROGS_BRAND_AUTOCOMPLETE_FILE"),
+		os.Getenv("EROGS_MUSIC_AUTOCOMPLETE_FILE"),
+	)
+	autocompleteDicts = append(autocompleteDicts, autocomplete.NameListDictionaries(
+		os.Getenv("CREATOR_AUTOCOMPLETE_FILE"),
+		os.Getenv("SINGER_AUTOCOMPLETE_FILE"),
+		os.Getenv("CHARACTER_AUTOCOMPLETE_FILE"),
+	)...)
+	for _, d := range autocompleteDicts {
+		d.Reload()
+	}
+	health.Register("erogs_autocomplete", health.ErogsAutocomplete(
+		os.Getenv("EROGS_GAME_AUTOCOMPLETE_FILE") != "",
+		os.Getenv("EROGS_BRAND_AUTOCOMPLETE_FILE") != "",
+		os.Getenv("EROGS_MUSIC_AUTOCOMPLETE_FILE") != "",
+	))
 	// ymgal init
-	if strings.EqualFold(os.Getenv("INIT_YMGAL"), "true") {
-		err = ymgalInit()
+	if !offline && strings.EqualFold(os.Getenv("INIT_YMGAL"), "true") {
+		err := ymgalInit()
 		if err != nil {
 			slog.Error(err.Error())
 			os.Exit(1)
 		}
+		health.Register("ymgal_token", health.YmgalToken(10*time.Minute))
 	}
 
 	// ----初始化專案作業結束----
 
 	// 掛載自動清除快取job
 	stopChan := make(chan struct{})
-	go cache.CleanCacheJob(time.Duration(utils.GetEnvInt("COMMAND_CLEAN_CACHE_JOB_HOURS", 12)), stopChan)
+	go cache.CleanCacheJob(time.Duration(utils.GetEnvInt("COMMAND_CLEAN_CACHE_JOB_HOURS", 12))*time.Hour, stopChan)
+	// 掛載Autocomplete檔案監看job
+	go autocomplete.Watch(autocompleteDicts, time.Duration(utils.GetEnvInt("AUTOCOMPLETE_RELOAD_INTERVAL", 60))*time.Second, stopChan)
 
 	token := os.Getenv("BOT_TOKEN")
 	kuroHelper, err := discordgo.New("Bot " + token)
@@ -121,8 +189,32 @@ func main() {
 
 	kuroHelper.Identify.Intents = discordgo.IntentsGuilds | discordgo.IntentsGuildMembers | discordgo.IntentsGuildMessages | discordgo.IntentsMessageContent
 
+	// 監控指標
+	metrics.NewGaugeFunc(
+		"kurohelper_gateway_heartbeat_latency_seconds",
+		"Latency between the last gateway heartbeat and its ACK.",
+		func() float64 { return kuroHelper.HeartbeatLatency().Seconds() },
+	)
+	health.Register("gateway", health.Gateway(kuroHelper))
+
 	slog.Info("KuroHelper is now running. Press CTRL+C to exit.")
 
+	// 指令worker pool與併發限制
+	bot.InitDispatcher(bot.DispatcherConfig{
+		Workers:         utils.GetEnvInt("DISPATCH_WORKERS", 32),
+		PriorityWorkers: utils.GetEnvInt("DISPATCH_PRIORITY_WORKERS", 8),
+		QueueSize:       utils.GetEnvInt("DISPATCH_QUEUE_SIZE", 256),
+		UserInFlight:    utils.GetEnvInt("DISPATCH_USER_INFLIGHT", 3),
+		GuildInFlight:   utils.GetEnvInt("DISPATCH_GUILD_INFLIGHT", 20),
+	})
+
+	// 指令限流
+	bot.InitRateLimit(bot.RateLimitConfig{
+		Overrides:       os.Getenv("COMMAND_RATE_LIMITS"),
+		GuildFactor:     utils.GetEnvInt("COMMAND_RATE_LIMIT_GUILD_FACTOR", 10),
+		AllowListFactor: utils.GetEnvInt("COMMAND_RATE_LIMIT_ALLOWLIST_FACTOR", 3),
+	})
+
 	kuroHelper.AddHandler(bot.Ready)
 	kuroHelper.AddHandler(bot.OnInteraction)
 
@@ -131,16 +223,77 @@ func main() {
 		slog.Error(err.Error())
 		os.Exit(1)
 	}
+	health.MarkStarted()
 
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	interruptSignal := <-c
-	slog.Debug(interruptSignal.String())
+	// 再按一次CTRL+C可以直接結束，不等待
+	signal.Stop(c)
+	slog.Info("KuroHelper is shutting down...", "signal", interruptSignal.String())
+
+	// 停止導流並等待執行中的指令(例如資料庫交易)完成，期間新的Interaction會收到維護訊息
+	health.MarkStopping()
+	drainTimeout := time.Duration(utils.GetEnvInt("SHUTDOWN_DRAIN_SECONDS", 30)) * time.Second
+	if remaining := bot.Drain(drainTimeout); remaining > 0 {
+		slog.Warn("drain timeout exceeded, cancelling in-flight interactions", "remaining", remaining, "timeout", drainTimeout)
+	}
 
 	// 關閉 jobs
 	close(stopChan)
 
+	// 保存快取，重新部署後舊訊息上的按鈕仍可使用
+	if err := cache.SaveAll(); err != nil {
+		slog.Warn(err.Error())
+	}
+
+	if httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := httpServer.Shutdown(ctx); err != nil {
+			slog.Warn(err.Error())
+		}
+		cancel()
+	}
+
 	kuroHelper.Close() // websocket disconnect
+
+	// 關閉資料庫連線池
+	if sqlDB, err := db.Dbs.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Warn(err.Error())
+		}
+	}
+
+	slog.Info("KuroHelper has been shut down")
+	logFile.Close()
+}
+
+// 監控與健康檢查用HTTP服務，addr 為空時不啟動
+//   - /metrics: Prometheus指標
+//   - /healthz: 存活檢查
+//   - /readyz: 就緒檢查(資料庫、gateway、Autocomplete索引、月幕token)
+func httpServerInit(addr string) *http.Server {
+	if addr == "" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.Handle("GET /healthz", health.LivenessHandler())
+	mux.Handle("GET /readyz", health.ReadinessHandler())
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("http server stopped", "error", err)
+		}
+	}()
+	slog.Info("http server is listening", "addr", addr)
+	return server
 }
 
 // db init
@@ -161,6 +314,10 @@ func dbInit() {
 		slog.Error(err.Error())
 		os.Exit(1)
 	}
+	if err := userdata.Migration(db.Dbs); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
 }
 
 // ymgal init
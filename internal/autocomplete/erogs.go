@@ -0,0 +1,48 @@
+package autocomplete
+
+import (
+	"slices"
+	"sync"
+
+	"kurohelperservice/provider/erogs"
+)
+
+// 批評空間的名稱清單，沒有設定檔案的清單會被略過
+//
+// 檔案格式由 kurohelperservice 決定，所以直接使用它的載入函式
+func ErogsDictionaries(gameFile, brandFile, musicFile string) []Dictionary {
+	var dicts []Dictionary
+	if gameFile != "" {
+		dicts = append(dicts, Dictionary{Source: Games, Path: gameFile, Load: erogsLoader(erogs.InitErogsGameAutoComplete, &erogs.GamesName, &erogs.GameInvertedIndex)})
+	}
+	if brandFile != "" {
+		dicts = append(dicts, Dictionary{Source: Brands, Path: brandFile, Load: erogsLoader(erogs.InitErogsBrandAutoComplete, &erogs.BrandsName, &erogs.BrandInvertedIndex)})
+	}
+	if musicFile != "" {
+		dicts = append(dicts, Dictionary{Source: Musics, Path: musicFile, Load: erogsLoader(erogs.InitErogsMusicAutoComplete, &erogs.MusicsName, &erogs.MusicInvertedIndex)})
+	}
+	return dicts
+}
+
+// erogs 的載入函式會寫入它的全域變數，同一時間只讓一個載入執行
+var erogsLoadMu sync.Mutex
+
+// 載入函式會寫入 erogs 的全域變數，這裡複製一份名稱後立刻清空，
+// 資料只留在 Source 原子替換的索引上，其他地方不會讀到載入到一半的內容
+func erogsLoader(init func(string), names *[]string, invertedIndex *map[rune][]int) func(string) ([]string, error) {
+	return func(path string) ([]string, error) {
+		erogsLoadMu.Lock()
+		defer erogsLoadMu.Unlock()
+		// 載入函式panic時也要清空
+		defer func() {
+			*names, *invertedIndex = nil, nil
+		}()
+
+		init(path)
+		loaded := slices.Clone(*names)
+		if len(loaded) == 0 {
+			return nil, ErrEmptyDictionary
+		}
+		return loaded, nil
+	}
+}
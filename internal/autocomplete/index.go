@@ -0,0 +1,276 @@
+// autocomplete 名稱清單的模糊搜尋索引
+//
+// 名稱與查詢都會先正規化(見 Normalize)，含有假名的名稱會多一組羅馬拼音的key；
+// 以bigram倒排索引找出候選，再依照 完全相同 > 開頭相同 > 包含 > 容錯(編輯距離) 排序，
+// 同一級距內依照熱門度(被選用的次數)、名稱長度、來源順序排序
+package autocomplete
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// 查詢至少需要的字數(正規化後)
+const MinQueryLength = 2
+
+// 容錯比對最多檢查的候選數量
+const maxFuzzyCandidates = 500
+
+type entry struct {
+	name string
+	// 正規化後的key，第二個(如果有)是羅馬拼音
+	keys []string
+}
+
+// 單一名稱清單的索引，建立後只讀，可以同時查詢
+type Index struct {
+	entries []entry
+	// bigram -> entry id(遞增、不重複)
+	grams  map[string][]int32
+	byName map[string]int32
+	// 熱門度，依照entry id
+	hits []atomic.Int64
+}
+
+// 建立索引，重複的名稱只保留第一個
+func NewIndex(names []string) *Index {
+	idx := &Index{
+		entries: make([]entry, 0, len(names)),
+		grams:   make(map[string][]int32),
+		byName:  make(map[string]int32, len(names)),
+	}
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		if _, ok := idx.byName[name]; ok {
+			continue
+		}
+		id := int32(len(idx.entries))
+		e := entry{name: name}
+		if key := Normalize(name); key != "" {
+			e.keys = append(e.keys, key)
+			if rm := romaji(key); rm != "" && rm != key {
+				e.keys = append(e.keys, rm)
+			}
+		}
+		for _, key := range e.keys {
+			for _, g := range bigrams(key) {
+				posting := idx.grams[g]
+				if len(posting) > 0 && posting[len(posting)-1] == id {
+					continue
+				}
+				idx.grams[g] = append(posting, id)
+			}
+		}
+		idx.entries = append(idx.entries, e)
+		idx.byName[name] = id
+	}
+	idx.hits = make([]atomic.Int64, len(idx.entries))
+	return idx
+}
+
+// 名稱數量
+func (idx *Index) Len() int {
+	if idx == nil {
+		return 0
+	}
+	return len(idx.entries)
+}
+
+// 增加名稱的熱門度(使用者實際用這個名稱查詢時呼叫)，不在索引內的名稱會被忽略
+func (idx *Index) Hit(name string) {
+	if idx == nil {
+		return
+	}
+	if id, ok := idx.byName[name]; ok {
+		idx.hits[id].Add(1)
+	}
+}
+
+// 比對級距，越小越前面
+const (
+	tierExact = iota
+	tierPrefix
+	tierContains
+	tierFuzzy
+)
+
+type match struct {
+	id       int32
+	tier     int
+	distance int
+	pos      int
+}
+
+// 查詢最相關的 limit 筆名稱
+func (idx *Index) Search(query string, limit int) []string {
+	if idx == nil || limit <= 0 {
+		return nil
+	}
+	q := Normalize(query)
+	qRunes := []rune(q)
+	if len(qRunes) < MinQueryLength {
+		return nil
+	}
+
+	grams := bigrams(q)
+	counts := make(map[int32]int)
+	for _, g := range grams {
+		for _, id := range idx.grams[g] {
+			counts[id]++
+		}
+	}
+
+	maxEdits := allowedEdits(len(qRunes))
+	// 每個錯字最多影響兩個bigram
+	need := max(len(grams)-2*maxEdits, 1)
+
+	var matches []match
+	var fuzzy []int32
+	for id, c := range counts {
+		if c < need {
+			continue
+		}
+		if c == len(grams) {
+			if m, ok := idx.matchExact(id, q); ok {
+				matches = append(matches, m)
+				continue
+			}
+		}
+		if maxEdits > 0 {
+			fuzzy = append(fuzzy, id)
+		}
+	}
+
+	// 容錯比對比較花時間，只檢查bigram命中最多的候選
+	if len(fuzzy) > maxFuzzyCandidates {
+		sort.Slice(fuzzy, func(a, b int) bool {
+			if counts[fuzzy[a]] != counts[fuzzy[b]] {
+				return counts[fuzzy[a]] > counts[fuzzy[b]]
+			}
+			return fuzzy[a] < fuzzy[b]
+		})
+		fuzzy = fuzzy[:maxFuzzyCandidates]
+	}
+	for _, id := range fuzzy {
+		best := -1
+		for _, key := range idx.entries[id].keys {
+			d := substringDistance(qRunes, []rune(key))
+			if best < 0 || d < best {
+				best = d
+			}
+		}
+		if best >= 0 && best <= maxEdits {
+			matches = append(matches, match{id: id, tier: tierFuzzy, distance: best})
+		}
+	}
+
+	sort.Slice(matches, func(a, b int) bool {
+		ma, mb := matches[a], matches[b]
+		if ma.tier != mb.tier {
+			return ma.tier < mb.tier
+		}
+		if ma.distance != mb.distance {
+			return ma.distance < mb.distance
+		}
+		if ha, hb := idx.hits[ma.id].Load(), idx.hits[mb.id].Load(); ha != hb {
+			return ha > hb
+		}
+		if ma.pos != mb.pos {
+			return ma.pos < mb.pos
+		}
+		la, lb := len(idx.entries[ma.id].name), len(idx.entries[mb.id].name)
+		if la != lb {
+			return la < lb
+		}
+		return ma.id < mb.id
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	out := make([]string, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, idx.entries[m.id].name)
+	}
+	return out
+}
+
+// 完全相同、開頭相同或包含
+func (idx *Index) matchExact(id int32, q string) (match, bool) {
+	best := match{id: id, tier: tierFuzzy}
+	found := false
+	for _, key := range idx.entries[id].keys {
+		var m match
+		switch pos := strings.Index(key, q); {
+		case pos < 0:
+			continue
+		case key == q:
+			m = match{id: id, tier: tierExact}
+		case pos == 0:
+			m = match{id: id, tier: tierPrefix}
+		default:
+			m = match{id: id, tier: tierContains, pos: pos}
+		}
+		if !found || m.tier < best.tier || (m.tier == best.tier && m.pos < best.pos) {
+			best = m
+			found = true
+		}
+	}
+	return best, found
+}
+
+// 依照查詢長度允許的錯字數
+func allowedEdits(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// 以rune為單位切bigram(不重複)
+func bigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(runes))
+	out := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		g := string(runes[i : i+2])
+		if _, ok := seen[g]; ok {
+			continue
+		}
+		seen[g] = struct{}{}
+		out = append(out, g)
+	}
+	return out
+}
+
+// q 與 text 任一子字串的最小編輯距離(Sellers演算法)
+func substringDistance(q, text []rune) int {
+	prev := make([]int, len(text)+1)
+	cur := make([]int, len(text)+1)
+	for i := 1; i <= len(q); i++ {
+		cur[0] = i
+		for j := 1; j <= len(text); j++ {
+			cost := 1
+			if q[i-1] == text[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j-1]+cost, prev[j]+1, cur[j-1]+1)
+		}
+		prev, cur = cur, prev
+	}
+	best := len(q)
+	for _, d := range prev {
+		best = min(best, d)
+	}
+	return best
+}
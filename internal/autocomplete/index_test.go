@@ -0,0 +1,188 @@
+package autocomplete
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllowedEdits(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{n: 2, want: 0},
+		{n: 3, want: 0},
+		{n: 4, want: 1},
+		{n: 7, want: 1},
+		{n: 8, want: 2},
+		{n: 20, want: 2},
+	}
+	for _, tt := range tests {
+		if got := allowedEdits(tt.n); got != tt.want {
+			t.Errorf("allowedEdits(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		query string
+		limit int
+		want  []string
+	}{
+		{
+			name:  "tier order exact > prefix > contains > fuzzy",
+			names: []string{"Sumer Pockets", "Re:Summer Pockets", "Summer Pockets REFLECTION BLUE", "Summer Pockets"},
+			query: "summer pockets",
+			want:  []string{"Summer Pockets", "Summer Pockets REFLECTION BLUE", "Re:Summer Pockets", "Sumer Pockets"},
+		},
+		{
+			name:  "mid title match",
+			names: []string{"Rewrite", "Rewrite Harvest festa!"},
+			query: "harvest",
+			want:  []string{"Rewrite Harvest festa!"},
+		},
+		{
+			name:  "contains sorted by position",
+			names: []string{"Little Busters! Ecstasy", "Kud Wafter Busters"},
+			query: "busters",
+			want:  []string{"Little Busters! Ecstasy", "Kud Wafter Busters"},
+		},
+		{
+			name:  "full width query",
+			names: []string{"CLANNAD"},
+			query: "ｃｌａｎｎａｄ",
+			want:  []string{"CLANNAD"},
+		},
+		{
+			name:  "katakana name with hiragana query",
+			names: []string{"カノン"},
+			query: "かのん",
+			want:  []string{"カノン"},
+		},
+		{
+			name:  "romaji query",
+			names: []string{"かのん", "AIR"},
+			query: "kanon",
+			want:  []string{"かのん"},
+		},
+		{
+			name:  "traditional query matches simplified name",
+			names: []string{"恋爱选择"},
+			query: "戀愛",
+			want:  []string{"恋爱选择"},
+		},
+		{
+			name:  "below MinQueryLength",
+			names: []string{"Summer Pockets"},
+			query: "s",
+			want:  nil,
+		},
+		{
+			name:  "only symbols",
+			names: []string{"Summer Pockets"},
+			query: "!?",
+			want:  nil,
+		},
+		{
+			name:  "MinQueryLength",
+			names: []string{"Summer Pockets"},
+			query: "su",
+			want:  []string{"Summer Pockets"},
+		},
+		{
+			name:  "no typo allowed under 4 runes",
+			names: []string{"Kanon"},
+			query: "kxn",
+			want:  nil,
+		},
+		{
+			name:  "one typo at 4 runes",
+			names: []string{"Kanon"},
+			query: "kann",
+			want:  []string{"Kanon"},
+		},
+		{
+			name:  "one typo under 8 runes",
+			names: []string{"CLANNAD"},
+			query: "clanad",
+			want:  []string{"CLANNAD"},
+		},
+		{
+			name:  "two typos rejected under 8 runes",
+			names: []string{"CLANNAD"},
+			query: "clnad",
+			want:  nil,
+		},
+		{
+			name:  "two typos at 8 runes or more",
+			names: []string{"Little Busters"},
+			query: "litlebustrs",
+			want:  []string{"Little Busters"},
+		},
+		{
+			name:  "three typos rejected",
+			names: []string{"Little Busters"},
+			query: "litlbustrs",
+			want:  nil,
+		},
+		{
+			name:  "limit",
+			names: []string{"Kanon A", "Kanon B", "Kanon C"},
+			query: "kanon",
+			limit: 2,
+			want:  []string{"Kanon A", "Kanon B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := tt.limit
+			if limit == 0 {
+				limit = 25
+			}
+			got := NewIndex(tt.names).Search(tt.query, limit)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// 同一級距內熱門度高的排前面，但不會越過更好的級距
+func TestIndexHit(t *testing.T) {
+	idx := NewIndex([]string{"Kanon", "Kanon A", "Kanon B"})
+
+	if got, want := idx.Search("kanon", 10), []string{"Kanon", "Kanon A", "Kanon B"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("before Hit: %q, want %q", got, want)
+	}
+
+	idx.Hit("Kanon B")
+	idx.Hit("Kanon B")
+	idx.Hit("Kanon A")
+	// 不在索引內的名稱會被忽略
+	idx.Hit("AIR")
+
+	if got, want := idx.Search("kanon", 10), []string{"Kanon", "Kanon B", "Kanon A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after Hit: %q, want %q", got, want)
+	}
+
+	// nil索引不會panic
+	var empty *Index
+	empty.Hit("Kanon")
+	if empty.Search("kanon", 10) != nil || empty.Len() != 0 {
+		t.Error("nil index should be empty")
+	}
+}
+
+func TestNewIndexSkipsDuplicates(t *testing.T) {
+	idx := NewIndex([]string{"Kanon", "", "  ", "Kanon", "AIR"})
+	if idx.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", idx.Len())
+	}
+}
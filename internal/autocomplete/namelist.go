@@ -0,0 +1,72 @@
+package autocomplete
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 批評空間沒有提供名稱清單的來源(創作者、歌手、角色)，沒有設定檔案的清單會被略過
+//
+// 沒有設定時這些來源只會從查詢結果學習名稱
+func NameListDictionaries(creatorFile, singerFile, characterFile string) []Dictionary {
+	var dicts []Dictionary
+	if creatorFile != "" {
+		dicts = append(dicts, Dictionary{Source: Creators, Path: creatorFile, Load: LoadNameList})
+	}
+	if singerFile != "" {
+		dicts = append(dicts, Dictionary{Source: Singers, Path: singerFile, Load: LoadNameList})
+	}
+	if characterFile != "" {
+		dicts = append(dicts, Dictionary{Source: Characters, Path: characterFile, Load: LoadNameList})
+	}
+	return dicts
+}
+
+// 讀取名稱清單檔案，格式為JSON字串陣列或一行一個名稱(#開頭為註解)
+//
+// 空白的名稱會略過，重複的名稱只保留第一個
+func LoadNameList(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []string
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("autocomplete: parse %s: %w", path, err)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(strings.TrimSpace(line), "#") {
+				continue
+			}
+			raw = append(raw, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("autocomplete: read %s: %w", path, err)
+		}
+	}
+
+	names := make([]string, 0, len(raw))
+	seen := make(map[string]struct{}, len(raw))
+	for _, name := range raw {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	return names, nil
+}
@@ -0,0 +1,56 @@
+package autocomplete
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadNameList(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{name: "json", content: `["麻枝准", " 樋上いたる ", "", "麻枝准"]`, want: []string{"麻枝准", "樋上いたる"}},
+		{name: "lines", content: "# 創作者\n麻枝准\r\n\n  樋上いたる\n麻枝准\n", want: []string{"麻枝准", "樋上いたる"}},
+		{name: "empty", content: "", want: []string{}},
+		{name: "truncated json", content: `["麻枝准", "樋上`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "names.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadNameList(path)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), path) {
+					t.Errorf("LoadNameList() error = %v, want parse error with path", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadNameList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// 沒有設定檔案的來源不會產生 Dictionary
+func TestNameListDictionaries(t *testing.T) {
+	dicts := NameListDictionaries("creators.txt", "", "characters.json")
+	var got []string
+	for _, d := range dicts {
+		got = append(got, d.Source.Name()+":"+d.Path)
+	}
+	if want := []string{"creator:creators.txt", "character:characters.json"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dictionaries = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+package autocomplete
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/siongui/gojianfan"
+	"golang.org/x/text/unicode/norm"
+)
+
+// 正規化名稱與查詢字串
+//
+//   - NFKC：全形英數轉半形、半形片假名轉全形(含濁音)
+//   - 轉小寫
+//   - 繁體轉簡體(gojianfan)
+//   - 片假名轉平假名
+//   - 移除空白與符號，只留下文字與數字
+func Normalize(s string) string {
+	s = norm.NFKC.String(s)
+	s = strings.ToLower(s)
+	s = gojianfan.T2S(s)
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		switch {
+		case r >= 'ァ' && r <= 'ヶ':
+			b.WriteRune(r - 0x60)
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// 平假名轉羅馬拼音(Hepburn)，其他字元原樣保留
+//
+// 長音符號直接省略，促音重複下一個子音；name沒有假名時回傳空字串
+func romaji(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	hasKana := false
+	for idx := 0; idx < len(runes); idx++ {
+		r := runes[idx]
+		if r == 'ー' {
+			hasKana = true
+			continue
+		}
+		if r == 'っ' {
+			hasKana = true
+			if idx+1 < len(runes) {
+				if next := kanaRomaji(runes[idx+1:]); next != "" && next[0] != 'n' {
+					b.WriteByte(next[0])
+				}
+			}
+			continue
+		}
+		if rm := kanaRomaji(runes[idx:]); rm != "" {
+			hasKana = true
+			b.WriteString(rm)
+			if idx+1 < len(runes) {
+				if _, ok := youon[string(runes[idx:idx+2])]; ok {
+					idx++
+				}
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if !hasKana {
+		return ""
+	}
+	return b.String()
+}
+
+// 取出開頭的假名(含拗音)對應的羅馬拼音
+func kanaRomaji(runes []rune) string {
+	if len(runes) >= 2 {
+		if rm, ok := youon[string(runes[:2])]; ok {
+			return rm
+		}
+	}
+	return gojuon[runes[0]]
+}
+
+var gojuon = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+	'ゃ': "ya", 'ゅ': "yu", 'ょ': "yo", 'ゎ': "wa", 'ゔ': "vu",
+}
+
+var youon = map[string]string{
+	"きゃ": "kya", "きゅ": "kyu", "きょ": "kyo",
+	"しゃ": "sha", "しゅ": "shu", "しょ": "sho", "しぇ": "she",
+	"ちゃ": "cha", "ちゅ": "chu", "ちょ": "cho", "ちぇ": "che",
+	"にゃ": "nya", "にゅ": "nyu", "にょ": "nyo",
+	"ひゃ": "hya", "ひゅ": "hyu", "ひょ": "hyo",
+	"みゃ": "mya", "みゅ": "myu", "みょ": "myo",
+	"りゃ": "rya", "りゅ": "ryu", "りょ": "ryo",
+	"ぎゃ": "gya", "ぎゅ": "gyu", "ぎょ": "gyo",
+	"じゃ": "ja", "じゅ": "ju", "じょ": "jo", "じぇ": "je",
+	"びゃ": "bya", "びゅ": "byu", "びょ": "byo",
+	"ぴゃ": "pya", "ぴゅ": "pyu", "ぴょ": "pyo",
+	"ふぁ": "fa", "ふぃ": "fi", "ふぇ": "fe", "ふぉ": "fo",
+	"てぃ": "ti", "でぃ": "di", "とぅ": "tu", "どぅ": "du",
+	"うぃ": "wi", "うぇ": "we", "うぉ": "wo",
+	"ゔぁ": "va", "ゔぃ": "vi", "ゔぇ": "ve", "ゔぉ": "vo",
+}
@@ -0,0 +1,50 @@
+package autocomplete
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "full width", in: "ＣＬＡＮＮＡＤ", want: "clannad"},
+		{name: "half width katakana", in: "ｻﾏｰﾎﾟｹｯﾂ", want: "さまーぽけっつ"},
+		{name: "lower case", in: "Summer Pockets", want: "summerpockets"},
+		{name: "traditional to simplified", in: "戀愛", want: "恋爱"},
+		{name: "katakana to hiragana", in: "カタカナ", want: "かたかな"},
+		{name: "symbols and spaces removed", in: "Rewrite+ ～", want: "rewrite"},
+		{name: "numbers kept", in: "AIR 2", want: "air2"},
+		{name: "only symbols", in: "!? ～", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRomaji(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "gojuon", in: "かのん", want: "kanon"},
+		{name: "long vowel mark dropped", in: "さまーぽけっつ", want: "samapokettsu"},
+		{name: "youon", in: "しょうじょ", want: "shoujo"},
+		{name: "sokuon doubles consonant", in: "きっさ", want: "kissa"},
+		{name: "sokuon before n", in: "かっん", want: "kan"},
+		{name: "mixed with latin", in: "airかのん", want: "airkanon"},
+		{name: "no kana", in: "clannad", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := romaji(tt.in); got != tt.want {
+				t.Errorf("romaji(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
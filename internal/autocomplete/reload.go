@@ -0,0 +1,179 @@
+package autocomplete
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 名稱清單檔案與對應的索引
+type Dictionary struct {
+	Source *Source
+	Path   string
+	// 讀取檔案並回傳名稱清單
+	Load func(path string) ([]string, error)
+}
+
+// 重新載入失敗時保留原本的索引
+var ErrEmptyDictionary = errors.New("autocomplete: dictionary has no entries")
+
+// 載入名稱清單並替換索引，檔案有問題時不會替換
+func (d Dictionary) Reload() (err error) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("autocomplete: load %s panicked: %v", d.Path, r)
+		}
+		if err != nil {
+			slog.Error("autocomplete reload failed, keeping current index", "source", d.Source.Name(), "path", d.Path, "entries", d.Source.Len(), "error", err)
+		}
+	}()
+
+	// 檢查過的內容複製一份再載入，檢查完到載入之間檔案又被改寫也不會讀到沒檢查過的內容
+	data, err := os.ReadFile(d.Path)
+	if err != nil {
+		return err
+	}
+	if err := validateData(d.Path, data); err != nil {
+		return err
+	}
+	snapshot, err := writeSnapshot(d.Path, data)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(snapshot)
+
+	names, err := d.Load(snapshot)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return ErrEmptyDictionary
+	}
+
+	d.Source.Store(names)
+	slog.Info("autocomplete reloaded", "source", d.Source.Name(), "path", d.Path, "entries", d.Source.Len(), "elapsed", time.Since(start))
+	return nil
+}
+
+// 載入前先檢查內容，JSON格式時會回報解析錯誤的位置(寫到一半的檔案通常會在這裡被擋下)
+func validateData(path string, data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return ErrEmptyDictionary
+	}
+	if data[0] == '[' || data[0] == '{' {
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("autocomplete: parse %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// 把內容寫進暫存檔，保留原本的副檔名讓載入函式判斷格式
+func writeSnapshot(path string, data []byte) (string, error) {
+	f, err := os.CreateTemp("", "autocomplete-*"+filepath.Ext(path))
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) (fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// 單一檔案的檢查狀態
+type dictWatch struct {
+	dict Dictionary
+	// 目前索引對應的檔案狀態(載入失敗的版本也算，避免一直重試)
+	loaded fileState
+	// 上一次檢查看到、還沒載入的檔案狀態
+	pending fileState
+	statErr bool
+}
+
+func newDictWatch(d Dictionary) *dictWatch {
+	st, _ := statFile(d.Path)
+	return &dictWatch{dict: d, loaded: st, pending: st}
+}
+
+// 檢查一次檔案，連續兩次看到同樣的新狀態才載入，回傳這次是否有載入
+func (w *dictWatch) check() bool {
+	st, err := statFile(w.dict.Path)
+	if err != nil {
+		// 同一個錯誤只記錄一次
+		if !w.statErr {
+			slog.Warn("autocomplete: stat dictionary failed", "source", w.dict.Source.Name(), "path", w.dict.Path, "error", err)
+			w.statErr = true
+		}
+		return false
+	}
+	w.statErr = false
+
+	if st == w.loaded {
+		w.pending = st
+		return false
+	}
+	if st != w.pending {
+		w.pending = st
+		return false
+	}
+	w.loaded = st
+	w.dict.Reload()
+	return true
+}
+
+// 定期檢查名稱清單檔案，內容變動後在背景重建索引
+//
+// 檔案變動後要連續兩次檢查都沒有再變動才會載入，避免讀到寫到一半的檔案；
+// 載入失敗的版本不會重試，直到檔案再次變動
+func Watch(dicts []Dictionary, interval time.Duration, stopChan <-chan struct{}) {
+	if interval <= 0 || len(dicts) == 0 {
+		return
+	}
+	slog.Info("autocomplete watcher 正在啟動...", "interval", interval, "files", len(dicts))
+
+	watches := make([]*dictWatch, len(dicts))
+	for idx, d := range dicts {
+		watches[idx] = newDictWatch(d)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, w := range watches {
+				w.check()
+			}
+		case <-stopChan:
+			slog.Info("autocomplete watcher 正在關閉...")
+			return
+		}
+	}
+}
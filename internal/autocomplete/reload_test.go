@@ -0,0 +1,190 @@
+package autocomplete
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 測試用的名稱清單：JSON字串陣列
+func jsonLoad(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func writeDict(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 載入成功一次後，各種壞掉的檔案都不會替換掉原本的索引
+func TestReloadKeepsIndexOnBadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		remove  bool
+		wantErr error
+	}{
+		{name: "truncated json", content: `["ever17", "rem`},
+		{name: "empty file", content: "", wantErr: ErrEmptyDictionary},
+		{name: "whitespace only", content: " \n", wantErr: ErrEmptyDictionary},
+		{name: "empty list", content: `[]`, wantErr: ErrEmptyDictionary},
+		{name: "missing file", remove: true, wantErr: os.ErrNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "names.json")
+			d := Dictionary{Source: NewSource("test"), Path: path, Load: jsonLoad}
+
+			writeDict(t, path, `["ever17", "remember11"]`)
+			if err := d.Reload(); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.remove {
+				os.Remove(path)
+			} else {
+				writeDict(t, path, tt.content)
+			}
+			err := d.Reload()
+			if err == nil {
+				t.Fatal("Reload() error = nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Reload() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := d.Source.Len(); got != 2 {
+				t.Errorf("Len() = %d, want 2 (index replaced)", got)
+			}
+		})
+	}
+}
+
+// 載入函式讀到的是檢查過的內容，不是檔案目前的內容
+func TestReloadLoadsValidatedContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.json")
+	writeDict(t, path, `["ever17"]`)
+
+	var loadedFrom string
+	d := Dictionary{Source: NewSource("test"), Path: path, Load: func(p string) ([]string, error) {
+		loadedFrom = p
+		// 檢查完之後檔案被改寫成寫到一半的內容
+		writeDict(t, path, `["ever17", "rem`)
+		return jsonLoad(p)
+	}}
+	if err := d.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if d.Source.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", d.Source.Len())
+	}
+	if loadedFrom == path || filepath.Ext(loadedFrom) != ".json" {
+		t.Errorf("loaded from %q, want a .json copy", loadedFrom)
+	}
+	if _, err := os.Stat(loadedFrom); !os.IsNotExist(err) {
+		t.Errorf("snapshot %q was not removed: %v", loadedFrom, err)
+	}
+}
+
+// 檔案變動後要連續兩次檢查看到同樣的狀態才載入
+func TestDictWatchCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.json")
+	writeDict(t, path, `["a"]`)
+	d := Dictionary{Source: NewSource("test"), Path: path, Load: jsonLoad}
+	if err := d.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	w := newDictWatch(d)
+
+	base := time.Now().Add(-time.Hour)
+	touch := func(content string, offset time.Duration) {
+		writeDict(t, path, content)
+		mt := base.Add(offset)
+		if err := os.Chtimes(path, mt, mt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []struct {
+		name       string
+		write      func()
+		wantReload bool
+		wantLen    int
+	}{
+		{name: "unchanged", wantLen: 1},
+		{name: "first write seen", write: func() { touch(`["a", "b`, time.Second) }, wantLen: 1},
+		// 檢查之間mtime又變了，代表還在寫入
+		{name: "mtime changed between checks", write: func() { touch(`["a", "b", "c"`, 2*time.Second) }, wantLen: 1},
+		{name: "mtime changed again", write: func() { touch(`["a", "b", "c"]`, 3*time.Second) }, wantLen: 1},
+		{name: "stable", wantReload: true, wantLen: 3},
+		{name: "no reload after loaded", wantLen: 3},
+		// 寫壞的檔案穩定後會嘗試載入一次，但不會替換索引，也不會一直重試
+		{name: "bad file seen", write: func() { touch(`["x`, 4*time.Second) }, wantLen: 3},
+		{name: "bad file stable", wantReload: true, wantLen: 3},
+		{name: "bad file not retried", wantLen: 3},
+		{name: "fixed file seen", write: func() { touch(`["x", "y"]`, 5*time.Second) }, wantLen: 3},
+		{name: "fixed file stable", wantReload: true, wantLen: 2},
+	}
+	for _, step := range steps {
+		if step.write != nil {
+			step.write()
+		}
+		if got := w.check(); got != step.wantReload {
+			t.Errorf("%s: check() = %v, want %v", step.name, got, step.wantReload)
+		}
+		if got := d.Source.Len(); got != step.wantLen {
+			t.Errorf("%s: Len() = %d, want %d", step.name, got, step.wantLen)
+		}
+	}
+}
+
+// erogs 的全域變數只在載入期間使用，載入後清空
+func TestErogsLoaderClearsGlobals(t *testing.T) {
+	var names []string
+	var index map[rune][]int
+	load := erogsLoader(func(string) {
+		names = []string{"ever17"}
+		index = map[rune][]int{'e': {0}}
+	}, &names, &index)
+
+	got, err := load("unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"ever17"}) {
+		t.Errorf("load() = %v", got)
+	}
+	if names != nil || index != nil {
+		t.Errorf("globals = %v, %v, want cleared", names, index)
+	}
+
+	empty := erogsLoader(func(string) {}, &names, &index)
+	if _, err := empty("unused"); !errors.Is(err, ErrEmptyDictionary) {
+		t.Errorf("empty load error = %v, want ErrEmptyDictionary", err)
+	}
+
+	panics := erogsLoader(func(string) {
+		names = []string{"half"}
+		panic("boom")
+	}, &names, &index)
+	func() {
+		defer func() { recover() }()
+		panics("unused")
+	}()
+	if names != nil {
+		t.Errorf("globals after panic = %v, want cleared", names)
+	}
+}
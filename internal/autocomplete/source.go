@@ -0,0 +1,191 @@
+package autocomplete
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"kurohelper/internal/cache"
+)
+
+// 學習到的名稱最多保留的數量，超過時丟掉最早學到的
+const maxLearned = 20000
+
+// 學習新名稱後最快多久重建一次索引
+const learnRebuildDelay = 30 * time.Second
+
+// 可以整個替換的索引，替換時正在查詢的請求繼續使用舊索引
+//
+// 索引內容為名稱清單檔案(Store)加上從查詢結果學到的名稱(Learn)
+type Source struct {
+	name  string
+	index atomic.Pointer[Index]
+
+	// 確保索引依照順序替換
+	buildMu sync.Mutex
+
+	mu         sync.Mutex
+	loaded     bool
+	base       []string
+	learned    []string
+	learnedSet map[string]struct{}
+	// 有新學到的名稱還沒進索引
+	dirty      bool
+	rebuilding bool
+	lastBuild  time.Time
+}
+
+// 批評空間的名稱清單(main在啟動時由 ErogsDictionaries 載入)
+var (
+	Games  = NewSource("game")
+	Brands = NewSource("brand")
+	Musics = NewSource("music")
+)
+
+// 名稱清單檔案為選用(main在啟動時由 NameListDictionaries 載入)，沒有設定時只從查詢結果學習
+//
+// 學到的名稱由 RestoreLearned 從快照還原
+var (
+	Creators   = NewSource("creator")
+	Singers    = NewSource("singer")
+	Characters = NewSource("character")
+)
+
+// 所有來源，保存與還原學到的名稱時走訪
+var sources = []*Source{Games, Brands, Musics, Creators, Singers, Characters}
+
+// 從 cache.AutocompleteLearnedStore 還原上次學到的名稱
+//
+// 需要在 cache.InitStorage 之後呼叫，快照沒有資料時不做任何事
+func RestoreLearned() {
+	for _, s := range sources {
+		names, err := cache.AutocompleteLearnedStore.Get(s.name)
+		if err != nil || len(names) == 0 {
+			continue
+		}
+		s.Learn(names...)
+		slog.Info("autocomplete learned names restored", "source", s.name, "names", len(names))
+	}
+}
+
+func NewSource(name string) *Source {
+	return &Source{name: name, learnedSet: make(map[string]struct{})}
+}
+
+func (s *Source) Name() string {
+	return s.name
+}
+
+// 用新的名稱清單重建索引，相同名稱的熱門度會保留
+func (s *Source) Store(names []string) {
+	s.mu.Lock()
+	s.base = names
+	s.loaded = true
+	s.mu.Unlock()
+	s.rebuild()
+}
+
+// 是否有載入過名稱清單檔案(只靠學習的來源一開始會是空的)
+func (s *Source) Loaded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loaded
+}
+
+// 加入查詢結果中的名稱，索引會在背景合併重建
+func (s *Source) Learn(names ...string) {
+	idx := s.index.Load()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := false
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if idx != nil {
+			if _, ok := idx.byName[name]; ok {
+				continue
+			}
+		}
+		if _, ok := s.learnedSet[name]; ok {
+			continue
+		}
+		s.learned = append(s.learned, name)
+		s.learnedSet[name] = struct{}{}
+		added = true
+	}
+	if len(s.learned) > maxLearned {
+		for _, name := range s.learned[:len(s.learned)-maxLearned] {
+			delete(s.learnedSet, name)
+		}
+		s.learned = append([]string(nil), s.learned[len(s.learned)-maxLearned:]...)
+	}
+	if !added {
+		return
+	}
+	s.dirty = true
+	if !s.rebuilding {
+		s.rebuilding = true
+		go s.rebuildLater(time.Until(s.lastBuild.Add(learnRebuildDelay)))
+	}
+}
+
+func (s *Source) rebuildLater(delay time.Duration) {
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	s.rebuild()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dirty {
+		go s.rebuildLater(learnRebuildDelay)
+		return
+	}
+	s.rebuilding = false
+}
+
+func (s *Source) rebuild() {
+	s.buildMu.Lock()
+	defer s.buildMu.Unlock()
+
+	s.mu.Lock()
+	names := make([]string, 0, len(s.base)+len(s.learned))
+	names = append(names, s.base...)
+	names = append(names, s.learned...)
+	learned := append([]string(nil), s.learned...)
+	s.dirty = false
+	s.lastBuild = time.Now()
+	s.mu.Unlock()
+
+	// 學到的名稱寫入快取，跟著 cache.SaveAll 存到硬碟
+	if len(learned) > 0 {
+		cache.AutocompleteLearnedStore.Set(s.name, learned)
+	}
+
+	start := time.Now()
+	idx := NewIndex(names)
+	if old := s.index.Load(); old != nil {
+		for name, id := range idx.byName {
+			if oldID, ok := old.byName[name]; ok {
+				idx.hits[id].Store(old.hits[oldID].Load())
+			}
+		}
+	}
+	s.index.Store(idx)
+	slog.Debug("autocomplete index built", "source", s.name, "names", idx.Len(), "grams", len(idx.grams), "elapsed", time.Since(start))
+}
+
+func (s *Source) Search(query string, limit int) []string {
+	return s.index.Load().Search(query, limit)
+}
+
+func (s *Source) Hit(name string) {
+	s.index.Load().Hit(name)
+}
+
+func (s *Source) Len() int {
+	return s.index.Load().Len()
+}
@@ -0,0 +1,209 @@
+package bot
+
+import (
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+
+	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/metrics"
+	"kurohelper/internal/utils"
+)
+
+// 超過併發限制時的回覆
+const tooManyRequestsMessage = "目前請求太多，請稍後再試一次"
+
+// 分派handler的worker pool設定
+type DispatcherConfig struct {
+	// worker總數
+	Workers int
+	// 只處理優先佇列(Autocomplete、按鈕)的worker數量，避免慢速查詢佔滿所有worker
+	PriorityWorkers int
+	// 每個佇列最多排隊幾個handler
+	QueueSize int
+	// 同一個使用者同時最多幾個handler(含排隊中)，<= 0 代表不限制
+	UserInFlight int
+	// 同一個伺服器同時最多幾個handler(含排隊中)，<= 0 代表不限制
+	GuildInFlight int
+}
+
+var defaultDispatcherConfig = DispatcherConfig{
+	Workers:         32,
+	PriorityWorkers: 8,
+	QueueSize:       256,
+	UserInFlight:    3,
+	GuildInFlight:   20,
+}
+
+var (
+	dispatcherConfig = defaultDispatcherConfig
+	dispatcherOnce   sync.Once
+	pool             *dispatcher
+)
+
+// 設定worker pool，需要在開始接收Interaction之前呼叫(沒有呼叫時使用預設值)
+func InitDispatcher(cfg DispatcherConfig) {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultDispatcherConfig.Workers
+	}
+	if cfg.PriorityWorkers < 0 || cfg.PriorityWorkers >= cfg.Workers {
+		cfg.PriorityWorkers = cfg.Workers / 4
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultDispatcherConfig.QueueSize
+	}
+	dispatcherConfig = cfg
+}
+
+func getDispatcher() *dispatcher {
+	dispatcherOnce.Do(func() {
+		pool = newDispatcher(dispatcherConfig)
+		pool.registerMetrics()
+	})
+	return pool
+}
+
+type job struct {
+	userID  string
+	guildID string
+	run     func()
+}
+
+type dispatcher struct {
+	cfg DispatcherConfig
+	// Autocomplete與按鈕
+	priority chan job
+	// 一般指令
+	normal chan job
+
+	mu     sync.Mutex
+	users  map[string]int
+	guilds map[string]int
+}
+
+func newDispatcher(cfg DispatcherConfig) *dispatcher {
+	d := &dispatcher{
+		cfg:      cfg,
+		priority: make(chan job, cfg.QueueSize),
+		normal:   make(chan job, cfg.QueueSize),
+		users:    make(map[string]int),
+		guilds:   make(map[string]int),
+	}
+	for n := range cfg.Workers {
+		if n < cfg.PriorityWorkers {
+			go d.priorityWorker()
+		} else {
+			go d.worker()
+		}
+	}
+	return d
+}
+
+// 指標名稱不能重複，只有全域的 pool 會註冊
+func (d *dispatcher) registerMetrics() {
+	metrics.NewCollectorFunc(
+		"kurohelper_dispatch_queue_length",
+		"Number of handlers waiting for a worker by lane.",
+		"gauge",
+		[]string{"lane"},
+		func(emit func(float64, ...string)) {
+			emit(float64(len(d.priority)), "priority")
+			emit(float64(len(d.normal)), "normal")
+		},
+	)
+}
+
+func (d *dispatcher) priorityWorker() {
+	for j := range d.priority {
+		d.execute(j)
+	}
+}
+
+// 優先佇列有工作時先處理
+func (d *dispatcher) worker() {
+	for {
+		select {
+		case j := <-d.priority:
+			d.execute(j)
+			continue
+		default:
+		}
+
+		select {
+		case j := <-d.priority:
+			d.execute(j)
+		case j := <-d.normal:
+			d.execute(j)
+		}
+	}
+}
+
+func (d *dispatcher) execute(j job) {
+	defer d.release(j)
+	j.run()
+}
+
+// 排入佇列，超過使用者/伺服器併發限制或佇列已滿時回傳 ErrTooManyRequests
+func (d *dispatcher) submit(priority bool, userID, guildID string, run func()) error {
+	d.mu.Lock()
+	if d.cfg.UserInFlight > 0 && userID != "" && d.users[userID] >= d.cfg.UserInFlight {
+		d.mu.Unlock()
+		metrics.DispatchRejected.Inc("user")
+		return kurohelpererrors.ErrTooManyRequests
+	}
+	if d.cfg.GuildInFlight > 0 && guildID != "" && d.guilds[guildID] >= d.cfg.GuildInFlight {
+		d.mu.Unlock()
+		metrics.DispatchRejected.Inc("guild")
+		return kurohelpererrors.ErrTooManyRequests
+	}
+	d.users[userID]++
+	d.guilds[guildID]++
+	d.mu.Unlock()
+
+	j := job{userID: userID, guildID: guildID, run: run}
+	queue := d.normal
+	if priority {
+		queue = d.priority
+	}
+	select {
+	case queue <- j:
+		return nil
+	default:
+		d.release(j)
+		metrics.DispatchRejected.Inc("queue_full")
+		return kurohelpererrors.ErrTooManyRequests
+	}
+}
+
+func (d *dispatcher) release(j job) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.users[j.userID]--; d.users[j.userID] <= 0 {
+		delete(d.users, j.userID)
+	}
+	if d.guilds[j.guildID]--; d.guilds[j.guildID] <= 0 {
+		delete(d.guilds, j.guildID)
+	}
+}
+
+// 拒絕執行時的回覆(僅自己可見)，Autocomplete無法顯示訊息所以回傳空的選項
+func respondRejected(s utils.Responder, i *discordgo.InteractionCreate, msg string) {
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+			Data: &discordgo.InteractionResponseData{
+				Choices: []*discordgo.ApplicationCommandOptionChoice{},
+			},
+		})
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	kurohelpererrors "kurohelper/internal/errors"
+)
+
+// 一次submit
+type submitCall struct {
+	priority bool
+	userID   string
+	guildID  string
+	// nil代表應該排入佇列
+	wantErr error
+}
+
+// 沒有worker的dispatcher，排入的工作會一直留在佇列裡
+func TestDispatcherSubmit(t *testing.T) {
+	tooMany := kurohelpererrors.ErrTooManyRequests
+
+	tests := []struct {
+		name  string
+		cfg   DispatcherConfig
+		calls []submitCall
+		// 最後佇列中的數量
+		wantPriority int
+		wantNormal   int
+	}{
+		{
+			name: "queue full",
+			cfg:  DispatcherConfig{QueueSize: 2},
+			calls: []submitCall{
+				{userID: "u1"},
+				{userID: "u2"},
+				{userID: "u3", wantErr: tooMany},
+				// 優先佇列是分開的
+				{priority: true, userID: "u3"},
+			},
+			wantPriority: 1,
+			wantNormal:   2,
+		},
+		{
+			name: "user in-flight cap",
+			cfg:  DispatcherConfig{QueueSize: 10, UserInFlight: 2},
+			calls: []submitCall{
+				{userID: "u1"},
+				{priority: true, userID: "u1"},
+				{userID: "u1", wantErr: tooMany},
+				{userID: "u2"},
+			},
+			wantPriority: 1,
+			wantNormal:   2,
+		},
+		{
+			name: "guild in-flight cap",
+			cfg:  DispatcherConfig{QueueSize: 10, GuildInFlight: 2},
+			calls: []submitCall{
+				{userID: "u1", guildID: "g1"},
+				{userID: "u2", guildID: "g1"},
+				{userID: "u3", guildID: "g1", wantErr: tooMany},
+				{userID: "u3", guildID: "g2"},
+				// 私訊沒有伺服器限制
+				{userID: "u4"},
+				{userID: "u5"},
+				{userID: "u6"},
+			},
+			wantNormal: 6,
+		},
+		{
+			// 被使用者限制擋下的請求不會佔用伺服器的名額
+			name: "user rejection does not count for guild",
+			cfg:  DispatcherConfig{QueueSize: 10, UserInFlight: 1, GuildInFlight: 2},
+			calls: []submitCall{
+				{userID: "u1", guildID: "g1"},
+				{userID: "u1", guildID: "g1", wantErr: tooMany},
+				{userID: "u2", guildID: "g1"},
+				{userID: "u3", guildID: "g1", wantErr: tooMany},
+			},
+			wantNormal: 2,
+		},
+		{
+			// 佇列滿了被拒絕時要歸還名額
+			name: "queue full releases in-flight",
+			cfg:  DispatcherConfig{QueueSize: 1, UserInFlight: 2},
+			calls: []submitCall{
+				{userID: "u1"},
+				{userID: "u1", wantErr: tooMany},
+				{priority: true, userID: "u1"},
+			},
+			wantPriority: 1,
+			wantNormal:   1,
+		},
+		{
+			name: "no limits",
+			cfg:  DispatcherConfig{QueueSize: 10},
+			calls: []submitCall{
+				{userID: "u1", guildID: "g1"},
+				{userID: "u1", guildID: "g1"},
+				{userID: "u1", guildID: "g1"},
+				{userID: "u1", guildID: "g1"},
+			},
+			wantNormal: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDispatcher(tt.cfg)
+			for idx, c := range tt.calls {
+				err := d.submit(c.priority, c.userID, c.guildID, func() {})
+				if !errors.Is(err, c.wantErr) {
+					t.Errorf("call %d: submit() error = %v, want %v", idx, err, c.wantErr)
+				}
+			}
+			if len(d.priority) != tt.wantPriority || len(d.normal) != tt.wantNormal {
+				t.Errorf("queued priority = %d, normal = %d, want %d, %d", len(d.priority), len(d.normal), tt.wantPriority, tt.wantNormal)
+			}
+		})
+	}
+}
+
+// 執行完成後歸還名額
+func TestDispatcherRelease(t *testing.T) {
+	d := newDispatcher(DispatcherConfig{QueueSize: 10, UserInFlight: 1, GuildInFlight: 1})
+	if err := d.submit(false, "u1", "g1", func() {}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.submit(false, "u1", "g1", func() {}); err == nil {
+		t.Fatal("second submit should be rejected")
+	}
+
+	d.execute(<-d.normal)
+	if len(d.users) != 0 || len(d.guilds) != 0 {
+		t.Errorf("in-flight after execute = %v, %v, want empty", d.users, d.guilds)
+	}
+	if err := d.submit(false, "u1", "g1", func() {}); err != nil {
+		t.Errorf("submit after release error = %v", err)
+	}
+}
+
+// 一般worker都在忙時，優先佇列的工作由專用的worker處理
+func TestDispatcherPriorityLane(t *testing.T) {
+	d := newDispatcher(DispatcherConfig{Workers: 2, PriorityWorkers: 1, QueueSize: 10})
+
+	block := make(chan struct{})
+	defer close(block)
+	started := make(chan struct{})
+	if err := d.submit(false, "u1", "", func() {
+		close(started)
+		<-block
+	}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	normalDone := make(chan struct{})
+	if err := d.submit(false, "u2", "", func() { close(normalDone) }); err != nil {
+		t.Fatal(err)
+	}
+	priorityDone := make(chan struct{})
+	if err := d.submit(true, "u3", "", func() { close(priorityDone) }); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-priorityDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("priority job did not run while the normal worker was busy")
+	}
+	// 專用worker不會處理一般佇列
+	select {
+	case <-normalDone:
+		t.Error("normal job ran on the priority worker")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
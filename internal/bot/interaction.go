@@ -1,78 +1,91 @@
 package bot
 
 import (
-	"fmt"
-	"log/slog"
+	"context"
+	"errors"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
 
+	"kurohelper/internal/cid"
 	"kurohelper/internal/commands"
 	"kurohelper/internal/commands/random"
 	"kurohelper/internal/commands/search"
 	"kurohelper/internal/commands/user"
 	"kurohelper/internal/commands/vndb"
 	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/metrics"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
 )
 
 type SlashCommand interface {
 	Definition() *discordgo.ApplicationCommand
-	Handler(s *discordgo.Session, i *discordgo.InteractionCreate)
+	Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate)
 }
 
 // 使用CIDV2的介面
 type ComponentV2Handler interface {
-	HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2)
+	HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2)
 }
 
 // 使用CIDV3的介面
 type ComponentV3Handler interface {
-	HandleComponentV2(s *discordgo.Session, i *discordgo.InteractionCreate, uuid string)
+	HandleComponentV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, uuid string)
 }
 
 // 選擇性介面：只有需要自動補完的指令才實作此方法
 type Autocompleter interface {
-	Autocomplete(s *discordgo.Session, i *discordgo.InteractionCreate)
+	Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate)
 }
 
 // 要使用的指令
-var commandMap = map[string]SlashCommand{
-	// 主要專用指令
-	"查詢遊戲":   &search.SearchGame{},
-	"查詢公司品牌": &search.SearchBrand{},
-	"查詢創作者":  &search.SearchCreator{},
-	"查詢角色":   &search.SearchCharacter{},
-	"查詢音樂":   &search.SearchMusic{},
-	"查詢歌手":   &search.SearchSinger{},
-	// 隨機相關指令
-	"隨機遊戲": &random.RandomGame{},
-	"隨機角色": &random.RandomCharacter{},
-	// 使用者相關指令
-	"個人資料":      &user.GetUserinfo{},
-	"註冊帳號":      &user.Register{},
-	"加已玩":       &user.AddHasPlayed{},
-	"加收藏":       &user.AddInWish{},
-	"刪除使用者遊戲資料": &user.RemoveUserGame{},
-	"帳號設定":      &user.Preference{},
-	"簽到":        &user.CheckIn{},
-	// vndb專用指令
-	"vndb統計資料": &vndb.VNDBStats{},
-	// 未分類指令
-	"幫助": &commands.Helper{},
-	"公告": &commands.Announcement{},
-}
+var commandMap = newCommandMap(provider.Upstream())
 
-// 註冊命令
-func RegisterCommand(s *discordgo.Session) {
-	for n, cmd := range commandMap {
-		_, err := s.ApplicationCommandCreate(s.State.User.ID, "", cmd.Definition())
-		if err != nil {
-			slog.Error(fmt.Sprintf("register %s command failed: %s", n, err.Error()))
-		}
+// 建立指令表，查詢相關指令使用傳入的資料來源
+func newCommandMap(p *provider.Providers) map[string]SlashCommand {
+	return map[string]SlashCommand{
+		// 主要專用指令
+		"查詢遊戲":   &search.SearchGame{Providers: p},
+		"查詢公司品牌": &search.SearchBrand{Providers: p},
+		"查詢創作者":  &search.SearchCreator{Providers: p},
+		"查詢角色":   &search.SearchCharacter{Providers: p},
+		"查詢音樂":   &search.SearchMusic{Providers: p},
+		"查詢歌手":   &search.SearchSinger{Providers: p},
+		// 隨機相關指令
+		"隨機遊戲": &random.RandomGame{Providers: p},
+		"隨機角色": &random.RandomCharacter{Providers: p},
+		// 使用者相關指令
+		"個人資料":      &user.GetUserinfo{},
+		"個人統計":      &user.UserStatistics{Providers: p},
+		"註冊帳號":      &user.Register{},
+		"加已玩":       &user.AddHasPlayed{Providers: p},
+		"加收藏":       &user.AddInWish{Providers: p},
+		"加黑名單":      &user.AddBlackList{Providers: p},
+		"移除黑名單":     &user.RemoveBlackList{},
+		"匯入遊戲資料":    &user.ImportUserGame{Providers: p},
+		"匯出資料":      &user.ExportUserData{},
+		"設定遊玩狀態":    &user.SetPlayStatus{Providers: p},
+		"編輯遊戲紀錄":    &user.EditUserGame{},
+		"刪除使用者遊戲資料": &user.RemoveUserGame{},
+		"帳號設定":      &user.Preference{},
+		"簽到":        &user.CheckIn{},
+		// vndb專用指令
+		"vndb統計資料": &vndb.VNDBStats{},
+		// 未分類指令
+		"幫助": &commands.Helper{},
+		"公告": &commands.Announcement{},
 	}
 }
 
+// 替換查詢指令使用的資料來源(離線模式、整合測試使用)
+//
+// 需要在 RegisterCommand 與開始接收Interaction之前呼叫
+func UseProviders(p *provider.Providers) {
+	commandMap = newCommandMap(p)
+}
+
 func GetSlashCommand(name string) SlashCommand {
 	cmd, ok := commandMap[name]
 	if !ok {
@@ -82,64 +95,137 @@ func GetSlashCommand(name string) SlashCommand {
 	return cmd
 }
 
+// Discord事件進入點，註冊在discordgo的AddHandler上
 func OnInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	DispatchInteraction(s, i)
+}
+
+// 依照Interaction類型分派給對應的指令
+//
+// 只依賴 utils.Responder，所以測試時可以直接餵入合成的 InteractionCreate 與 discordtest.Recorder。
+// 每個Interaction都會建立 tracing.Trace，handler結束時輸出一行摘要
+func DispatchInteraction(s utils.Responder, i *discordgo.InteractionCreate) {
+	kind, commandName, customID := interactionInfo(i)
+	if kind == "" {
+		return
+	}
+	ctx, trace := tracing.Start(baseCtx, i, kind, commandName, customID)
+	logger := trace.Logger()
+
+	// 交給goroutine執行時由goroutine負責結束Trace
+	dispatched := false
+	defer func() {
+		if !dispatched {
+			trace.Finish()
+		}
+	}()
+	// 交給worker pool執行，關閉中回覆維護訊息，超過併發限制回覆請求太多
+	spawn := func(fn func()) {
+		if !acquireWorker() {
+			logger.Info("rejected interaction during shutdown")
+			respondRejected(s, i, maintenanceMessage)
+			return
+		}
+		err := getDispatcher().submit(kind != tracing.KindCommand, trace.UserID, trace.GuildID, func() {
+			defer releaseWorker()
+			defer trace.Finish()
+			fn()
+		})
+		if err != nil {
+			releaseWorker()
+			trace.RecordError(err)
+			respondRejected(s, i, tooManyRequestsMessage)
+			return
+		}
+		dispatched = true
+	}
+
 	switch i.Type {
 	// 一般事件
 	case discordgo.InteractionApplicationCommand:
-		if cmd := GetSlashCommand(i.ApplicationCommandData().Name); cmd != nil {
-			go cmd.Handler(s, i)
+		if cmd := GetSlashCommand(commandName); cmd != nil {
+			if ok, retryAfter := checkRateLimit(commandName, cmd, trace.UserID, trace.GuildID); !ok {
+				trace.RecordError(kurohelpererrors.ErrCommandCooldown)
+				metrics.DispatchRejected.Inc("cooldown")
+				respondCooldown(s, i, retryAfter)
+				return
+			}
+			spawn(func() { cmd.Handler(ctx, s, i) })
 		}
 	// Autocomplete
 	case discordgo.InteractionApplicationCommandAutocomplete:
-		if cmd := GetSlashCommand(i.ApplicationCommandData().Name); cmd != nil {
+		if cmd := GetSlashCommand(commandName); cmd != nil {
 			if auto, ok := cmd.(Autocompleter); ok {
-				go auto.Autocomplete(s, i)
+				spawn(func() { auto.Autocomplete(ctx, s, i) })
 			} else {
-				slog.Warn(i.ApplicationCommandData().Name + " 沒有實作Autocomplete")
+				logger.Warn(commandName + " 沒有實作Autocomplete")
 				return
 			}
 		}
 
 	case discordgo.InteractionMessageComponent:
-		customID := i.MessageComponentData().CustomID
-
-		// 處理V3版CID
-		if after, ok := strings.CutPrefix(customID, "v3@"); ok {
-			parts := strings.SplitN(after, ":", 2)
-			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
-				utils.HandleError(kurohelpererrors.ErrCIDWrongFormat, s, i)
-				return
-			}
+		// 操作別人的元件時只回覆給自己看的提示，不修改原本的訊息
+		rejectNotOwner := func() {
+			trace.RecordError(kurohelpererrors.ErrCIDNotOwner)
+			metrics.DispatchRejected.Inc("not_owner")
+			respondRejected(s, i, notOwnerMessage)
+		}
 
-			cmd := GetSlashCommand(parts[0])
+		// 只帶cacheID的CID交給 ComponentV3Handler
+		handleV3 := func(commandName, cacheID string) {
+			cmd := GetSlashCommand(commandName)
 			if cmd == nil {
-				slog.Warn(parts[0] + " 沒有註冊SlashCommand")
+				logger.Warn(commandName + " 沒有註冊SlashCommand")
 				return
 			}
 
 			v3cmd, ok := cmd.(ComponentV3Handler)
 			if !ok {
-				slog.Warn(parts[0] + " 沒有實作ComponentV3Handler")
+				logger.Warn(commandName + " 沒有實作ComponentV3Handler")
+				return
+			}
+			if !canUseComponent(cmd, cacheID, trace.UserID) {
+				rejectNotOwner()
 				return
 			}
 
-			go v3cmd.HandleComponentV2(s, i, parts[1])
+			spawn(func() { v3cmd.HandleComponentV2(ctx, s, i, cacheID) })
+		}
+
+		// 舊版V3 CID(v3@commandName:cacheID)
+		if strings.HasPrefix(customID, cid.V3Prefix) {
+			commandName, cacheID, err := cid.DecodeV3(customID)
+			if err != nil {
+				utils.HandleError(err, s, i)
+				return
+			}
+			handleV3(commandName, cacheID)
 			return
 		}
 
-		// V2版CID
-		cid, err := utils.ParseCIDV2(customID)
+		// V4與舊版V2 CID
+		componentCID, err := utils.ParseCID(customID)
 		if err != nil {
+			if errors.Is(err, kurohelpererrors.ErrCIDInvalidSignature) {
+				logger.Warn("rejected custom id with invalid signature")
+				utils.HandleError(err, s, i)
+				return
+			}
 			utils.HandleError(kurohelpererrors.ErrCIDWrongFormat, s, i)
 			return
 		}
 
+		if componentCID.GetBehaviorID() == utils.V3Behavior {
+			handleV3(componentCID.GetCommandName(), componentCID.GetCacheID())
+			return
+		}
+
 		// 下拉選單選擇遊戲時，修改Value值
-		if cid.GetBehaviorID() == utils.SelectMenuBehavior {
-			cid.ChangeValue(i.MessageComponentData().Values[0])
+		if componentCID.GetBehaviorID() == utils.SelectMenuBehavior {
+			componentCID.ChangeValue(i.MessageComponentData().Values[0])
 		}
 
-		commandName := cid.GetCommandName()
+		commandName := componentCID.GetCommandName()
 		if commandName == "" {
 			utils.HandleError(kurohelpererrors.ErrCIDWrongFormat, s, i)
 			return
@@ -147,18 +233,44 @@ func OnInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
 
 		cmd := GetSlashCommand(commandName)
 		if cmd == nil {
-			slog.Warn(commandName + " 沒有註冊SlashCommand")
+			logger.Warn(commandName + " 沒有註冊SlashCommand")
 			return
 		}
 
 		v2cmd, ok := cmd.(ComponentV2Handler)
 		if !ok {
-			slog.Warn(commandName + " 沒有實作ComponentV2Handler")
+			logger.Warn(commandName + " 沒有實作ComponentV2Handler")
+			return
+		}
+		if !canUseComponent(cmd, componentCID.GetCacheID(), trace.UserID) {
+			rejectNotOwner()
 			return
 		}
 
-		go v2cmd.HandleComponent(s, i, cid)
+		spawn(func() { v2cmd.HandleComponent(ctx, s, i, componentCID) })
 	default:
 		return
 	}
 }
+
+// 取得Trace需要的Interaction資訊，不支援的種類kind回傳空字串
+//
+// Component的指令名稱從V4 CID解碼，舊版直接取CID的第一段(V2: cmd:..., V3: v3@cmd:uuid)
+func interactionInfo(i *discordgo.InteractionCreate) (kind tracing.Kind, commandName string, customID string) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		return tracing.KindCommand, i.ApplicationCommandData().Name, ""
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		return tracing.KindAutocomplete, i.ApplicationCommandData().Name, ""
+	case discordgo.InteractionMessageComponent:
+		customID = i.MessageComponentData().CustomID
+		if cid.IsV4(customID) {
+			p, _ := cid.DecodeV4(customID)
+			return tracing.KindComponent, p.Command, customID
+		}
+		commandName, _, _ = strings.Cut(strings.TrimPrefix(customID, cid.V3Prefix), ":")
+		return tracing.KindComponent, commandName, customID
+	default:
+		return "", "", ""
+	}
+}
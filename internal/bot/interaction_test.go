@@ -0,0 +1,340 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kurohelper/internal/discordtest"
+	"kurohelper/internal/provider"
+)
+
+// 錯誤訊息(utils.MakeErrorComponentV2)的顏色
+const errorAccentColor = 0xcc543a
+
+func TestMain(m *testing.M) {
+	p, err := provider.LoadFixtures("../../fixtures")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	UseProviders(p)
+	os.Exit(m.Run())
+}
+
+// 點擊元件的方式
+type stepAction int
+
+const (
+	// 點擊Label相同的按鈕
+	clickButton stepAction = iota
+	// 在下拉選單選擇指定的值
+	selectValue
+)
+
+// 指令送出後的一次元件操作
+type step struct {
+	action stepAction
+	// clickButton為按鈕Label，selectValue為選項的值
+	target string
+	// 這次操作最後一個回應應該出現的文字
+	want string
+	// 由其他使用者操作，應該只收到僅自己可見的 notOwnerMessage，原本的訊息不變
+	otherUser bool
+}
+
+func TestSearchCommandFlows(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		options []*discordgo.ApplicationCommandInteractionDataOption
+		// 第一頁應該出現的文字
+		want  string
+		steps []step
+	}{
+		{
+			name:    "erogs game",
+			command: "查詢遊戲",
+			options: options("keyword", "flow erogs game"),
+			want:    "Summer Pockets",
+			steps: []step{
+				{action: clickButton, target: "▶️", otherUser: true},
+				{action: clickButton, target: "▶️", want: "Kanon"},
+				{action: selectValue, target: "e1000", otherUser: true},
+				{action: selectValue, target: "e1000", want: "Kanon(1999-06-04)"},
+			},
+		},
+		{
+			name:    "vndb game",
+			command: "查詢遊戲",
+			options: options("keyword", "flow vndb game", "查詢資料庫選項", "1"),
+			want:    "サマーポケッツ",
+			steps: []step{
+				{action: clickButton, target: "▶️", want: "Kanon"},
+				// 第一次開啟就要有切換到批評空間的按鈕
+				{action: selectValue, target: "v1", want: "[🔀批評空間]"},
+				{action: clickButton, target: "🔀批評空間", want: "Kanon(1999-06-04)"},
+			},
+		},
+		{
+			name:    "erogs brand",
+			command: "查詢公司品牌",
+			options: options("keyword", "flow erogs brand", "查詢資料庫選項", "2"),
+			want:    "Key",
+			steps: []step{
+				{action: clickButton, target: "▶️", want: "Kanon"},
+				{action: selectValue, target: "e1000", want: "Kanon(1999-06-04)"},
+			},
+		},
+		{
+			name:    "vndb brand",
+			command: "查詢公司品牌",
+			options: options("keyword", "flow vndb brand", "查詢資料庫選項", "1"),
+			want:    "Key",
+			steps: []step{
+				{action: clickButton, target: "▶️", want: "Kanon"},
+				{action: selectValue, target: "v1", want: "[🔀批評空間]"},
+			},
+		},
+		{
+			name:    "creator",
+			command: "查詢創作者",
+			options: options("keyword", "flow creator"),
+			want:    "麻枝准",
+			steps: []step{
+				{action: clickButton, target: "▶️", want: "折戸伸治"},
+				{action: clickButton, target: "查看詳情", want: "歷代作品"},
+				{action: selectValue, target: "e4013", want: "CLANNAD(2004-04-28)"},
+			},
+		},
+		{
+			name:    "vndb character",
+			command: "查詢角色",
+			options: options("keyword", "flow character", "查詢資料庫選項", "1"),
+			want:    "鳴瀬しろは",
+			steps: []step{
+				{action: clickButton, target: "▶️", want: "古河渚"},
+				{action: selectValue, target: "c42", want: "古河渚 (Nagisa Furukawa)"},
+			},
+		},
+		{
+			name:    "music",
+			command: "查詢音樂",
+			options: options("keyword", "flow music"),
+			want:    "鳥の詩",
+			steps: []step{
+				{action: clickButton, target: "▶️", want: "Philosophyz"},
+				{action: selectValue, target: "e4206", want: "# 鳥の詩"},
+			},
+		},
+		{
+			name:    "singer",
+			command: "查詢歌手",
+			options: options("keyword", "flow singer"),
+			want:    "Lia",
+			steps: []step{
+				{action: clickButton, target: "▶️", want: "Ceui"},
+				{action: clickButton, target: "查看詳情", want: "🎤 Lia"},
+				{action: selectValue, target: "e4201", want: "# 鳥の詩"},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 每個案例使用不同的使用者，避免互相影響限流與併發限制
+			user := discordtest.WithUser(fmt.Sprintf("1000000000000%05d", n), "tester")
+			other := discordtest.WithUser(fmt.Sprintf("2000000000000%05d", n), "other")
+			rec := discordtest.NewRecorder()
+
+			got := dispatchAndCheck(t, rec, discordtest.NewSlashCommand(tt.command, tt.options, user), tt.want)
+
+			for _, st := range tt.steps {
+				clicker := user
+				if st.otherUser {
+					clicker = other
+				}
+				var i *discordgo.InteractionCreate
+				switch st.action {
+				case clickButton:
+					customID := findButton(got.Components, st.target)
+					if customID == "" {
+						t.Fatalf("button %q not found in %s", st.target, componentText(got.Components))
+					}
+					i = discordtest.NewButton(customID, clicker)
+				case selectValue:
+					customID := findSelectMenu(got.Components, st.target)
+					if customID == "" {
+						t.Fatalf("select option %q not found in %s", st.target, componentText(got.Components))
+					}
+					i = discordtest.NewSelectMenu(customID, []string{st.target}, clicker)
+				}
+				if st.otherUser {
+					dispatchNotOwner(t, rec, i)
+					continue
+				}
+				got = dispatchAndCheck(t, rec, i, st.want)
+			}
+		})
+	}
+}
+
+func options(pairs ...string) []*discordgo.ApplicationCommandInteractionDataOption {
+	opts := make([]*discordgo.ApplicationCommandInteractionDataOption, 0, len(pairs)/2)
+	for n := 0; n+1 < len(pairs); n += 2 {
+		opts = append(opts, discordtest.StringOption(pairs[n], pairs[n+1]))
+	}
+	return opts
+}
+
+// 送出Interaction並等待handler結束，檢查這次的回應後回傳最後一筆
+//
+// 每個Interaction的第一個回應都必須是InteractionRespond(Discord規定3秒內要回應)
+func dispatchAndCheck(t *testing.T, rec *discordtest.Recorder, i *discordgo.InteractionCreate, want string) discordtest.Response {
+	t.Helper()
+
+	before := len(rec.Responses())
+	DispatchInteraction(rec, i)
+	waitIdle(t)
+
+	responses := rec.Responses()[before:]
+	if len(responses) == 0 {
+		t.Fatal("no response recorded")
+	}
+	if responses[0].Kind != discordtest.KindRespond {
+		t.Fatalf("first response kind = %s, want %s", responses[0].Kind, discordtest.KindRespond)
+	}
+	for _, resp := range responses {
+		if resp.InteractionID != "" && resp.InteractionID != i.ID {
+			t.Fatalf("response for interaction %s, want %s", resp.InteractionID, i.ID)
+		}
+	}
+
+	last := responses[len(responses)-1]
+	if isErrorResponse(last) {
+		t.Fatalf("got error response: %s", componentText(last.Components))
+	}
+	if text := componentText(last.Components); !strings.Contains(text, want) {
+		t.Fatalf("last response does not contain %q:\n%s", want, text)
+	}
+	return last
+}
+
+// 其他使用者操作時只會收到一個僅自己可見的 notOwnerMessage，不會執行handler
+func dispatchNotOwner(t *testing.T, rec *discordtest.Recorder, i *discordgo.InteractionCreate) {
+	t.Helper()
+
+	before := len(rec.Responses())
+	DispatchInteraction(rec, i)
+	waitIdle(t)
+
+	responses := rec.Responses()[before:]
+	if len(responses) != 1 {
+		t.Fatalf("got %d responses, want only the rejection", len(responses))
+	}
+	resp := responses[0]
+	if resp.Kind != discordtest.KindRespond || resp.Type != discordgo.InteractionResponseChannelMessageWithSource {
+		t.Errorf("rejection kind = %s/%d, want a new message", resp.Kind, resp.Type)
+	}
+	if resp.Flags&discordgo.MessageFlagsEphemeral == 0 {
+		t.Error("rejection is not ephemeral")
+	}
+	if resp.Content != notOwnerMessage {
+		t.Errorf("rejection content = %q, want %q", resp.Content, notOwnerMessage)
+	}
+}
+
+// 等待所有handler執行完畢
+func waitIdle(t *testing.T) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for inflightCount.Load() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("handler did not finish in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func isErrorResponse(resp discordtest.Response) bool {
+	for _, c := range resp.Components {
+		if container, ok := c.(discordgo.Container); ok && container.AccentColor != nil && *container.AccentColor == errorAccentColor {
+			return true
+		}
+	}
+	return false
+}
+
+// 依序走訪所有元件(包含Container、ActionsRow、Section內的元件)
+func walkComponents(components []discordgo.MessageComponent, fn func(discordgo.MessageComponent)) {
+	for _, c := range components {
+		fn(c)
+		switch v := c.(type) {
+		case discordgo.Container:
+			walkComponents(v.Components, fn)
+		case *discordgo.Container:
+			walkComponents(v.Components, fn)
+		case discordgo.ActionsRow:
+			walkComponents(v.Components, fn)
+		case *discordgo.ActionsRow:
+			walkComponents(v.Components, fn)
+		case discordgo.Section:
+			walkComponents(v.Components, fn)
+			if v.Accessory != nil {
+				walkComponents([]discordgo.MessageComponent{v.Accessory}, fn)
+			}
+		}
+	}
+}
+
+// 找出可以點擊且Label相同的第一個按鈕，回傳CustomID
+func findButton(components []discordgo.MessageComponent, label string) string {
+	var customID string
+	walkComponents(components, func(c discordgo.MessageComponent) {
+		if b, ok := c.(discordgo.Button); ok && customID == "" && !b.Disabled && b.Label == label {
+			customID = b.CustomID
+		}
+	})
+	return customID
+}
+
+// 找出有指定選項的下拉選單，回傳CustomID
+func findSelectMenu(components []discordgo.MessageComponent, value string) string {
+	var customID string
+	walkComponents(components, func(c discordgo.MessageComponent) {
+		menu, ok := c.(discordgo.SelectMenu)
+		if !ok || customID != "" {
+			return
+		}
+		for _, opt := range menu.Options {
+			if opt.Value == value {
+				customID = menu.CustomID
+				return
+			}
+		}
+	})
+	return customID
+}
+
+// 把元件內的文字、按鈕與選項串起來，方便比對與除錯
+func componentText(components []discordgo.MessageComponent) string {
+	var sb strings.Builder
+	walkComponents(components, func(c discordgo.MessageComponent) {
+		switch v := c.(type) {
+		case discordgo.TextDisplay:
+			sb.WriteString(v.Content + "\n")
+		case discordgo.Button:
+			sb.WriteString("[" + v.Label + "]\n")
+		case discordgo.SelectMenu:
+			for _, opt := range v.Options {
+				sb.WriteString("<" + opt.Value + " " + opt.Label + ">\n")
+			}
+		}
+	})
+	return sb.String()
+}
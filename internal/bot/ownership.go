@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"kurohelper/internal/cache"
+	"kurohelper/internal/utils"
+)
+
+// 非本人操作元件時的回覆
+const notOwnerMessage = "這是其他人的查詢結果，只有使用指令的人可以操作喔，請自己使用指令查詢"
+
+// 有實作這個介面的指令可以宣告元件的使用權限，沒有實作時只有本人可以操作
+type ComponentAccessor interface {
+	ComponentAccess() utils.ComponentAccess
+}
+
+func componentAccess(cmd SlashCommand) utils.ComponentAccess {
+	if a, ok := cmd.(ComponentAccessor); ok {
+		return a.ComponentAccess()
+	}
+	return utils.ComponentOwnerOnly
+}
+
+// 是否可以操作這個元件
+//
+// 沒有擁有者紀錄(舊訊息或快取已過期)時交給handler處理，handler會回覆快取遺失
+func canUseComponent(cmd SlashCommand, cacheID, userID string) bool {
+	if componentAccess(cmd) == utils.ComponentShared {
+		return true
+	}
+	owner, ok := cache.CIDOwner(cacheID)
+	return !ok || owner == userID
+}
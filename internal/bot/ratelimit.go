@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kurohelper/internal/ratelimit"
+	"kurohelper/internal/store"
+	"kurohelper/internal/utils"
+)
+
+// 有實作這個介面的指令會依照回傳的規則限流(可以被 COMMAND_RATE_LIMITS 覆蓋)
+type RateLimited interface {
+	RateLimit() ratelimit.Rule
+}
+
+// 指令限流設定
+type RateLimitConfig struct {
+	// 覆蓋指令內建的規則，格式為 "指令名稱=次數/秒數,..."，例如 "隨機遊戲=3/60,查詢遊戲=10/60"
+	Overrides string
+	// 同一個伺服器共用的額度是單一使用者的幾倍，<= 0 代表不限制伺服器
+	GuildFactor int
+	// 白名單伺服器(store.GuildDiscordAllowList)的額度倍數
+	AllowListFactor int
+}
+
+var (
+	limiter         = ratelimit.NewLimiter()
+	rateLimitConfig = RateLimitConfig{GuildFactor: 10, AllowListFactor: 3}
+	// 從環境變數讀到的規則
+	rateLimitOverrides = map[string]ratelimit.Rule{}
+)
+
+// 設定指令限流，需要在開始接收Interaction之前呼叫
+func InitRateLimit(cfg RateLimitConfig) {
+	rateLimitConfig = cfg
+
+	overrides := make(map[string]ratelimit.Rule)
+	for _, entry := range strings.Split(cfg.Overrides, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, ruleStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			slog.Warn("ratelimit: invalid override, expected <command>=<burst>/<seconds>", "entry", entry)
+			continue
+		}
+		rule, err := ratelimit.ParseRule(ruleStr)
+		if err != nil {
+			slog.Warn(err.Error())
+			continue
+		}
+		overrides[strings.TrimSpace(name)] = rule
+	}
+	rateLimitOverrides = overrides
+
+	for name, rule := range overrides {
+		slog.Info("ratelimit: override", "command", name, "rule", rule.String())
+	}
+}
+
+// 取得指令的限流規則
+func commandRateLimit(name string, cmd SlashCommand) ratelimit.Rule {
+	if rule, ok := rateLimitOverrides[name]; ok {
+		return rule
+	}
+	if rl, ok := cmd.(RateLimited); ok {
+		return rl.RateLimit()
+	}
+	return ratelimit.Rule{}
+}
+
+// 白名單伺服器，私訊時使用者ID也會存在同一個名單
+func isAllowListed(guildID, userID string) bool {
+	id := guildID
+	if id == "" {
+		id = userID
+	}
+	_, ok := store.GuildDiscordAllowList[id]
+	return ok
+}
+
+// 檢查使用者與伺服器的額度，超過時回傳還需要等待的時間
+func checkRateLimit(name string, cmd SlashCommand, userID, guildID string) (bool, time.Duration) {
+	rule := commandRateLimit(name, cmd)
+	if !rule.Enabled() {
+		return true, 0
+	}
+	if isAllowListed(guildID, userID) {
+		rule = rule.Scale(rateLimitConfig.AllowListFactor)
+	}
+
+	checks := []ratelimit.Check{{Key: "user:" + name + ":" + userID, Rule: rule}}
+	if guildID != "" && rateLimitConfig.GuildFactor > 0 {
+		checks = append(checks, ratelimit.Check{Key: "guild:" + name + ":" + guildID, Rule: rule.Scale(rateLimitConfig.GuildFactor)})
+	}
+	return limiter.Allow(time.Now(), checks...)
+}
+
+// 冷卻中的回覆(僅自己可見)
+func respondCooldown(s utils.Responder, i *discordgo.InteractionCreate, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags:      discordgo.MessageFlagsIsComponentsV2 | discordgo.MessageFlagsEphemeral,
+			Components: utils.MakeErrorComponentV2(fmt.Sprintf("指令冷卻中，請在%d秒後再試", seconds)),
+		},
+	})
+}
@@ -0,0 +1,171 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kurohelper/internal/discordtest"
+	"kurohelper/internal/ratelimit"
+	"kurohelper/internal/store"
+)
+
+// 每個測試使用新的 limiter 與設定，結束後還原
+func useRateLimit(t *testing.T, cfg RateLimitConfig) {
+	t.Helper()
+	oldLimiter, oldConfig, oldOverrides := limiter, rateLimitConfig, rateLimitOverrides
+	t.Cleanup(func() {
+		limiter, rateLimitConfig, rateLimitOverrides = oldLimiter, oldConfig, oldOverrides
+	})
+	limiter = ratelimit.NewLimiter()
+	InitRateLimit(cfg)
+}
+
+func TestInitRateLimitOverrides(t *testing.T) {
+	useRateLimit(t, RateLimitConfig{Overrides: " 隨機遊戲=3/60, 查詢遊戲 =10/5,壞掉的,缺少秒數=3,,亂寫=a/b"})
+
+	want := map[string]ratelimit.Rule{
+		"隨機遊戲": {Burst: 3, Per: time.Minute},
+		"查詢遊戲": {Burst: 10, Per: 5 * time.Second},
+	}
+	if len(rateLimitOverrides) != len(want) {
+		t.Errorf("overrides = %v, want %v", rateLimitOverrides, want)
+	}
+	for name, rule := range want {
+		if got := rateLimitOverrides[name]; got != rule {
+			t.Errorf("override %s = %v, want %v", name, got, rule)
+		}
+	}
+	// 覆蓋的規則優先於指令內建的規則
+	if got := commandRateLimit("隨機遊戲", nil); got != want["隨機遊戲"] {
+		t.Errorf("commandRateLimit() = %v, want %v", got, want["隨機遊戲"])
+	}
+	if got := commandRateLimit("沒有設定", nil); got.Enabled() {
+		t.Errorf("commandRateLimit() without rule = %v, want disabled", got)
+	}
+}
+
+func TestCheckRateLimit(t *testing.T) {
+	const cmd = "限流測試"
+	store.GuildDiscordAllowList["allow-guild"] = struct{}{}
+	store.GuildDiscordAllowList["allow-user"] = struct{}{}
+	t.Cleanup(func() {
+		delete(store.GuildDiscordAllowList, "allow-guild")
+		delete(store.GuildDiscordAllowList, "allow-user")
+	})
+
+	type call struct {
+		userID, guildID string
+		want            bool
+	}
+	tests := []struct {
+		name  string
+		cfg   RateLimitConfig
+		calls []call
+	}{
+		{
+			name: "per user",
+			cfg:  RateLimitConfig{Overrides: cmd + "=2/60"},
+			calls: []call{
+				{userID: "u1", guildID: "g1", want: true},
+				{userID: "u1", guildID: "g1", want: true},
+				{userID: "u1", guildID: "g1", want: false},
+				{userID: "u2", guildID: "g1", want: true},
+			},
+		},
+		{
+			// 伺服器共用 1*2 次
+			name: "guild factor",
+			cfg:  RateLimitConfig{Overrides: cmd + "=1/60", GuildFactor: 2},
+			calls: []call{
+				{userID: "u1", guildID: "g1", want: true},
+				{userID: "u1", guildID: "g1", want: false},
+				{userID: "u2", guildID: "g1", want: true},
+				{userID: "u3", guildID: "g1", want: false},
+				{userID: "u3", guildID: "g2", want: true},
+				// 私訊沒有伺服器額度
+				{userID: "u4", want: true},
+			},
+		},
+		{
+			name: "allow-listed guild",
+			cfg:  RateLimitConfig{Overrides: cmd + "=1/60", AllowListFactor: 3},
+			calls: []call{
+				{userID: "u1", guildID: "allow-guild", want: true},
+				{userID: "u1", guildID: "allow-guild", want: true},
+				{userID: "u1", guildID: "allow-guild", want: true},
+				{userID: "u1", guildID: "allow-guild", want: false},
+				{userID: "u2", guildID: "g1", want: true},
+				{userID: "u2", guildID: "g1", want: false},
+			},
+		},
+		{
+			// 私訊時用使用者ID比對白名單
+			name: "allow-listed user in dm",
+			cfg:  RateLimitConfig{Overrides: cmd + "=1/60", AllowListFactor: 2},
+			calls: []call{
+				{userID: "allow-user", want: true},
+				{userID: "allow-user", want: true},
+				{userID: "allow-user", want: false},
+			},
+		},
+		{
+			name: "no rule",
+			cfg:  RateLimitConfig{GuildFactor: 10},
+			calls: []call{
+				{userID: "u1", guildID: "g1", want: true},
+				{userID: "u1", guildID: "g1", want: true},
+				{userID: "u1", guildID: "g1", want: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useRateLimit(t, tt.cfg)
+			for idx, c := range tt.calls {
+				ok, wait := checkRateLimit(cmd, nil, c.userID, c.guildID)
+				if ok != c.want {
+					t.Errorf("call %d (%s@%s): allowed = %v, want %v", idx, c.userID, c.guildID, ok, c.want)
+				}
+				if ok != (wait == 0) {
+					t.Errorf("call %d: allowed = %v with wait %s", idx, ok, wait)
+				}
+			}
+		})
+	}
+}
+
+// 冷卻時間(1次/60秒，剛用完時約60秒)無條件進位顯示
+func TestRespondCooldown(t *testing.T) {
+	useRateLimit(t, RateLimitConfig{Overrides: "冷卻測試=1/60"})
+	checkRateLimit("冷卻測試", nil, "u1", "")
+	ok, wait := checkRateLimit("冷卻測試", nil, "u1", "")
+	if ok || wait <= 59*time.Second || wait > time.Minute {
+		t.Fatalf("checkRateLimit() = %v, %s, want about 60s", ok, wait)
+	}
+
+	tests := []struct {
+		wait time.Duration
+		want string
+	}{
+		{wait: wait, want: "60秒"},
+		{wait: 1200 * time.Millisecond, want: "2秒"},
+		{wait: 3 * time.Second, want: "3秒"},
+	}
+	for _, tt := range tests {
+		rec := discordtest.NewRecorder()
+		respondCooldown(rec, discordtest.NewSlashCommand("冷卻測試", nil), tt.wait)
+		resp, ok := rec.Last()
+		if !ok {
+			t.Fatal("no response")
+		}
+		if text := componentText(resp.Components); !strings.Contains(text, "請在"+tt.want+"後再試") {
+			t.Errorf("respondCooldown(%s) = %q, want %s", tt.wait, text, tt.want)
+		}
+		if resp.Flags&discordgo.MessageFlagsEphemeral == 0 {
+			t.Errorf("respondCooldown(%s) is not ephemeral", tt.wait)
+		}
+	}
+}
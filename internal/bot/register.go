@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 註冊指令時需要的Discord API(*discordgo.Session 有實作)
+type commandRegistrar interface {
+	ApplicationCommands(appID, guildID string, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+	ApplicationCommandBulkOverwrite(appID string, guildID string, commands []*discordgo.ApplicationCommand, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+}
+
+// 註冊命令
+//
+// 預設註冊為全域指令；有設定 COMMAND_GUILD_ID 時改註冊到該伺服器(開發用，會立即生效)。
+// 只有在Discord上的定義與 commandMap 不一致時才會整批覆寫，
+// 覆寫時不在 commandMap 內的舊指令也會一併被刪除
+func RegisterCommand(s *discordgo.Session) {
+	registerCommands(s, s.State.User.ID, desiredCommands())
+}
+
+func registerCommands(r commandRegistrar, appID string, desired []*discordgo.ApplicationCommand) {
+	guildID := strings.TrimSpace(os.Getenv("COMMAND_GUILD_ID"))
+
+	if err := syncCommands(r, appID, guildID, desired); err != nil {
+		slog.Error(fmt.Sprintf("register command failed: %s", err.Error()), "guildID", guildID)
+	}
+
+	// 開發伺服器模式下可以順便清掉之前註冊的全域指令，避免同名指令出現兩次
+	if guildID != "" && strings.EqualFold(os.Getenv("COMMAND_CLEAR_GLOBAL"), "true") {
+		if err := syncCommands(r, appID, "", nil); err != nil {
+			slog.Error(fmt.Sprintf("clear global command failed: %s", err.Error()))
+		}
+	}
+}
+
+// commandMap 內所有指令的定義(依名稱排序)
+func desiredCommands() []*discordgo.ApplicationCommand {
+	defs := make([]*discordgo.ApplicationCommand, 0, len(commandMap))
+	for _, cmd := range commandMap {
+		defs = append(defs, cmd.Definition())
+	}
+	slices.SortFunc(defs, func(a, b *discordgo.ApplicationCommand) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return defs
+}
+
+// 比對Discord上已註冊的指令，有差異時才整批覆寫
+func syncCommands(r commandRegistrar, appID, guildID string, desired []*discordgo.ApplicationCommand) error {
+	registered, err := r.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return err
+	}
+
+	desiredVersion, err := commandsVersion(desired)
+	if err != nil {
+		return err
+	}
+	registeredVersion, err := commandsVersion(registered)
+	if err != nil {
+		return err
+	}
+	if desiredVersion == registeredVersion {
+		slog.Info("command definitions unchanged, skip register", "version", desiredVersion, "guildID", guildID)
+		return nil
+	}
+
+	desiredNames := make(map[string]struct{}, len(desired))
+	for _, cmd := range desired {
+		desiredNames[cmd.Name] = struct{}{}
+	}
+	for _, cmd := range registered {
+		if _, ok := desiredNames[cmd.Name]; !ok {
+			slog.Info("remove orphaned command", "name", cmd.Name, "guildID", guildID)
+		}
+	}
+
+	if desired == nil {
+		// BulkOverwrite 需要空陣列而不是null才會刪除全部指令
+		desired = []*discordgo.ApplicationCommand{}
+	}
+	if _, err := r.ApplicationCommandBulkOverwrite(appID, guildID, desired); err != nil {
+		return err
+	}
+	slog.Info("command definitions registered", "version", desiredVersion, "previous", registeredVersion, "count", len(desired), "guildID", guildID)
+	return nil
+}
+
+// 用來比對的指令定義
+//
+// 只保留我們會設定的欄位，並補上Discord回傳時的預設值，
+// 避免ID、Version這類由Discord產生的欄位造成誤判
+type commandSignature struct {
+	Type                     discordgo.ApplicationCommandType `json:"type"`
+	Name                     string                           `json:"name"`
+	NameLocalizations        map[discordgo.Locale]string      `json:"name_localizations,omitempty"`
+	Description              string                           `json:"description"`
+	DescriptionLocalizations map[discordgo.Locale]string      `json:"description_localizations,omitempty"`
+	DefaultMemberPermissions *int64                           `json:"default_member_permissions,omitempty"`
+	NSFW                     bool                             `json:"nsfw"`
+	Options                  []optionSignature                `json:"options,omitempty"`
+}
+
+type optionSignature struct {
+	Type                     discordgo.ApplicationCommandOptionType `json:"type"`
+	Name                     string                                 `json:"name"`
+	NameLocalizations        map[discordgo.Locale]string            `json:"name_localizations,omitempty"`
+	Description              string                                 `json:"description"`
+	DescriptionLocalizations map[discordgo.Locale]string            `json:"description_localizations,omitempty"`
+	ChannelTypes             []discordgo.ChannelType                `json:"channel_types,omitempty"`
+	Required                 bool                                   `json:"required"`
+	Autocomplete             bool                                   `json:"autocomplete"`
+	Choices                  []choiceSignature                      `json:"choices,omitempty"`
+	MinValue                 *float64                               `json:"min_value,omitempty"`
+	MaxValue                 float64                                `json:"max_value,omitempty"`
+	MinLength                *int                                   `json:"min_length,omitempty"`
+	MaxLength                int                                    `json:"max_length,omitempty"`
+	Options                  []optionSignature                      `json:"options,omitempty"`
+}
+
+type choiceSignature struct {
+	Name              string                      `json:"name"`
+	NameLocalizations map[discordgo.Locale]string `json:"name_localizations,omitempty"`
+	// Discord回傳的數字會被解析成float64，統一轉成字串比對
+	Value string `json:"value"`
+}
+
+// 指令定義的版本(排序後的signature做sha256)
+func commandsVersion(cmds []*discordgo.ApplicationCommand) (string, error) {
+	sigs := make([]commandSignature, 0, len(cmds))
+	for _, cmd := range cmds {
+		sigs = append(sigs, newCommandSignature(cmd))
+	}
+	slices.SortFunc(sigs, func(a, b commandSignature) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	data, err := json.Marshal(sigs)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func newCommandSignature(cmd *discordgo.ApplicationCommand) commandSignature {
+	sig := commandSignature{
+		Type:                     cmd.Type,
+		Name:                     cmd.Name,
+		Description:              cmd.Description,
+		DefaultMemberPermissions: cmd.DefaultMemberPermissions,
+		Options:                  newOptionSignatures(cmd.Options),
+	}
+	if sig.Type == 0 {
+		sig.Type = discordgo.ChatApplicationCommand
+	}
+	if cmd.NameLocalizations != nil {
+		sig.NameLocalizations = *cmd.NameLocalizations
+	}
+	if cmd.DescriptionLocalizations != nil {
+		sig.DescriptionLocalizations = *cmd.DescriptionLocalizations
+	}
+	if cmd.NSFW != nil {
+		sig.NSFW = *cmd.NSFW
+	}
+	return sig
+}
+
+func newOptionSignatures(opts []*discordgo.ApplicationCommandOption) []optionSignature {
+	if len(opts) == 0 {
+		return nil
+	}
+
+	sigs := make([]optionSignature, 0, len(opts))
+	for _, opt := range opts {
+		sig := optionSignature{
+			Type:                     opt.Type,
+			Name:                     opt.Name,
+			NameLocalizations:        opt.NameLocalizations,
+			Description:              opt.Description,
+			DescriptionLocalizations: opt.DescriptionLocalizations,
+			ChannelTypes:             opt.ChannelTypes,
+			Required:                 opt.Required,
+			Autocomplete:             opt.Autocomplete,
+			MinValue:                 opt.MinValue,
+			MaxValue:                 opt.MaxValue,
+			MinLength:                opt.MinLength,
+			MaxLength:                opt.MaxLength,
+			Options:                  newOptionSignatures(opt.Options),
+		}
+		for _, c := range opt.Choices {
+			sig.Choices = append(sig.Choices, choiceSignature{
+				Name:              c.Name,
+				NameLocalizations: c.NameLocalizations,
+				Value:             fmt.Sprint(c.Value),
+			})
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs
+}
@@ -0,0 +1,214 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 記錄覆寫呼叫的 commandRegistrar，已註冊的指令依 guildID 分開
+type fakeRegistrar struct {
+	registered map[string][]*discordgo.ApplicationCommand
+	listErr    error
+	// 每次覆寫的guildID與指令
+	overwrites []overwriteCall
+}
+
+type overwriteCall struct {
+	guildID  string
+	commands []*discordgo.ApplicationCommand
+}
+
+func (f *fakeRegistrar) ApplicationCommands(appID, guildID string, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	return f.registered[guildID], nil
+}
+
+func (f *fakeRegistrar) ApplicationCommandBulkOverwrite(appID string, guildID string, commands []*discordgo.ApplicationCommand, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	f.overwrites = append(f.overwrites, overwriteCall{guildID: guildID, commands: commands})
+	return commands, nil
+}
+
+// 模擬Discord回傳的指令：經過一次JSON，並補上Discord產生的欄位
+func asRegistered(t *testing.T, cmds []*discordgo.ApplicationCommand) []*discordgo.ApplicationCommand {
+	t.Helper()
+	data, err := json.Marshal(cmds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []*discordgo.ApplicationCommand
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	for idx, cmd := range out {
+		cmd.ID = "id-" + cmd.Name
+		cmd.ApplicationID = "app"
+		cmd.Version = "ver"
+		if cmd.Type == 0 {
+			cmd.Type = discordgo.ChatApplicationCommand
+		}
+		out[idx] = cmd
+	}
+	return out
+}
+
+func testCommand(name, description string) *discordgo.ApplicationCommand {
+	minValue := 1.0
+	return &discordgo.ApplicationCommand{
+		Name:        name,
+		Description: description,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "page",
+				Description: "頁數",
+				MinValue:    &minValue,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "one", Value: 1},
+				},
+			},
+		},
+	}
+}
+
+func TestSyncCommands(t *testing.T) {
+	desired := []*discordgo.ApplicationCommand{testCommand("a", "first"), testCommand("b", "second")}
+
+	tests := []struct {
+		name       string
+		registered []*discordgo.ApplicationCommand
+		desired    []*discordgo.ApplicationCommand
+		// nil代表不應該覆寫
+		wantOverwrite []*discordgo.ApplicationCommand
+	}{
+		{
+			name:       "unchanged signature",
+			registered: asRegistered(t, desired),
+			desired:    desired,
+		},
+		{
+			name:       "unchanged in different order",
+			registered: asRegistered(t, []*discordgo.ApplicationCommand{desired[1], desired[0]}),
+			desired:    desired,
+		},
+		{
+			name:          "first register",
+			desired:       desired,
+			wantOverwrite: desired,
+		},
+		{
+			name:          "changed description",
+			registered:    asRegistered(t, []*discordgo.ApplicationCommand{testCommand("a", "old"), desired[1]}),
+			desired:       desired,
+			wantOverwrite: desired,
+		},
+		{
+			name:          "orphaned command",
+			registered:    asRegistered(t, append([]*discordgo.ApplicationCommand{testCommand("old", "removed")}, desired...)),
+			desired:       desired,
+			wantOverwrite: desired,
+		},
+		{
+			name:          "clear all",
+			registered:    asRegistered(t, desired),
+			wantOverwrite: []*discordgo.ApplicationCommand{},
+		},
+		{
+			name: "nothing to clear",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &fakeRegistrar{registered: map[string][]*discordgo.ApplicationCommand{"": tt.registered}}
+			if err := syncCommands(r, "app", "", tt.desired); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantOverwrite == nil {
+				if len(r.overwrites) != 0 {
+					t.Errorf("overwrote %d times, want none", len(r.overwrites))
+				}
+				return
+			}
+			if len(r.overwrites) != 1 {
+				t.Fatalf("overwrote %d times, want 1", len(r.overwrites))
+			}
+			// 清空時要送空陣列而不是null
+			if got := r.overwrites[0].commands; got == nil || !reflect.DeepEqual(got, tt.wantOverwrite) {
+				t.Errorf("overwrite commands = %v, want %v", got, tt.wantOverwrite)
+			}
+		})
+	}
+}
+
+func TestSyncCommandsListError(t *testing.T) {
+	listErr := errors.New("discord down")
+	r := &fakeRegistrar{listErr: listErr}
+	if err := syncCommands(r, "app", "", desiredCommands()); !errors.Is(err, listErr) {
+		t.Errorf("syncCommands() error = %v, want %v", err, listErr)
+	}
+	if len(r.overwrites) != 0 {
+		t.Errorf("overwrote %d times after list error", len(r.overwrites))
+	}
+}
+
+// 實際的指令定義經過Discord回傳後也要判斷成沒有變動
+func TestDesiredCommandsStable(t *testing.T) {
+	desired := desiredCommands()
+	if len(desired) == 0 {
+		t.Fatal("no commands registered in commandMap")
+	}
+	r := &fakeRegistrar{registered: map[string][]*discordgo.ApplicationCommand{"": asRegistered(t, desired)}}
+	if err := syncCommands(r, "app", "", desired); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.overwrites) != 0 {
+		t.Errorf("real definitions overwritten %d times, want none", len(r.overwrites))
+	}
+}
+
+// COMMAND_GUILD_ID 決定註冊的範圍，COMMAND_CLEAR_GLOBAL 只在開發伺服器模式下清掉全域指令
+func TestRegisterCommandsScope(t *testing.T) {
+	desired := []*discordgo.ApplicationCommand{testCommand("a", "first")}
+	old := asRegistered(t, []*discordgo.ApplicationCommand{testCommand("a", "old")})
+
+	tests := []struct {
+		name        string
+		guildID     string
+		clearGlobal string
+		// 依照順序應該被覆寫的範圍，以及覆寫的指令數量
+		wantGuilds []string
+		wantCounts []int
+	}{
+		{name: "global", wantGuilds: []string{""}, wantCounts: []int{1}},
+		{name: "clear global ignored without guild", clearGlobal: "true", wantGuilds: []string{""}, wantCounts: []int{1}},
+		{name: "guild", guildID: " 123 ", wantGuilds: []string{"123"}, wantCounts: []int{1}},
+		{name: "guild and clear global", guildID: "123", clearGlobal: "TRUE", wantGuilds: []string{"123", ""}, wantCounts: []int{1, 0}},
+		{name: "guild and clear global off", guildID: "123", clearGlobal: "false", wantGuilds: []string{"123"}, wantCounts: []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("COMMAND_GUILD_ID", tt.guildID)
+			t.Setenv("COMMAND_CLEAR_GLOBAL", tt.clearGlobal)
+
+			// 全域與伺服器都有舊的定義
+			r := &fakeRegistrar{registered: map[string][]*discordgo.ApplicationCommand{"": old, "123": old}}
+			registerCommands(r, "app", desired)
+
+			var guilds []string
+			var counts []int
+			for _, call := range r.overwrites {
+				guilds = append(guilds, call.guildID)
+				counts = append(counts, len(call.commands))
+			}
+			if !reflect.DeepEqual(guilds, tt.wantGuilds) || !reflect.DeepEqual(counts, tt.wantCounts) {
+				t.Errorf("overwrites = %v %v, want %v %v", guilds, counts, tt.wantGuilds, tt.wantCounts)
+			}
+		})
+	}
+}
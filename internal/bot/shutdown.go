@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 關閉期間收到新Interaction時的回覆
+const maintenanceMessage = "機器人正在維護或重新啟動，請稍後再試一次"
+
+var (
+	// 所有handler的ctx都從這裡衍生，等待逾時後取消
+	baseCtx, cancelBase = context.WithCancel(context.Background())
+
+	// 保護 draining 與 inflight.Add，避免關閉時 Add 與 Wait 同時發生
+	lifecycleMu sync.Mutex
+	draining    bool
+	inflight    sync.WaitGroup
+	// 執行中的handler數量(log用)
+	inflightCount atomic.Int64
+)
+
+// 登記一個即將執行的handler，關閉中時回傳false
+func acquireWorker() bool {
+	lifecycleMu.Lock()
+	defer lifecycleMu.Unlock()
+
+	if draining {
+		return false
+	}
+	inflight.Add(1)
+	inflightCount.Add(1)
+	return true
+}
+
+func releaseWorker() {
+	inflightCount.Add(-1)
+	inflight.Done()
+}
+
+// 停止接受新的Interaction並等待執行中的handler結束
+//
+// 超過timeout時取消所有handler的ctx，回傳還沒結束的handler數量
+func Drain(timeout time.Duration) int64 {
+	lifecycleMu.Lock()
+	draining = true
+	lifecycleMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0
+	case <-time.After(timeout):
+		cancelBase()
+		return inflightCount.Load()
+	}
+}
@@ -1,9 +1,15 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"kurohelper/internal/tracing"
 	"kurohelperservice"
 	"kurohelperservice/provider/bangumi"
 	"kurohelperservice/provider/erogs"
@@ -15,83 +21,205 @@ import (
 // 對每一次查詢建立CID以及關鍵字的關聯
 // 因為CID不允許過長字元，所以遇到很長的關鍵字時會直接丟錯，所以才需要這層快取
 var (
-	CIDV2Store = NewCacheStoreV2[string](time.Hour)
+	CIDV2Store = NewCacheStoreV2[CIDEntry]("CIDV2Store", time.Hour, WithCleanInterval(time.Hour))
 )
 
+// CIDV2Store 的資料
+type CIDEntry struct {
+	// 列表資料的快取key(只用來綁定擁有者時為空)
+	Key string
+	// 執行指令的使用者Discord ID，用來限制只有本人可以操作元件
+	OwnerID string
+}
+
+// 取得CID的擁有者，沒有紀錄時回傳false
+func CIDOwner(cacheID string) (string, bool) {
+	entry, err := CIDV2Store.Get(cacheID)
+	if err != nil || entry.OwnerID == "" {
+		return "", false
+	}
+	return entry.OwnerID, true
+}
+
 // 更通用的CID快取，給CID V3用
 var (
-	CIDV3Store = NewCacheStoreV2[any](time.Hour)
+	CIDV3Store = NewCacheStoreV2[any]("CIDV3Store", time.Hour, WithCleanInterval(time.Hour))
 )
 
 // 批評空間快取
 var (
 	// 使用關鍵字Base64作為鍵
-	ErogsGameListStore = NewCacheStoreV2[[]erogs.GameList](cacheLostTime)
+	ErogsGameListStore = NewCacheStoreV2[[]erogs.GameList]("ErogsGameListStore", cacheLostTime, WithNegativeCache())
 	// 使用批評空間ID作為鍵
-	ErogsGameStore = NewCacheStoreV2[*erogs.Game](cacheLostTime)
+	ErogsGameStore = NewCacheStoreV2[*erogs.Game]("ErogsGameStore", cacheLostTime, WithNegativeCache(), WithStaleWhileRevalidate())
 	// 使用關鍵字Base64作為鍵
-	ErogsMusicListStore = NewCacheStoreV2[[]erogs.MusicList](cacheLostTime)
+	ErogsMusicListStore = NewCacheStoreV2[[]erogs.MusicList]("ErogsMusicListStore", cacheLostTime, WithNegativeCache())
 	// 使用批評空間ID作為鍵
-	ErogsMusicStore = NewCacheStoreV2[*erogs.Music](cacheLostTime)
+	ErogsMusicStore = NewCacheStoreV2[*erogs.Music]("ErogsMusicStore", cacheLostTime, WithNegativeCache())
 	// 使用關鍵字Base64作為鍵
-	ErogsBrandStore = NewCacheStoreV2[*erogs.Brand](cacheLostTime)
+	ErogsBrandStore = NewCacheStoreV2[*erogs.Brand]("ErogsBrandStore", cacheLostTime, WithNegativeCache())
 	// 創作者列表：使用關鍵字 Base64 作為鍵
-	ErogsCreatorListStore = NewCacheStoreV2[[]erogs.CreatorList](cacheLostTime)
+	ErogsCreatorListStore = NewCacheStoreV2[[]erogs.CreatorList]("ErogsCreatorListStore", cacheLostTime, WithNegativeCache())
 	// 歌手列表：使用關鍵字 Base64 作為鍵
-	ErogsSingerListStore = NewCacheStoreV2[[]erogs.CreatorList](cacheLostTime)
+	ErogsSingerListStore = NewCacheStoreV2[[]erogs.CreatorList]("ErogsSingerListStore", cacheLostTime, WithNegativeCache())
 	// 創作者詳情：使用 "e" + 創作者 ID 作為鍵
-	ErogsCreatorStore = NewCacheStoreV2[*erogs.Creator](cacheLostTime)
+	ErogsCreatorStore = NewCacheStoreV2[*erogs.Creator]("ErogsCreatorStore", cacheLostTime, WithNegativeCache())
 	// 歌手詳情：使用 "e" + 歌手 ID 作為鍵
-	ErogsSingerStore = NewCacheStoreV2[*erogs.Singer](cacheLostTime)
+	ErogsSingerStore = NewCacheStoreV2[*erogs.Singer]("ErogsSingerStore", cacheLostTime, WithNegativeCache())
 )
 
 // VNDB快取
 var (
 	// 使用關鍵字Base64作為鍵
-	VndbGameListStore = NewCacheStoreV2[[]vndb.GetVnIDUseListResponse](cacheLostTime)
+	VndbGameListStore = NewCacheStoreV2[[]vndb.GetVnIDUseListResponse]("VndbGameListStore", cacheLostTime, WithNegativeCache())
 	// 使用VNDB ID作為鍵 (遊戲詳細資料,可被遊戲搜尋和品牌搜尋共用)
-	VndbGameStore = NewCacheStoreV2[*vndb.BasicResponse[vndb.GetVnUseIDResponse]](cacheLostTime)
+	VndbGameStore = NewCacheStoreV2[*vndb.BasicResponse[vndb.GetVnUseIDResponse]]("VndbGameStore", cacheLostTime, WithNegativeCache(), WithStaleWhileRevalidate())
+	// 使用VNDB ID作為鍵，值為對應的批評空間遊戲("e" + 遊戲ID)，切換資料來源使用
+	VndbErogsMapStore = NewCacheStoreV2[string]("VndbErogsMapStore", cacheLostTime, WithNegativeCache())
 	// 使用關鍵字Base64作為鍵
-	VndbBrandStore = NewCacheStoreV2[*vndb.ProducerSearchResponse](cacheLostTime)
+	VndbBrandStore = NewCacheStoreV2[*vndb.ProducerSearchResponse]("VndbBrandStore", cacheLostTime, WithNegativeCache())
 	// 角色列表：使用關鍵字 Base64 作為鍵
-	VndbCharacterListStore = NewCacheStoreV2[[]vndb.CharacterSearchResponse](cacheLostTime)
+	VndbCharacterListStore = NewCacheStoreV2[[]vndb.CharacterSearchResponse]("VndbCharacterListStore", cacheLostTime, WithNegativeCache())
 	// 角色詳情：使用 VNDB 角色 ID（如 c123）作為鍵
-	VndbCharacterStore = NewCacheStoreV2[*vndb.CharacterSearchResponse](cacheLostTime)
+	VndbCharacterStore = NewCacheStoreV2[*vndb.CharacterSearchResponse]("VndbCharacterStore", cacheLostTime, WithNegativeCache(), WithStaleWhileRevalidate())
 )
 
 // Bangumi快取(因為沒有任何CID事件，所以直接拿搜尋關鍵字做 base64 對應實際資料)
 var (
-	BangumiCharacterStore = NewCacheStoreV2[*bangumi.Character](cacheLostTime)
+	BangumiCharacterStore = NewCacheStoreV2[*bangumi.Character]("BangumiCharacterStore", cacheLostTime, WithNegativeCache())
 )
 
+// Autocomplete從查詢結果學到的名稱，使用來源名稱作為鍵，跟著硬碟快照保存，重啟後還原
+var AutocompleteLearnedStore = NewCacheStoreV2[[]string]("AutocompleteLearnedStore", 30*24*time.Hour)
+
 // 使用者相關快取(混合資料型態)
-var UserInfoCache = NewCacheStoreV2[any](10 * time.Minute)
+var UserInfoCache = NewCacheStoreV2[any]("UserInfoCache", 10*time.Minute, WithoutPersist(), WithCleanInterval(10*time.Minute))
 
 // 月幕快取
 // var (
-// 	YmgalGame = NewCacheStoreV2[*ymgal.SearchGameResp](time.Hour)
+// 	YmgalGame = NewCacheStoreV2[*ymgal.SearchGameResp]("YmgalGame", time.Hour)
 // )
 
 // cache struct
 type CacheV2[T any] struct {
-	Value    T
+	Value T
+	// 超過後資料視為過時，GetOrLoad 會先回傳舊資料並在背景更新(沒有開啟 WithStaleWhileRevalidate 時等於 ExpireAt)
+	RefreshAt time.Time
+	// 超過後資料直接丟棄
 	ExpireAt time.Time
 }
 
 // CacheStoreV2 泛型快取儲存
 // data的鍵值為UUID
 type CacheStoreV2[T any] struct {
-	data       map[string]*CacheV2[T]
-	expireTime time.Duration
-	mu         sync.RWMutex
+	name          string
+	storage       Storage[T]
+	expireTime    time.Duration
+	cleanInterval time.Duration
+	persist       bool
+	mu            sync.Mutex
+	// GetOrLoad 使用
+	flight flightGroup[T]
+	// 查無資料的key與過期時間(只有開啟 WithNegativeCache 才會使用)
+	negativeCache bool
+	negative      map[string]time.Time
+	// 過時資料背景更新(只有開啟 WithStaleWhileRevalidate 才會使用)
+	staleWhileRevalidate bool
+	refreshing           map[string]struct{}
+	// 命中統計
+	hits   atomic.Int64
+	stale  atomic.Int64
+	misses atomic.Int64
+}
+
+// 建立快取儲存時的選項
+type StoreOption func(*storeOptions)
+
+type storeOptions struct {
+	cleanInterval        time.Duration
+	persist              bool
+	negativeCache        bool
+	staleWhileRevalidate bool
+}
+
+// 清除過期資料的間隔(預設使用 CleanCacheJob 的間隔)
+func WithCleanInterval(d time.Duration) StoreOption {
+	return func(o *storeOptions) {
+		o.cleanInterval = d
+	}
+}
+
+// 不寫入硬碟(存放無法序列化的資料，或是存活時間很短的快取使用)
+func WithoutPersist() StoreOption {
+	return func(o *storeOptions) {
+		o.persist = false
+	}
+}
+
+// 快取查無資料(ErrSearchNoContent)的結果，短時間內不再重複查詢上游
+//
+// 存活時間由 InitNegativeCacheTime 設定
+func WithNegativeCache() StoreOption {
+	return func(o *storeOptions) {
+		o.negativeCache = true
+	}
+}
+
+// 超過存活時間後不直接丟棄，GetOrLoad 會先回傳舊資料並在背景重新查詢
+//
+// 舊資料最多再保留 InitStaleTime 設定的時間
+func WithStaleWhileRevalidate() StoreOption {
+	return func(o *storeOptions) {
+		o.staleWhileRevalidate = true
+	}
 }
 
 // NewCacheStore 建立新的快取儲存
-func NewCacheStoreV2[T any](expireTime time.Duration) *CacheStoreV2[T] {
-	return &CacheStoreV2[T]{
-		data:       make(map[string]*CacheV2[T]),
-		expireTime: expireTime,
+//
+// name 用來當作硬碟快照的檔名，每個快取必須唯一
+func NewCacheStoreV2[T any](name string, expireTime time.Duration, opts ...StoreOption) *CacheStoreV2[T] {
+	o := storeOptions{persist: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := &CacheStoreV2[T]{
+		name:          name,
+		storage:       NewMemoryStorage[T](),
+		expireTime:    expireTime,
+		cleanInterval: o.cleanInterval,
+		persist:       o.persist,
+
+		negativeCache: o.negativeCache,
+		negative:      make(map[string]time.Time),
+
+		staleWhileRevalidate: o.staleWhileRevalidate,
+		refreshing:           make(map[string]struct{}),
 	}
+	register(c)
+	return c
+}
+
+// 快取名稱
+func (c *CacheStoreV2[T]) Name() string {
+	return c.name
+}
+
+// 清除過期資料的間隔
+func (c *CacheStoreV2[T]) CleanInterval() time.Duration {
+	return c.cleanInterval
+}
+
+// 更換底層儲存，原本的資料會搬到新的儲存
+func (c *CacheStoreV2[T]) UseStorage(storage Storage[T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.storage.Range(func(key string, item *CacheV2[T]) bool {
+		storage.Set(key, item)
+		return true
+	})
+	c.storage = storage
 }
 
 // Set 設定快取
@@ -99,28 +227,182 @@ func (c *CacheStoreV2[T]) Set(key string, value T) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.data[key] = &CacheV2[T]{
-		Value:    value,
-		ExpireAt: time.Now().Add(c.expireTime),
+	refreshAt := time.Now().Add(c.expireTime)
+	expireAt := refreshAt
+	if c.staleWhileRevalidate {
+		expireAt = expireAt.Add(staleTime)
 	}
+	c.storage.Set(key, &CacheV2[T]{
+		Value:     value,
+		RefreshAt: refreshAt,
+		ExpireAt:  expireAt,
+	})
+	delete(c.negative, key)
+}
+
+// Delete 刪除快取(包含查無資料的紀錄)
+func (c *CacheStoreV2[T]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.storage.Delete(key)
+	delete(c.negative, key)
+}
+
+// 快取資料的狀態
+type entryState int
+
+const (
+	entryMissing entryState = iota
+	entryFresh
+	entryStale
+)
+
+// 查詢快取但不計入統計
+func (c *CacheStoreV2[T]) lookup(key string) (T, entryState) {
+	// LRU讀取時會調整順序，所以一律使用寫鎖
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.storage.Get(key)
+
+	// 不存在或已過期
+	now := time.Now()
+	if !ok || now.After(item.ExpireAt) {
+		c.storage.Delete(key)
+		var zero T
+		return zero, entryMissing
+	}
+	if now.After(item.RefreshAt) {
+		return item.Value, entryStale
+	}
+	return item.Value, entryFresh
 }
 
 // Get 從快取中取得資料
+//
+// 過時但還沒超過硬性期限的資料也會回傳
 func (c *CacheStoreV2[T]) Get(key string) (T, error) {
-	c.mu.RLock()
-	item, ok := c.data[key]
-	c.mu.RUnlock()
+	value, state := c.lookup(key)
+	switch state {
+	case entryFresh:
+		c.hits.Add(1)
+	case entryStale:
+		c.stale.Add(1)
+	default:
+		c.misses.Add(1)
+		return value, kurohelperservice.ErrCacheLost
+	}
+	return value, nil
+}
 
-	// 不存在或已過期
-	if !ok || time.Now().After(item.ExpireAt) {
-		c.mu.Lock()
-		delete(c.data, key)
-		c.mu.Unlock()
+// GetOrLoad 從快取取得資料，沒有的話呼叫loader查詢並存入快取
+//
+// 同一個key同時只會呼叫一次loader，其他呼叫者會等待並共用結果；
+// 開啟 WithNegativeCache 時，loader回傳 ErrSearchNoContent 也會被快取一段時間；
+// 開啟 WithStaleWhileRevalidate 時，過時資料會直接回傳並在背景呼叫loader更新；
+// loader 要使用傳入的ctx，背景更新時會換成不會跟著請求結束的ctx；
+// 命中狀態會記錄到ctx中的 tracing.Trace
+func (c *CacheStoreV2[T]) GetOrLoad(ctx context.Context, key string, loader func(ctx context.Context) (T, error)) (T, error) {
+	trace := tracing.FromContext(ctx)
+	value, state := c.lookup(key)
+	switch state {
+	case entryFresh:
+		c.hits.Add(1)
+		trace.RecordCache(tracing.CacheHit)
+		return value, nil
+	case entryStale:
+		c.stale.Add(1)
+		trace.RecordCache(tracing.CacheStale)
+		c.refreshInBackground(ctx, key, loader)
+		return value, nil
+	}
+	if c.isNegative(key) {
+		c.hits.Add(1)
+		trace.RecordCache(tracing.CacheHit)
 		var zero T
-		return zero, kurohelperservice.ErrCacheLost
+		return zero, kurohelperservice.ErrSearchNoContent
 	}
+	c.misses.Add(1)
+	trace.RecordCache(tracing.CacheMiss)
+
+	value, err, _ := c.flight.do(key, func() (T, error) {
+		// 等待期間可能已經有其他人寫入
+		if value, state := c.lookup(key); state == entryFresh {
+			return value, nil
+		}
 
-	return item.Value, nil
+		value, err := loader(ctx)
+		if err != nil {
+			if c.negativeCache && errors.Is(err, kurohelperservice.ErrSearchNoContent) {
+				c.mu.Lock()
+				c.negative[key] = time.Now().Add(negativeCacheTime)
+				c.mu.Unlock()
+			}
+			return value, err
+		}
+		c.Set(key, value)
+		return value, nil
+	})
+	return value, err
+}
+
+// 背景更新的時間上限
+const backgroundRefreshTimeout = 30 * time.Second
+
+// 背景更新過時資料，同一個key同時只會有一個更新在執行
+//
+// 請求的ctx在回應後就會被取消，所以改用 context.WithoutCancel(保留trace等資料)加上時間上限；
+// 更新失敗時保留舊資料，直到超過硬性期限
+func (c *CacheStoreV2[T]) refreshInBackground(ctx context.Context, key string, loader func(ctx context.Context) (T, error)) {
+	c.mu.Lock()
+	if _, ok := c.refreshing[key]; ok {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = struct{}{}
+	c.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundRefreshTimeout)
+		defer func() {
+			cancel()
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+
+		_, err, _ := c.flight.do(key, func() (T, error) {
+			value, err := loader(ctx)
+			if err != nil {
+				return value, err
+			}
+			c.Set(key, value)
+			return value, nil
+		})
+		if err != nil {
+			slog.Warn(fmt.Sprintf("cache: %s 背景更新失敗: %s", c.name, err.Error()), "key", key)
+		}
+	}()
+}
+
+func (c *CacheStoreV2[T]) isNegative(key string) bool {
+	if !c.negativeCache {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expireAt, ok := c.negative[key]
+	if !ok {
+		return false
+	}
+	if time.Now().After(expireAt) {
+		delete(c.negative, key)
+		return false
+	}
+	return true
 }
 
 // Clean 清除過期快取
@@ -128,11 +410,23 @@ func (c *CacheStoreV2[T]) Clean() (deleteCount int, total int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	total = len(c.data)
-	for k, d := range c.data {
-		if time.Now().After(d.ExpireAt) {
-			delete(c.data, k)
-			deleteCount++
+	total = c.storage.Len()
+	now := time.Now()
+	var expired []string
+	c.storage.Range(func(key string, item *CacheV2[T]) bool {
+		if now.After(item.ExpireAt) {
+			expired = append(expired, key)
+		}
+		return true
+	})
+	for _, k := range expired {
+		c.storage.Delete(k)
+	}
+	deleteCount = len(expired)
+
+	for k, expireAt := range c.negative {
+		if now.After(expireAt) {
+			delete(c.negative, k)
 		}
 	}
 
@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kurohelperservice"
+)
+
+// 同一個key同時未命中時只會呼叫一次loader，所有人拿到同一個結果
+func TestGetOrLoadSharesConcurrentMisses(t *testing.T) {
+	c := newTestStore[int](t, time.Hour, WithoutPersist())
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]int, callers)
+	errs := make([]error, callers)
+	for idx := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[idx], errs[idx] = c.GetOrLoad(context.Background(), "k", loader)
+		}()
+	}
+
+	// 等第一個呼叫者進入loader後再放行
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("loader called %d times, want 1", got)
+	}
+	for idx := range callers {
+		if errs[idx] != nil || results[idx] != 42 {
+			t.Errorf("caller %d = %d, %v, want 42", idx, results[idx], errs[idx])
+		}
+	}
+
+	// 之後直接命中快取
+	if _, err := c.GetOrLoad(context.Background(), "k", loader); err != nil || calls.Load() != 1 {
+		t.Errorf("cached GetOrLoad error = %v, calls = %d", err, calls.Load())
+	}
+	if st := c.Stats(); st.Hits != 1 {
+		t.Errorf("Hits = %d, want 1", st.Hits)
+	}
+}
+
+func TestGetOrLoadNegativeCache(t *testing.T) {
+	old := negativeCacheTime
+	negativeCacheTime = 50 * time.Millisecond
+	t.Cleanup(func() { negativeCacheTime = old })
+
+	tests := []struct {
+		name      string
+		opts      []StoreOption
+		err       error
+		wantCalls int32
+	}{
+		{name: "no content is cached", opts: []StoreOption{WithNegativeCache()}, err: kurohelperservice.ErrSearchNoContent, wantCalls: 1},
+		{name: "other errors are not cached", opts: []StoreOption{WithNegativeCache()}, err: errors.New("upstream down"), wantCalls: 2},
+		{name: "without negative cache", err: kurohelperservice.ErrSearchNoContent, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestStore[[]int](t, time.Hour, append(tt.opts, WithoutPersist())...)
+			var calls atomic.Int32
+			loader := func(context.Context) ([]int, error) {
+				calls.Add(1)
+				return nil, tt.err
+			}
+
+			for range 2 {
+				if _, err := c.GetOrLoad(context.Background(), "k", loader); !errors.Is(err, tt.err) {
+					t.Fatalf("GetOrLoad() error = %v, want %v", err, tt.err)
+				}
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("loader called %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+
+	// 超過存活時間後重新查詢，Set 會清掉查無資料的紀錄
+	t.Run("expires", func(t *testing.T) {
+		c := newTestStore[[]int](t, time.Hour, WithNegativeCache(), WithoutPersist())
+		var calls atomic.Int32
+		loader := func(context.Context) ([]int, error) {
+			calls.Add(1)
+			return nil, kurohelperservice.ErrSearchNoContent
+		}
+		c.GetOrLoad(context.Background(), "k", loader)
+		time.Sleep(2 * negativeCacheTime)
+		c.GetOrLoad(context.Background(), "k", loader)
+		if got := calls.Load(); got != 2 {
+			t.Errorf("loader called %d times, want 2", got)
+		}
+
+		c.Set("k", []int{1})
+		got, err := c.GetOrLoad(context.Background(), "k", loader)
+		if err != nil || len(got) != 1 {
+			t.Errorf("GetOrLoad() after Set = %v, %v", got, err)
+		}
+	})
+}
+
+// loader panic時轉成錯誤回傳，之後同一個key可以重新載入
+func TestGetOrLoadLoaderPanic(t *testing.T) {
+	c := newTestStore[int](t, time.Hour, WithoutPersist())
+
+	_, err := c.GetOrLoad(context.Background(), "k", func(context.Context) (int, error) {
+		panic("boom")
+	})
+	if !errors.Is(err, errLoaderPanic) {
+		t.Fatalf("GetOrLoad() error = %v, want errLoaderPanic", err)
+	}
+
+	got, err := c.GetOrLoad(context.Background(), "k", func(context.Context) (int, error) { return 1, nil })
+	if err != nil || got != 1 {
+		t.Errorf("retry = %d, %v, want 1", got, err)
+	}
+}
+
+// 過時資料先回傳舊值，背景更新使用不會跟著請求取消、但有時間上限的ctx
+func TestGetOrLoadStaleWhileRevalidate(t *testing.T) {
+	c := newTestStore[int](t, 20*time.Millisecond, WithStaleWhileRevalidate(), WithoutPersist())
+	c.Set("k", 1)
+	time.Sleep(30 * time.Millisecond)
+
+	type loadCtx struct {
+		err         error
+		hasDeadline bool
+	}
+	loaded := make(chan loadCtx, 1)
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (int, error) {
+		<-release
+		_, hasDeadline := ctx.Deadline()
+		loaded <- loadCtx{err: ctx.Err(), hasDeadline: hasDeadline}
+		return 2, nil
+	}
+
+	// 請求在拿到結果後就結束了
+	ctx, cancel := context.WithCancel(context.Background())
+	got, err := c.GetOrLoad(ctx, "k", loader)
+	cancel()
+	if err != nil || got != 1 {
+		t.Fatalf("GetOrLoad() = %d, %v, want stale 1", got, err)
+	}
+	if st := c.Stats(); st.Stale != 1 {
+		t.Errorf("Stale = %d, want 1", st.Stale)
+	}
+
+	// 更新進行中再次讀取不會重複觸發
+	if got, _ := c.GetOrLoad(context.Background(), "k", loader); got != 1 {
+		t.Errorf("second GetOrLoad() = %d, want stale 1", got)
+	}
+
+	close(release)
+	select {
+	case lc := <-loaded:
+		if lc.err != nil {
+			t.Errorf("background loader ctx error = %v, want nil after request was canceled", lc.err)
+		}
+		if !lc.hasDeadline {
+			t.Error("background loader ctx has no deadline")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("background refresh did not run")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if v, err := c.Get("k"); err == nil && v == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("refreshed value was not stored")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if len(loaded) != 0 {
+		t.Error("loader ran more than once")
+	}
+}
+
+// 背景更新失敗時保留舊資料
+func TestGetOrLoadStaleRefreshFailure(t *testing.T) {
+	c := newTestStore[int](t, 20*time.Millisecond, WithStaleWhileRevalidate(), WithoutPersist())
+	c.Set("k", 1)
+	time.Sleep(30 * time.Millisecond)
+
+	done := make(chan struct{})
+	got, err := c.GetOrLoad(context.Background(), "k", func(context.Context) (int, error) {
+		defer close(done)
+		return 0, errors.New("upstream down")
+	})
+	if err != nil || got != 1 {
+		t.Fatalf("GetOrLoad() = %d, %v, want stale 1", got, err)
+	}
+	<-done
+
+	// 等背景更新結束(refreshing 清掉)再確認
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c.mu.Lock()
+		_, running := c.refreshing["k"]
+		c.mu.Unlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if v, err := c.Get("k"); err != nil || v != 1 {
+		t.Errorf("Get() = %d, %v, want 1", v, err)
+	}
+}
@@ -3,8 +3,22 @@ package cache
 import "time"
 
 // default
-var cacheLostTime = 4 * time.Hour
+var (
+	cacheLostTime     = 4 * time.Hour
+	negativeCacheTime = 5 * time.Minute
+	staleTime         = 24 * time.Hour
+)
 
 func InitCacheLostTime(hours int) {
 	cacheLostTime = time.Duration(hours) * time.Hour
 }
+
+// 查無資料結果的快取時間
+func InitNegativeCacheTime(minutes int) {
+	negativeCacheTime = time.Duration(minutes) * time.Minute
+}
+
+// 過時資料在背景更新期間最多保留多久(超過後直接丟棄)
+func InitStaleTime(hours int) {
+	staleTime = time.Duration(hours) * time.Hour
+}
@@ -3,52 +3,90 @@ package cache
 import (
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 )
 
+// 沒有設定清除間隔時使用
+const defaultCleanInterval = 12 * time.Hour
+
 // 清除Cache排程
 //
-// 先不檢查快取存活時間，統一全部清除
-func CleanCacheJob(minute time.Duration, stopChan <-chan struct{}) {
+// 每個已註冊的快取依照自己的 CleanInterval 清除過期資料(沒有設定時使用 interval)，
+// 另外每隔 interval 輸出一次命中統計；有設定硬碟快照時依照 SnapshotInterval 另外寫入快照
+func CleanCacheJob(interval time.Duration, stopChan <-chan struct{}) {
 	slog.Info("CleanCacheJob 正在啟動...")
-	ticker := time.NewTicker(minute * time.Hour)
+	if interval <= 0 {
+		interval = defaultCleanInterval
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range Stores() {
+		storeInterval := s.CleanInterval()
+		if storeInterval <= 0 {
+			storeInterval = interval
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cleanStoreLoop(s, storeInterval, stopChan)
+		}()
+	}
+	if storageConfig.PersistDir != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			snapshotLoop(storageConfig.SnapshotInterval, stopChan)
+		}()
+	}
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			// 清除Cache
-			egsDC, egsC := UserInfoCache.Clean() // 混和型態快取
-			slog.Info(fmt.Sprintf("UserInfoCache          快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = CIDV2Store.Clean()
-			slog.Info(fmt.Sprintf("CIDV2Store             快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = CIDV3Store.Clean()
-			slog.Info(fmt.Sprintf("CIDV3Store             快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = ErogsGameListStore.Clean()
-			slog.Info(fmt.Sprintf("ErogsGameListStore     快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = ErogsGameStore.Clean()
-			slog.Info(fmt.Sprintf("ErogsGameStore         快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = ErogsMusicListStore.Clean()
-			slog.Info(fmt.Sprintf("ErogsMusicListStore    快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = ErogsMusicStore.Clean()
-			slog.Info(fmt.Sprintf("ErogsMusicStore        快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = ErogsCreatorListStore.Clean()
-			slog.Info(fmt.Sprintf("ErogsCreatorListStore  快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = ErogsCreatorStore.Clean()
-			slog.Info(fmt.Sprintf("ErogsCreatorStore      快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = ErogsSingerListStore.Clean()
-			slog.Info(fmt.Sprintf("ErogsSingerListStore   快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = ErogsSingerStore.Clean()
-			slog.Info(fmt.Sprintf("ErogsSingerStore       快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = VndbCharacterListStore.Clean()
-			slog.Info(fmt.Sprintf("VndbCharacterListStore 快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = VndbCharacterStore.Clean()
-			slog.Info(fmt.Sprintf("VndbCharacterStore     快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
-			egsDC, egsC = BangumiCharacterStore.Clean()
-			slog.Info(fmt.Sprintf("BangumiCharacterStore  快取資料: %d筆/%d筆 (清理/總數)", egsDC, egsC))
+			// 命中統計
+			for _, st := range AllStats() {
+				if st.Hits+st.Stale+st.Misses == 0 {
+					continue
+				}
+				slog.Info(fmt.Sprintf("%-22s 命中統計: %d/%d/%d (命中/過時/未命中)", st.Name, st.Hits, st.Stale, st.Misses))
+			}
 
 		case <-stopChan:
+			wg.Wait()
 			slog.Info("CleanCacheJob 正在關閉...")
 			return
 		}
 	}
 }
+
+func cleanStoreLoop(s Store, interval time.Duration, stopChan <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			deleteCount, total := s.Clean()
+			slog.Info(fmt.Sprintf("%-22s 快取資料: %d筆/%d筆 (清理/總數)", s.Name(), deleteCount, total))
+		case <-stopChan:
+			return
+		}
+	}
+}
+
+// 定期寫入硬碟快照，讓非正常結束時最多只遺失一個間隔的資料(正常關閉時由呼叫端再 SaveAll 一次)
+func snapshotLoop(interval time.Duration, stopChan <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := SaveAll(); err != nil {
+				slog.Warn(fmt.Sprintf("cache: save snapshot failed: %s", err.Error()))
+			}
+		case <-stopChan:
+			return
+		}
+	}
+}
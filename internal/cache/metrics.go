@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"kurohelper/internal/metrics"
+)
+
+// 抓取時才讀取 AllStats，不需要在每次查詢時另外計數
+func init() {
+	metrics.NewCollectorFunc(
+		"kurohelper_cache_entries",
+		"Number of entries currently held by each cache store.",
+		"gauge",
+		[]string{"cache"},
+		func(emit func(float64, ...string)) {
+			for _, st := range AllStats() {
+				emit(float64(st.Entries), st.Name)
+			}
+		},
+	)
+	metrics.NewCollectorFunc(
+		"kurohelper_cache_requests_total",
+		"Number of cache lookups by cache store and result (hit, stale, miss).",
+		"counter",
+		[]string{"cache", "result"},
+		func(emit func(float64, ...string)) {
+			for _, st := range AllStats() {
+				emit(float64(st.Hits), st.Name, "hit")
+				emit(float64(st.Stale), st.Name, "stale")
+				emit(float64(st.Misses), st.Name, "miss")
+			}
+		},
+	)
+}
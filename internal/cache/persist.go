@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 快取儲存設定
+type StorageConfig struct {
+	// 每個快取最多保留幾筆資料(LRU淘汰)，<= 0 代表不限制
+	MaxEntries int
+	// 硬碟快照資料夾，空字串代表只放在記憶體
+	PersistDir string
+	// 寫入快照的間隔，<= 0 時使用 defaultSnapshotInterval
+	SnapshotInterval time.Duration
+}
+
+// 沒有設定快照間隔時使用
+const defaultSnapshotInterval = 10 * time.Minute
+
+var storageConfig StorageConfig
+
+// 依照設定替換所有快取的底層儲存，並從硬碟快照還原資料
+//
+// 需要在開始處理指令之前呼叫
+func InitStorage(cfg StorageConfig) {
+	storageConfig = cfg
+	if storageConfig.SnapshotInterval <= 0 {
+		storageConfig.SnapshotInterval = defaultSnapshotInterval
+	}
+	if cfg.PersistDir != "" {
+		if err := os.MkdirAll(cfg.PersistDir, 0o755); err != nil {
+			slog.Error(fmt.Sprintf("cache: create persist dir failed: %s", err.Error()))
+			storageConfig.PersistDir = ""
+		}
+	}
+
+	for _, s := range managedStores() {
+		if err := s.configure(storageConfig); err != nil {
+			// 快照壞掉時直接從空的快取開始
+			slog.Warn(fmt.Sprintf("cache: restore %s failed: %s", s.Name(), err.Error()))
+		}
+	}
+}
+
+// 把所有可持久化的快取寫入硬碟(沒有設定 PersistDir 時不做任何事)
+func SaveAll() error {
+	if storageConfig.PersistDir == "" {
+		return nil
+	}
+
+	var errs []error
+	for _, s := range managedStores() {
+		if err := s.save(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *CacheStoreV2[T]) snapshotPath() string {
+	if !c.persist || storageConfig.PersistDir == "" {
+		return ""
+	}
+	return filepath.Join(storageConfig.PersistDir, c.name+".gob")
+}
+
+func (c *CacheStoreV2[T]) configure(cfg StorageConfig) error {
+	if cfg.MaxEntries > 0 {
+		c.UseStorage(NewLRUStorage[T](cfg.MaxEntries))
+	}
+
+	path := c.snapshotPath()
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	var snapshot []snapshotEntry[T]
+	if err := gob.NewDecoder(f).Decode(&snapshot); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for _, e := range snapshot {
+		if now.After(e.ExpireAt) {
+			continue
+		}
+		refreshAt := e.RefreshAt
+		if refreshAt.IsZero() {
+			refreshAt = e.ExpireAt
+		}
+		c.storage.Set(e.Key, &CacheV2[T]{Value: e.Value, RefreshAt: refreshAt, ExpireAt: e.ExpireAt})
+	}
+	slog.Info(fmt.Sprintf("cache: %s 從快照還原 %d筆", c.name, c.storage.Len()))
+	return nil
+}
+
+// 快照中的單筆資料(依照LRU由舊到新排列)
+type snapshotEntry[T any] struct {
+	Key       string
+	Value     T
+	RefreshAt time.Time
+	ExpireAt  time.Time
+}
+
+func (c *CacheStoreV2[T]) save() error {
+	path := c.snapshotPath()
+	if path == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	snapshot := make([]snapshotEntry[T], 0, c.storage.Len())
+	c.storage.Range(func(key string, item *CacheV2[T]) bool {
+		if !now.After(item.ExpireAt) {
+			snapshot = append(snapshot, snapshotEntry[T]{Key: key, Value: item.Value, RefreshAt: item.RefreshAt, ExpireAt: item.ExpireAt})
+		}
+		return true
+	})
+	c.mu.Unlock()
+
+	// 先寫到暫存檔再改名，避免寫到一半被中斷時留下壞掉的快照
+	tmp, err := os.CreateTemp(filepath.Dir(path), c.name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(snapshot); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
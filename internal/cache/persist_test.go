@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func usePersistDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	storageConfig = StorageConfig{PersistDir: dir}
+	t.Cleanup(func() { storageConfig = StorageConfig{} })
+	return dir
+}
+
+// 模擬重新啟動：同名但沒有註冊、也沒有資料的快取
+func reopen[T any](c *CacheStoreV2[T]) *CacheStoreV2[T] {
+	return &CacheStoreV2[T]{
+		name:       c.name,
+		storage:    NewMemoryStorage[T](),
+		expireTime: c.expireTime,
+		persist:    c.persist,
+		negative:   make(map[string]time.Time),
+		refreshing: make(map[string]struct{}),
+	}
+}
+
+type snapshotValue struct {
+	Name  string
+	Items []int
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	dir := usePersistDir(t)
+
+	c := newTestStore[snapshotValue](t, time.Hour)
+	c.UseStorage(NewLRUStorage[snapshotValue](10))
+	c.Set("a", snapshotValue{Name: "a", Items: []int{1}})
+	c.Set("b", snapshotValue{Name: "b", Items: []int{2, 3}})
+	c.Set("c", snapshotValue{Name: "c"})
+	// a變成最新
+	c.Get("a")
+	// 已經過期的不寫入快照
+	c.mu.Lock()
+	c.storage.Set("expired", &CacheV2[snapshotValue]{Value: snapshotValue{Name: "expired"}, ExpireAt: time.Now().Add(-time.Minute)})
+	c.mu.Unlock()
+
+	if err := c.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 暫存檔改名後不會留在資料夾內
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != c.Name()+".gob" {
+		t.Errorf("files = %v, want only %s.gob", files, c.Name())
+	}
+
+	restored := reopen(c)
+	if err := restored.configure(StorageConfig{PersistDir: dir, MaxEntries: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	// LRU順序跟著快照還原
+	if got, want := storageKeys(restored.storage), []string{"b", "c", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+	got, err := restored.Get("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, snapshotValue{Name: "b", Items: []int{2, 3}}) {
+		t.Errorf("Get(b) = %+v", got)
+	}
+	if _, err := restored.Get("expired"); err == nil {
+		t.Error("expired entry should not be restored")
+	}
+}
+
+// 快照容量比設定大時，只保留最新的資料
+func TestSnapshotRestoreRespectsMaxEntries(t *testing.T) {
+	dir := usePersistDir(t)
+
+	c := newTestStore[int](t, time.Hour)
+	for idx, k := range []string{"a", "b", "c"} {
+		c.Set(k, idx)
+	}
+	c.UseStorage(NewLRUStorage[int](3))
+	if err := c.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := reopen(c)
+	if err := restored.configure(StorageConfig{PersistDir: dir, MaxEntries: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if restored.storage.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", restored.storage.Len())
+	}
+}
+
+func TestSnapshotSkipped(t *testing.T) {
+	dir := usePersistDir(t)
+
+	c := newTestStore[int](t, time.Hour, WithoutPersist())
+	c.Set("a", 1)
+	if err := c.save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, c.Name()+".gob")); !os.IsNotExist(err) {
+		t.Errorf("WithoutPersist store wrote a snapshot: %v", err)
+	}
+
+	// 沒有快照檔時從空的快取開始
+	missing := newTestStore[int](t, time.Hour)
+	if err := missing.configure(StorageConfig{PersistDir: dir}); err != nil {
+		t.Errorf("configure() without snapshot error = %v", err)
+	}
+}
+
+// 壞掉的快照回傳錯誤，原本的資料不受影響
+func TestSnapshotCorrupted(t *testing.T) {
+	dir := usePersistDir(t)
+
+	c := newTestStore[int](t, time.Hour)
+	c.Set("a", 1)
+	if err := os.WriteFile(filepath.Join(dir, c.Name()+".gob"), []byte("not gob"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.configure(StorageConfig{PersistDir: dir}); err == nil {
+		t.Error("configure() with corrupted snapshot should fail")
+	}
+	if v, err := c.Get("a"); err != nil || v != 1 {
+		t.Errorf("Get(a) = %d, %v, want 1", v, err)
+	}
+}
+
+func TestSaveAllWithoutPersistDir(t *testing.T) {
+	storageConfig = StorageConfig{}
+	if err := SaveAll(); err != nil {
+		t.Errorf("SaveAll() error = %v", err)
+	}
+}
+
+// 不用等到清除排程，快照會依照自己的間隔寫入
+func TestSnapshotLoop(t *testing.T) {
+	dir := usePersistDir(t)
+
+	c := newTestStore[int](t, time.Hour)
+	c.Set("a", 1)
+
+	stopChan := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		snapshotLoop(10*time.Millisecond, stopChan)
+		close(done)
+	}()
+
+	path := filepath.Join(dir, c.Name()+".gob")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("snapshot was not written")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(stopChan)
+	<-done
+}
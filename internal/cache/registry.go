@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 已註冊快取對外提供的操作(不需要知道泛型型別)
+//
+// 清除排程、統計、管理工具都透過 Stores() 走訪，不需要手動列出每個快取
+type Store interface {
+	Name() string
+	Stats() CacheStats
+	Clean() (deleteCount int, total int)
+	// 清除過期資料的間隔，0代表使用 CleanCacheJob 的預設間隔
+	CleanInterval() time.Duration
+}
+
+// 快取內部使用的操作
+type managedStore interface {
+	Store
+	configure(cfg StorageConfig) error
+	save() error
+}
+
+var (
+	registryMu sync.RWMutex
+	stores     []managedStore
+)
+
+// NewCacheStoreV2 建立時自動註冊，名稱重複直接panic(屬於程式錯誤)
+func register(s managedStore) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, existing := range stores {
+		if existing.Name() == s.Name() {
+			panic(fmt.Sprintf("cache: store %q already registered", s.Name()))
+		}
+	}
+	stores = append(stores, s)
+}
+
+func managedStores() []managedStore {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return slices.Clone(stores)
+}
+
+// 所有已註冊的快取(依照名稱排序)
+func Stores() []Store {
+	list := managedStores()
+	out := make([]Store, 0, len(list))
+	for _, s := range list {
+		out = append(out, s)
+	}
+	slices.SortFunc(out, func(a, b Store) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return out
+}
+
+// 依名稱取得快取
+func Lookup(name string) (Store, bool) {
+	for _, s := range managedStores() {
+		if s.Name() == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
@@ -10,7 +10,7 @@ import (
 )
 
 var (
-	SearchGameCacheStore = NewCacheStoreV2[*InteractionCacheEntity](20 * time.Minute)
+	SearchGameCacheStore = NewCacheStoreV2[*InteractionCacheEntity]("SearchGameCacheStore", 20*time.Minute, WithoutPersist(), WithCleanInterval(20*time.Minute))
 )
 
 type InteractionCacheEntity struct {
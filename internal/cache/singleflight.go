@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+)
+
+var errLoaderPanic = errors.New("cache: loader panicked")
+
+// 同一個key同時間只會有一個loader在執行，其他呼叫者等待並共用結果
+type flightGroup[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+type flightCall[T any] struct {
+	wg    sync.WaitGroup
+	value T
+	err   error
+}
+
+// shared 代表結果是由其他呼叫者載入的
+func (g *flightGroup[T]) do(key string, fn func() (T, error)) (value T, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[T])
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.value, call.err, true
+	}
+
+	call := &flightCall[T]{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	// loader panic時轉成錯誤回傳，並放行等待中的呼叫者
+	defer func() {
+		if r := recover(); r != nil {
+			var zero T
+			call.value, call.err = zero, fmt.Errorf("%w: %v", errLoaderPanic, r)
+			value, err, shared = call.value, call.err, false
+			slog.Error("cache: loader panicked", "key", key, "panic", r, "stack", string(debug.Stack()))
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+	}()
+
+	call.value, call.err = fn()
+	return call.value, call.err, false
+}
@@ -0,0 +1,38 @@
+package cache
+
+// 單一快取的命中統計(從啟動開始累計)
+type CacheStats struct {
+	Name string
+	// 資料未過時
+	Hits int64
+	// 回傳過時資料(背景更新中)
+	Stale int64
+	// 需要重新查詢
+	Misses  int64
+	Entries int
+}
+
+// 取得快取的命中統計
+func (c *CacheStoreV2[T]) Stats() CacheStats {
+	c.mu.Lock()
+	entries := c.storage.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Name:    c.name,
+		Hits:    c.hits.Load(),
+		Stale:   c.stale.Load(),
+		Misses:  c.misses.Load(),
+		Entries: entries,
+	}
+}
+
+// 取得所有快取的命中統計(依照名稱排序)
+func AllStats() []CacheStats {
+	list := Stores()
+	out := make([]CacheStats, 0, len(list))
+	for _, s := range list {
+		out = append(out, s.Stats())
+	}
+	return out
+}
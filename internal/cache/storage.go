@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// Storage CacheStoreV2 底層的儲存介面
+//
+// 實作不需要自己處理併發，CacheStoreV2 會在呼叫前上鎖
+type Storage[T any] interface {
+	Get(key string) (*CacheV2[T], bool)
+	Set(key string, item *CacheV2[T])
+	Delete(key string)
+	Len() int
+	// 走訪所有資料，fn回傳false時停止(走訪途中不可修改資料)
+	Range(fn func(key string, item *CacheV2[T]) bool)
+}
+
+// 沒有容量限制的map(預設)
+type memoryStorage[T any] struct {
+	data map[string]*CacheV2[T]
+}
+
+func NewMemoryStorage[T any]() Storage[T] {
+	return &memoryStorage[T]{data: make(map[string]*CacheV2[T])}
+}
+
+func (m *memoryStorage[T]) Get(key string) (*CacheV2[T], bool) {
+	item, ok := m.data[key]
+	return item, ok
+}
+
+func (m *memoryStorage[T]) Set(key string, item *CacheV2[T]) {
+	m.data[key] = item
+}
+
+func (m *memoryStorage[T]) Delete(key string) {
+	delete(m.data, key)
+}
+
+func (m *memoryStorage[T]) Len() int {
+	return len(m.data)
+}
+
+func (m *memoryStorage[T]) Range(fn func(key string, item *CacheV2[T]) bool) {
+	for k, v := range m.data {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// 有容量上限的LRU，超過上限時淘汰最久沒有被存取的資料
+type lruStorage[T any] struct {
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type lruEntry[T any] struct {
+	key  string
+	item *CacheV2[T]
+}
+
+// capacity <= 0 時等同 NewMemoryStorage
+func NewLRUStorage[T any](capacity int) Storage[T] {
+	if capacity <= 0 {
+		return NewMemoryStorage[T]()
+	}
+	return &lruStorage[T]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (l *lruStorage[T]) Get(key string) (*CacheV2[T], bool) {
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry[T]).item, true
+}
+
+func (l *lruStorage[T]) Set(key string, item *CacheV2[T]) {
+	if elem, ok := l.items[key]; ok {
+		elem.Value.(*lruEntry[T]).item = item
+		l.order.MoveToFront(elem)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(&lruEntry[T]{key: key, item: item})
+	for l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry[T]).key)
+	}
+}
+
+func (l *lruStorage[T]) Delete(key string) {
+	if elem, ok := l.items[key]; ok {
+		l.order.Remove(elem)
+		delete(l.items, key)
+	}
+}
+
+func (l *lruStorage[T]) Len() int {
+	return l.order.Len()
+}
+
+// 由最舊到最新走訪，讓載入快照時可以照原本的順序重建
+func (l *lruStorage[T]) Range(fn func(key string, item *CacheV2[T]) bool) {
+	for elem := l.order.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(*lruEntry[T])
+		if !fn(entry.key, entry.item) {
+			return
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testStoreSeq atomic.Int64
+
+// 測試用的快取，名稱加上流水號，重複執行(-count)時不會因為名稱重複而panic
+func newTestStore[T any](t *testing.T, expireTime time.Duration, opts ...StoreOption) *CacheStoreV2[T] {
+	name := fmt.Sprintf("%s-%d", strings.ReplaceAll(t.Name(), "/", "_"), testStoreSeq.Add(1))
+	return NewCacheStoreV2[T](name, expireTime, opts...)
+}
+
+func storageKeys[T any](s Storage[T]) []string {
+	var keys []string
+	s.Range(func(key string, _ *CacheV2[T]) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestLRUStorage(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		ops      func(s Storage[int])
+		want     []string
+	}{
+		{
+			name:     "evicts oldest over capacity",
+			capacity: 2,
+			ops: func(s Storage[int]) {
+				s.Set("a", &CacheV2[int]{Value: 1})
+				s.Set("b", &CacheV2[int]{Value: 2})
+				s.Set("c", &CacheV2[int]{Value: 3})
+			},
+			want: []string{"b", "c"},
+		},
+		{
+			name:     "get moves to newest",
+			capacity: 2,
+			ops: func(s Storage[int]) {
+				s.Set("a", &CacheV2[int]{Value: 1})
+				s.Set("b", &CacheV2[int]{Value: 2})
+				s.Get("a")
+				s.Set("c", &CacheV2[int]{Value: 3})
+			},
+			want: []string{"a", "c"},
+		},
+		{
+			name:     "overwrite moves to newest without growing",
+			capacity: 3,
+			ops: func(s Storage[int]) {
+				s.Set("a", &CacheV2[int]{Value: 1})
+				s.Set("b", &CacheV2[int]{Value: 2})
+				s.Set("a", &CacheV2[int]{Value: 10})
+			},
+			want: []string{"b", "a"},
+		},
+		{
+			name:     "delete frees a slot",
+			capacity: 2,
+			ops: func(s Storage[int]) {
+				s.Set("a", &CacheV2[int]{Value: 1})
+				s.Set("b", &CacheV2[int]{Value: 2})
+				s.Delete("a")
+				s.Delete("missing")
+				s.Set("c", &CacheV2[int]{Value: 3})
+			},
+			want: []string{"b", "c"},
+		},
+		{
+			name:     "zero capacity is unlimited",
+			capacity: 0,
+			ops: func(s Storage[int]) {
+				for _, k := range []string{"a", "b", "c"} {
+					s.Set(k, &CacheV2[int]{})
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewLRUStorage[int](tt.capacity)
+			tt.ops(s)
+			if tt.want == nil {
+				if s.Len() != 3 {
+					t.Errorf("Len() = %d, want 3", s.Len())
+				}
+				return
+			}
+			if got := storageKeys(s); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keys = %v, want %v", got, tt.want)
+			}
+			if s.Len() != len(tt.want) {
+				t.Errorf("Len() = %d, want %d", s.Len(), len(tt.want))
+			}
+		})
+	}
+}
+
+// 換成LRU時原本的資料要保留，超過上限的部分淘汰
+func TestUseStorage(t *testing.T) {
+	c := newTestStore[int](t, time.Hour, WithoutPersist())
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.UseStorage(NewLRUStorage[int](1))
+
+	c.Set("c", 3)
+	if _, err := c.Get("c"); err != nil {
+		t.Errorf("Get(c) error = %v", err)
+	}
+	if c.storage.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.storage.Len())
+	}
+}
@@ -0,0 +1,225 @@
+package cid
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	kurohelpererrors "kurohelper/internal/errors"
+)
+
+const testCacheID = "0b6f5c3e-8a51-4f0e-9b8e-2f4a1c7d9e10"
+
+func setup(t *testing.T, legacy bool) {
+	t.Helper()
+	if err := Init(Config{Secret: "test-secret", AcceptLegacy: legacy}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { acceptLegacy = true })
+}
+
+func TestEncodeDecodeV4(t *testing.T) {
+	setup(t, true)
+
+	tests := []struct {
+		name string
+		p    Payload
+	}{
+		{name: "page", p: NewPagePayload("查詢遊戲", "erogs", testCacheID, 3)},
+		{name: "disabled page", p: NewPagePayload("查詢遊戲", "erogs", testCacheID, -99)},
+		{name: "select menu", p: NewSelectMenuPayload("查詢公司品牌", "vndb", testCacheID)},
+		{name: "back to home", p: NewBackToHomePayload("查詢角色", "vndb", testCacheID)},
+		{name: "detail", p: NewDetailBtnPayload("查詢創作者", "list", testCacheID, "e1234")},
+		{name: "value with colon", p: NewDetailBtnPayload("查詢遊戲", "vndb", testCacheID, "a:b::c")},
+		{name: "value with leading zero", p: NewDetailBtnPayload("查詢遊戲", "vndb", testCacheID, "007")},
+		{name: "switch source", p: NewSwitchSourcePayload("查詢遊戲", "vndb", testCacheID, "v17")},
+		{name: "user data operation", p: NewUserDataOperationPayload("加黑名單", testCacheID, 27263)},
+		{name: "v3", p: Payload{Command: "帳號設定", Behavior: V3Behavior, CacheID: testCacheID}},
+		{name: "cacheID is not uuid", p: NewPagePayload("公告", "list", "none", 1)},
+		{name: "upper case uuid", p: NewPagePayload("公告", "list", strings.ToUpper(testCacheID), 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := EncodeV4(tt.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsV4(out) {
+				t.Errorf("IsV4(%q) = false", out)
+			}
+			if len(out) > MaxCustomIDLength {
+				t.Errorf("len = %d, want <= %d", len(out), MaxCustomIDLength)
+			}
+			got, err := DecodeV4(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.p {
+				t.Errorf("DecodeV4() = %+v, want %+v", got, tt.p)
+			}
+		})
+	}
+}
+
+func TestDecodeV4Rejects(t *testing.T) {
+	setup(t, true)
+
+	out, err := EncodeV4(NewDetailBtnPayload("查詢遊戲", "vndb", testCacheID, "v17"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(out, V4Prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reencode := func(modify func(b []byte)) string {
+		b := append([]byte(nil), raw...)
+		modify(b)
+		return V4Prefix + base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	tests := []struct {
+		name     string
+		customID string
+		want     error
+	}{
+		{name: "tampered behavior", customID: reencode(func(b []byte) { b[1] = byte(PageBehavior) }), want: kurohelpererrors.ErrCIDInvalidSignature},
+		{name: "tampered value", customID: reencode(func(b []byte) { b[len(b)-macSize-1] ^= 1 }), want: kurohelpererrors.ErrCIDInvalidSignature},
+		{name: "tampered mac", customID: reencode(func(b []byte) { b[len(b)-1] ^= 1 }), want: kurohelpererrors.ErrCIDInvalidSignature},
+		{name: "truncated", customID: out[:10], want: kurohelpererrors.ErrCIDWrongFormat},
+		{name: "not base64", customID: V4Prefix + "!!!", want: kurohelpererrors.ErrCIDWrongFormat},
+		{name: "empty", customID: V4Prefix, want: kurohelpererrors.ErrCIDWrongFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeV4(tt.customID); !errors.Is(err, tt.want) {
+				t.Errorf("DecodeV4() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// 換了金鑰之後舊的CID就驗證不過
+	if err := Init(Config{Secret: "another-secret", AcceptLegacy: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeV4(out); !errors.Is(err, kurohelpererrors.ErrCIDInvalidSignature) {
+		t.Errorf("DecodeV4() with another secret error = %v, want ErrCIDInvalidSignature", err)
+	}
+}
+
+func TestEncodeV4Rejects(t *testing.T) {
+	setup(t, true)
+
+	tests := []struct {
+		name string
+		p    Payload
+		want error
+	}{
+		{name: "unknown behavior", p: Payload{Command: "查詢遊戲", Behavior: 'X', CacheID: testCacheID}, want: kurohelpererrors.ErrCIDBehaviorMismatch},
+		{name: "page value not int", p: Payload{Command: "查詢遊戲", Behavior: PageBehavior, CacheID: testCacheID, Value: "next"}, want: kurohelpererrors.ErrCIDWrongFormat},
+		{name: "back to home with value", p: Payload{Command: "查詢遊戲", Behavior: BackToHomeBehavior, CacheID: testCacheID, Value: "1"}, want: kurohelpererrors.ErrCIDWrongFormat},
+		{name: "too long", p: NewDetailBtnPayload("查詢遊戲", "vndb", testCacheID, strings.Repeat("v", 60)), want: kurohelpererrors.ErrCIDTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := EncodeV4(tt.p)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("EncodeV4() = %q, %v, want %v", out, err, tt.want)
+			}
+		})
+	}
+}
+
+// 目前會用到的最長組合也不能超過Discord的長度上限
+//
+// 整數Value最長的指令是「刪除使用者遊戲資料」(9個中文字)，字串Value(資料ID)只出現在查詢指令，
+// 其中最長的是「查詢公司品牌」；路由最長是 music_select，cacheID 都是UUID
+func TestEncodeV4WorstCaseLength(t *testing.T) {
+	setup(t, true)
+
+	longestRoute := "music_select"
+	tests := []struct {
+		name string
+		p    Payload
+	}{
+		{name: "int value", p: NewPagePayload("刪除使用者遊戲資料", longestRoute, testCacheID, 99999999)},
+		{name: "user data operation", p: NewUserDataOperationPayload("刪除使用者遊戲資料", testCacheID, 99999999)},
+		{name: "string value", p: NewDetailBtnPayload("查詢公司品牌", longestRoute, testCacheID, "v1234567")},
+		{name: "select menu", p: NewSelectMenuPayload("查詢公司品牌", longestRoute, testCacheID)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := EncodeV4(tt.p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out) > MaxCustomIDLength {
+				t.Errorf("len = %d, want <= %d", len(out), MaxCustomIDLength)
+			}
+		})
+	}
+}
+
+func TestIntValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       Payload
+		want    int
+		wantErr error
+	}{
+		{name: "page", p: NewPagePayload("查詢遊戲", "", testCacheID, 2), want: 2},
+		{name: "user data operation", p: NewUserDataOperationPayload("加黑名單", testCacheID, 27263), want: 27263},
+		{name: "string behavior", p: NewDetailBtnPayload("查詢遊戲", "", testCacheID, "12"), wantErr: kurohelpererrors.ErrCIDBehaviorMismatch},
+		{name: "bad value", p: Payload{Behavior: PageBehavior, Value: "x"}, wantErr: kurohelpererrors.ErrCIDWrongFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.p.IntValue()
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("IntValue() = %d, %v, want %d, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeV3(t *testing.T) {
+	tests := []struct {
+		name        string
+		legacy      bool
+		customID    string
+		wantCommand string
+		wantCacheID string
+		wantErr     error
+	}{
+		{name: "legacy on", legacy: true, customID: "v3@帳號設定:" + testCacheID, wantCommand: "帳號設定", wantCacheID: testCacheID},
+		{name: "legacy off", legacy: false, customID: "v3@帳號設定:" + testCacheID, wantErr: kurohelpererrors.ErrCIDWrongFormat},
+		{name: "missing cacheID", legacy: true, customID: "v3@帳號設定:", wantErr: kurohelpererrors.ErrCIDWrongFormat},
+		{name: "missing separator", legacy: true, customID: "v3@帳號設定", wantErr: kurohelpererrors.ErrCIDWrongFormat},
+		{name: "not v3", legacy: true, customID: "帳號設定:" + testCacheID, wantErr: kurohelpererrors.ErrCIDWrongFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup(t, tt.legacy)
+			command, cacheID, err := DecodeV3(tt.customID)
+			if !errors.Is(err, tt.wantErr) || command != tt.wantCommand || cacheID != tt.wantCacheID {
+				t.Errorf("DecodeV3() = %q, %q, %v, want %q, %q, %v", command, cacheID, err, tt.wantCommand, tt.wantCacheID, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInitRequireSecret(t *testing.T) {
+	t.Cleanup(func() { acceptLegacy = true })
+
+	if err := Init(Config{RequireSecret: true}); !errors.Is(err, kurohelpererrors.ErrCIDSecretRequired) {
+		t.Errorf("Init() error = %v, want ErrCIDSecretRequired", err)
+	}
+	if err := Init(Config{Secret: "test-secret", RequireSecret: true}); err != nil {
+		t.Errorf("Init() error = %v", err)
+	}
+	if err := Init(Config{}); err != nil {
+		t.Errorf("Init() without persistence error = %v", err)
+	}
+}
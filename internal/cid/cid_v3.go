@@ -1,10 +1,32 @@
 package cid
 
-import "fmt"
+import (
+	"strings"
+
+	kurohelpererrors "kurohelper/internal/errors"
+)
 
 const V3Prefix = "v3@"
 
-// MakeCIDV3 產生 v3 格式的 CustomID（v3@commandName:cacheID）。
-func MakeCIDV3(commandName, cacheID string) string {
-	return fmt.Sprintf("%s%s:%s", V3Prefix, commandName, cacheID)
+// CIDV3 在V4中使用的behavior，只帶cacheID，交給 ComponentV3Handler 處理
+const V3Behavior Behavior = 'C'
+
+// MakeCIDV3 產生交給 ComponentV3Handler 處理的 CustomID
+//
+// 實際輸出V4格式，舊版 "v3@commandName:cacheID" 仍可以解析
+func MakeCIDV3(commandName, cacheID string) (string, error) {
+	return EncodeV4(Payload{Command: commandName, Behavior: V3Behavior, CacheID: cacheID})
+}
+
+// 解碼舊版 "v3@commandName:cacheID"，CID_ACCEPT_LEGACY 關閉時一律回傳 ErrCIDWrongFormat
+func DecodeV3(customID string) (commandName, cacheID string, err error) {
+	after, ok := strings.CutPrefix(customID, V3Prefix)
+	if !ok || !acceptLegacy {
+		return "", "", kurohelpererrors.ErrCIDWrongFormat
+	}
+	commandName, cacheID, ok = strings.Cut(after, ":")
+	if !ok || strings.TrimSpace(commandName) == "" || strings.TrimSpace(cacheID) == "" {
+		return "", "", kurohelpererrors.ErrCIDWrongFormat
+	}
+	return commandName, cacheID, nil
 }
@@ -0,0 +1,327 @@
+package cid
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	kurohelpererrors "kurohelper/internal/errors"
+)
+
+// V4 CustomID
+//
+// 格式為 "4" + base64url(內容 + HMAC前8 bytes)，內容為：
+//
+//	flags(1) behavior(1) command(len+bytes) route(len+bytes) cacheID value
+//
+// cacheID 是UUID時壓成16 bytes，value 是整數時用 zigzag varint，否則用 len+bytes。
+// 因為不再用冒號分隔，所以 value 可以包含任何字元；HMAC 可以擋掉偽造或竄改過的CID
+const V4Prefix = "4"
+
+// Discord CustomID 長度上限
+const MaxCustomIDLength = 100
+
+const (
+	flagIntValue byte = 1 << iota
+	flagUUIDCacheID
+)
+
+const macSize = 8
+
+var (
+	secret       []byte
+	acceptLegacy = true
+)
+
+// CID設定
+type Config struct {
+	// HMAC金鑰，空字串時隨機產生(重新啟動後舊訊息上的按鈕會失效)
+	Secret string
+	// 快取有硬碟快照時為true，重啟後舊按鈕還要能用，所以一定要有固定的金鑰
+	RequireSecret bool
+	// 是否繼續接受舊版(V2、V3)CID
+	AcceptLegacy bool
+}
+
+func init() {
+	secret = randomSecret()
+}
+
+// 設定HMAC金鑰與舊版CID相容性，需要在開始接收Interaction之前呼叫
+//
+// RequireSecret 為true但沒有金鑰時回傳 ErrCIDSecretRequired
+func Init(cfg Config) error {
+	if cfg.Secret == "" {
+		if cfg.RequireSecret {
+			return kurohelpererrors.ErrCIDSecretRequired
+		}
+		slog.Warn("CID_SECRET is empty, using a random key: buttons on old messages will stop working after restart")
+		secret = randomSecret()
+	} else {
+		secret = []byte(cfg.Secret)
+	}
+	acceptLegacy = cfg.AcceptLegacy
+	return nil
+}
+
+// 是否接受舊版CID
+func AcceptLegacy() bool {
+	return acceptLegacy
+}
+
+func randomSecret() []byte {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return b
+}
+
+// CID行為，決定 Value 的型別
+type Behavior byte
+
+const (
+	// Value 是頁數(int)
+	PageBehavior Behavior = 'P'
+	// Value 編碼時為空，選擇後由Discord填入
+	SelectMenuBehavior Behavior = 'S'
+	// 沒有 Value
+	BackToHomeBehavior Behavior = 'H'
+	// Value 是要查看的資料ID
+	DetailBtnBehavior Behavior = 'D'
+	// Value 是另一個資料來源的ID
+	SwitchSourceBehavior Behavior = 'W'
+	// Value 是目標資料ID(int)
+	UserDataOperationBehavior Behavior = 'U'
+)
+
+type valueKind int
+
+const (
+	valueNone valueKind = iota
+	valueString
+	valueInt
+)
+
+// 各行為的 Value 型別，不在表內的行為無法編碼與解碼
+var behaviorValue = map[Behavior]valueKind{
+	PageBehavior:              valueInt,
+	SelectMenuBehavior:        valueNone,
+	BackToHomeBehavior:        valueNone,
+	DetailBtnBehavior:         valueString,
+	SwitchSourceBehavior:      valueString,
+	UserDataOperationBehavior: valueInt,
+	V3Behavior:                valueNone,
+}
+
+// V4 CID內容
+//
+// 建議用 NewXxxPayload 產生，EncodeV4 與 DecodeV4 都會檢查 Value 是否符合 Behavior
+type Payload struct {
+	Command  string
+	Route    string
+	Behavior Behavior
+	CacheID  string
+	Value    string
+}
+
+func NewPagePayload(command, route, cacheID string, page int) Payload {
+	return Payload{Command: command, Route: route, Behavior: PageBehavior, CacheID: cacheID, Value: strconv.Itoa(page)}
+}
+
+func NewSelectMenuPayload(command, route, cacheID string) Payload {
+	return Payload{Command: command, Route: route, Behavior: SelectMenuBehavior, CacheID: cacheID}
+}
+
+func NewBackToHomePayload(command, route, cacheID string) Payload {
+	return Payload{Command: command, Route: route, Behavior: BackToHomeBehavior, CacheID: cacheID}
+}
+
+func NewDetailBtnPayload(command, route, cacheID, id string) Payload {
+	return Payload{Command: command, Route: route, Behavior: DetailBtnBehavior, CacheID: cacheID, Value: id}
+}
+
+func NewSwitchSourcePayload(command, route, cacheID, targetID string) Payload {
+	return Payload{Command: command, Route: route, Behavior: SwitchSourceBehavior, CacheID: cacheID, Value: targetID}
+}
+
+// 使用者資料操作不需要 Route
+func NewUserDataOperationPayload(command, cacheID string, targetID int) Payload {
+	return Payload{Command: command, Behavior: UserDataOperationBehavior, CacheID: cacheID, Value: strconv.Itoa(targetID)}
+}
+
+// 取得整數 Value，只有 PageBehavior 與 UserDataOperationBehavior 可以使用
+func (p Payload) IntValue() (int, error) {
+	if behaviorValue[p.Behavior] != valueInt {
+		return 0, kurohelpererrors.ErrCIDBehaviorMismatch
+	}
+	n, err := strconv.Atoi(p.Value)
+	if err != nil {
+		return 0, kurohelpererrors.ErrCIDWrongFormat
+	}
+	return n, nil
+}
+
+// 檢查 Value 是否符合 Behavior
+//
+// 沒有 Value 的行為只在編碼時要求為空，SelectMenu 解碼後的 Value 會由呼叫端填入
+func (p Payload) validate() error {
+	kind, ok := behaviorValue[p.Behavior]
+	if !ok {
+		return kurohelpererrors.ErrCIDBehaviorMismatch
+	}
+	switch kind {
+	case valueNone:
+		if p.Value != "" {
+			return kurohelpererrors.ErrCIDWrongFormat
+		}
+	case valueInt:
+		if _, err := strconv.Atoi(p.Value); err != nil {
+			return kurohelpererrors.ErrCIDWrongFormat
+		}
+	}
+	return nil
+}
+
+// 是否為V4格式(base64url不會出現冒號與@)
+func IsV4(customID string) bool {
+	return strings.HasPrefix(customID, V4Prefix) && !strings.ContainsAny(customID, ":@")
+}
+
+// 編碼成V4 CustomID，超過 MaxCustomIDLength 時回傳 ErrCIDTooLong(Discord會拒絕整則訊息)
+func EncodeV4(p Payload) (string, error) {
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+
+	var flags byte
+	body := make([]byte, 2, 64)
+
+	body = appendString(body, p.Command)
+	body = appendString(body, p.Route)
+
+	if id, err := uuid.Parse(p.CacheID); err == nil && id.String() == p.CacheID {
+		flags |= flagUUIDCacheID
+		body = append(body, id[:]...)
+	} else {
+		body = appendString(body, p.CacheID)
+	}
+
+	if n, err := strconv.ParseInt(p.Value, 10, 64); err == nil && strconv.FormatInt(n, 10) == p.Value {
+		flags |= flagIntValue
+		body = binary.AppendVarint(body, n)
+	} else {
+		body = appendString(body, p.Value)
+	}
+
+	body[0] = flags
+	body[1] = byte(p.Behavior)
+	body = append(body, sign(body)...)
+
+	out := V4Prefix + base64.RawURLEncoding.EncodeToString(body)
+	if len(out) > MaxCustomIDLength {
+		return "", fmt.Errorf("%w: %d characters (command %s, route %s)", kurohelpererrors.ErrCIDTooLong, len(out), p.Command, p.Route)
+	}
+	return out, nil
+}
+
+// 解碼V4 CustomID，簽章不符時回傳 ErrCIDInvalidSignature
+func DecodeV4(customID string) (Payload, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(customID, V4Prefix))
+	if err != nil || len(raw) < 2+macSize {
+		return Payload{}, kurohelpererrors.ErrCIDWrongFormat
+	}
+
+	body, mac := raw[:len(raw)-macSize], raw[len(raw)-macSize:]
+	if !hmac.Equal(mac, sign(body)) {
+		return Payload{}, kurohelpererrors.ErrCIDInvalidSignature
+	}
+
+	flags := body[0]
+	p := Payload{Behavior: Behavior(body[1])}
+	r := reader{buf: body[2:]}
+
+	p.Command = r.string()
+	p.Route = r.string()
+	if flags&flagUUIDCacheID != 0 {
+		var id uuid.UUID
+		copy(id[:], r.bytes(len(id)))
+		p.CacheID = id.String()
+	} else {
+		p.CacheID = r.string()
+	}
+	if flags&flagIntValue != 0 {
+		p.Value = strconv.FormatInt(r.varint(), 10)
+	} else {
+		p.Value = r.string()
+	}
+
+	if r.err != nil || len(r.buf) != 0 {
+		return Payload{}, kurohelpererrors.ErrCIDWrongFormat
+	}
+	if err := p.validate(); err != nil {
+		return Payload{}, err
+	}
+	return p, nil
+}
+
+func sign(body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(body)
+	return h.Sum(nil)[:macSize]
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+var errShortBuffer = errors.New("cid: short buffer")
+
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.buf) {
+		r.err = errShortBuffer
+		return make([]byte, max(n, 0))
+	}
+	out := r.buf[:n]
+	r.buf = r.buf[n:]
+	return out
+}
+
+func (r *reader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	n, size := binary.Uvarint(r.buf)
+	if size <= 0 || n > uint64(len(r.buf)) {
+		r.err = errShortBuffer
+		return ""
+	}
+	r.buf = r.buf[size:]
+	return string(r.bytes(int(n)))
+}
+
+func (r *reader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	n, size := binary.Varint(r.buf)
+	if size <= 0 {
+		r.err = errShortBuffer
+		return 0
+	}
+	r.buf = r.buf[size:]
+	return n
+}
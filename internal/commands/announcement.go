@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -35,11 +36,16 @@ func (a *Announcement) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
-func (a *Announcement) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	a.HandleComponent(s, i, nil)
+// 公告任何人都可以瀏覽
+func (a *Announcement) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentShared
 }
 
-func (a *Announcement) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func (a *Announcement) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	a.HandleComponent(ctx, s, i, nil)
+}
+
+func (a *Announcement) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	if cid == nil {
 		respondAnnouncementList(s, i, false)
 		return
@@ -61,7 +67,7 @@ func (a *Announcement) HandleComponent(s *discordgo.Session, i *discordgo.Intera
 	}
 }
 
-func respondAnnouncementList(s *discordgo.Session, i *discordgo.InteractionCreate, editExisting bool) {
+func respondAnnouncementList(s utils.Responder, i *discordgo.InteractionCreate, editExisting bool) {
 	if !editExisting {
 		if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
@@ -97,7 +103,7 @@ func respondAnnouncementList(s *discordgo.Session, i *discordgo.InteractionCreat
 	utils.WebhookEditRespond(s, i, components)
 }
 
-func respondAnnouncementDetail(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func respondAnnouncementDetail(s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	id, err := strconv.Atoi(cid.ToSelectMenuCIDV2().Value)
 	if err != nil || id <= 0 {
 		utils.HandleErrorV2(fmt.Errorf("announcement: invalid id"), s, i, utils.InteractionRespondEditComplex)
@@ -186,15 +192,19 @@ func buildAnnouncementListComponents(list []kurohelperdb.Announcement) ([]discor
 		})
 	}
 
+	selectMenu, err := utils.MakeSelectMenuComponent(
+		menuItems,
+		announcementCommandName,
+		announcementDetailRoute,
+		announcementNoCacheID,
+		"選擇公告查看詳細",
+	)
+	if err != nil {
+		return nil, err
+	}
 	containerComponents = append(containerComponents,
 		discordgo.Separator{Divider: &divider},
-		utils.MakeSelectMenuComponent(
-			menuItems,
-			announcementCommandName,
-			announcementDetailRoute,
-			announcementNoCacheID,
-			"選擇公告查看詳細",
-		),
+		selectMenu,
 	)
 
 	return []discordgo.MessageComponent{
@@ -247,9 +257,13 @@ func buildAnnouncementDetailComponents(item kurohelperdb.Announcement) ([]discor
 		)
 	}
 
+	backToHome, err := utils.MakeBackToHomeComponent(announcementCommandName, announcementListRoute, announcementNoCacheID)
+	if err != nil {
+		return nil, err
+	}
 	containerComponents = append(containerComponents,
 		discordgo.Separator{Divider: &divider},
-		utils.MakeBackToHomeComponent(announcementCommandName, announcementListRoute, announcementNoCacheID),
+		backToHome,
 	)
 
 	return []discordgo.MessageComponent{
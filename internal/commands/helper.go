@@ -1,9 +1,11 @@
 package commands
 
 import (
+	"context"
+
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
 	kurohelperdb "kurohelperservice/db"
-	"log/slog"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -17,10 +19,10 @@ func (h *Helper) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
-func (h *Helper) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (h *Helper) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
 	serverLink, err := kurohelperdb.GetAppConfigByKey(kurohelperdb.Dbs, "SERVER_LINK")
 	if err != nil {
-		slog.Warn(err.Error())
+		tracing.Logger(ctx).Warn(err.Error())
 		serverLink.ConfigValue = "目前群組連結不公開"
 	}
 
@@ -1,11 +1,16 @@
 package random
 
 import (
+	"context"
+	"time"
+
 	"errors"
 	"fmt"
 	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/ratelimit"
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
-	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,7 +20,9 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
-type RandomCharacter struct{}
+type RandomCharacter struct {
+	Providers *provider.Providers
+}
 
 func (r *RandomCharacter) Definition() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
@@ -42,7 +49,12 @@ func (r *RandomCharacter) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
-func (r *RandomCharacter) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+// 隨機角色每分鐘最多3次
+func (r *RandomCharacter) RateLimit() ratelimit.Rule {
+	return ratelimit.Rule{Burst: 3, Per: time.Minute}
+}
+
+func (r *RandomCharacter) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
 	// 長時間查詢
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
@@ -52,11 +64,11 @@ func (r *RandomCharacter) Handler(s *discordgo.Session, i *discordgo.Interaction
 		utils.HandleError(err, s, i)
 		return
 	}
-	vndbRandomCharacter(s, i, opt)
+	vndbRandomCharacter(ctx, s, i, r.Providers, opt)
 }
 
-func vndbRandomCharacter(s *discordgo.Session, i *discordgo.InteractionCreate, opt string) {
-	res, err := vndb.GetRandomCharacter(opt)
+func vndbRandomCharacter(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers, opt string) {
+	res, err := p.Vndb.GetRandomCharacter(ctx, opt)
 	if err != nil {
 		utils.HandleError(err, s, i)
 		return
@@ -73,7 +85,7 @@ func vndbRandomCharacter(s *discordgo.Session, i *discordgo.InteractionCreate, o
 	if res.Original != "" {
 		nameData = fmt.Sprintf("%s (%s)", res.Original, res.Name)
 	}
-	slog.Info("隨機角色", "name", nameData, "guildID", i.GuildID)
+	tracing.Logger(ctx).Info("隨機角色", "name", nameData)
 	if len(res.Aliases) == 0 {
 		res.Aliases = []string{"未收錄"}
 	}
@@ -1,22 +1,38 @@
 package random
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"kurohelper/internal/autocomplete"
 	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/executor"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/ratelimit"
+	"kurohelper/internal/store"
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
 
+	"kurohelperservice"
+	kurohelperdb "kurohelperservice/db"
 	"kurohelperservice/provider/vndb"
 	"kurohelperservice/provider/ymgal"
 
 	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
 )
 
-type RandomGame struct{}
+type RandomGame struct {
+	Providers *provider.Providers
+}
+
+// 抽到黑名單時最多重抽的次數(包含第一次)，全部都是黑名單時回傳 ErrRandomAllBlackListed
+const randomGameMaxAttempts = 5
 
 func (r *RandomGame) Definition() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
@@ -43,8 +59,13 @@ func (r *RandomGame) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
+// 隨機遊戲每分鐘最多3次
+func (r *RandomGame) RateLimit() ratelimit.Rule {
+	return ratelimit.Rule{Burst: 3, Per: time.Minute}
+}
+
 // 隨機遊戲Handler
-func (r *RandomGame) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (r *RandomGame) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
 	// 長時間查詢
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
@@ -55,20 +76,41 @@ func (r *RandomGame) Handler(s *discordgo.Session, i *discordgo.InteractionCreat
 		return
 	}
 	if opt == "" || opt == "1" {
-		vndbRandomGame(s, i)
+		vndbRandomGame(ctx, s, i, r.Providers)
 	} else {
-		ymgalRandomGame(s, i)
+		ymgalRandomGame(ctx, s, i, r.Providers)
 	}
 
 }
 
-func ymgalRandomGame(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	game, err := ymgal.GetRandomGame()
+func ymgalRandomGame(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers) {
+	blackList, err := loadBlackList(utils.GetUserID(i))
 	if err != nil {
 		utils.HandleError(err, s, i)
 		return
 	}
 
+	var game []ymgal.RandomGameResp
+	for attempt := 0; ; attempt++ {
+		if attempt == randomGameMaxAttempts {
+			utils.HandleError(kurohelpererrors.ErrRandomAllBlackListed, s, i)
+			return
+		}
+		game, err = p.Ymgal.GetRandomGame(ctx)
+		if err != nil {
+			utils.HandleError(err, s, i)
+			return
+		}
+		if len(game) == 0 {
+			utils.HandleError(kurohelperservice.ErrSearchNoContent, s, i)
+			return
+		}
+		if !blackList.hasName(game[0].Name, game[0].ChineseName) {
+			break
+		}
+		tracing.Logger(ctx).Debug("隨機遊戲抽到黑名單，重新抽選", "gameTitle", game[0].Name)
+	}
+
 	title := game[0].Name
 	if game[0].HaveChinese {
 		title += "/" + game[0].ChineseName
@@ -92,12 +134,33 @@ func ymgalRandomGame(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	utils.InteractionEmbedRespond(s, i, embed, nil, true)
 }
 
-func vndbRandomGame(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	res, err := vndb.GetRandomVN()
+func vndbRandomGame(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers) {
+	blackList, err := loadBlackList(utils.GetUserID(i))
 	if err != nil {
 		utils.HandleError(err, s, i)
 		return
 	}
+
+	var res *vndb.BasicResponse[vndb.GetVnUseIDResponse]
+	for attempt := 0; ; attempt++ {
+		if attempt == randomGameMaxAttempts {
+			utils.HandleError(kurohelpererrors.ErrRandomAllBlackListed, s, i)
+			return
+		}
+		res, err = p.Vndb.GetRandomVN(ctx)
+		if err != nil {
+			utils.HandleError(err, s, i)
+			return
+		}
+		if len(res.Results) == 0 {
+			utils.HandleError(kurohelperservice.ErrSearchNoContent, s, i)
+			return
+		}
+		if !blackList.hasVndb(ctx, p.Erogs, res.Results[0].ID, res.Results[0].Alttitle, res.Results[0].Title) {
+			break
+		}
+		tracing.Logger(ctx).Debug("隨機遊戲抽到黑名單，重新抽選", "gameTitle", res.Results[0].Title)
+	}
 	/* 處理回傳結構 */
 
 	gameTitle := res.Results[0].Alttitle
@@ -110,7 +173,7 @@ func vndbRandomGame(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	} else {
 		brandTitle = res.Results[0].Developers[0].Name
 	}
-	slog.Info("隨機遊戲", "gameTitle", gameTitle, "guildID", i.GuildID)
+	tracing.Logger(ctx).Info("隨機遊戲", "gameTitle", gameTitle)
 	// staff block
 	var scenario string
 	var art string
@@ -200,7 +263,7 @@ func vndbRandomGame(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	image := utils.GenerateImage(i, res.Results[0].Image.Url)
 	if res.Results[0].Image.Sexual >= 1 || res.Results[0].Image.Violence >= 1 {
 		image = nil
-		slog.Debug("封面已過濾圖片顯示", "gameTitle", gameTitle)
+		tracing.Logger(ctx).Debug("封面已過濾圖片顯示", "gameTitle", gameTitle)
 	}
 
 	embed := &discordgo.MessageEmbed{
@@ -257,3 +320,67 @@ func vndbRandomGame(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 	utils.InteractionEmbedRespond(s, i, embed, nil, true)
 }
+
+// 使用者黑名單中的遊戲
+type blackList struct {
+	// 批評空間遊戲("e" + 遊戲ID)，和VNDB遊戲對應到的批評空間遊戲比對
+	erogsKeys map[string]struct{}
+	// 正規化後的名稱，ymgal沒有ID可以對應，只能用名稱比對
+	names map[string]struct{}
+}
+
+// 讀取使用者的黑名單，沒有建檔時回傳空的黑名單
+func loadBlackList(discordID string) (blackList, error) {
+	list := blackList{erogsKeys: make(map[string]struct{}), names: make(map[string]struct{})}
+	if _, ok := store.UserStore[discordID]; !ok {
+		return list, nil
+	}
+	userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, discordID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return list, nil
+		}
+		return blackList{}, err
+	}
+	for _, g := range userGames {
+		if !g.BlackListMark {
+			continue
+		}
+		list.erogsKeys["e"+strconv.Itoa(g.GameErogsID)] = struct{}{}
+		if name := autocomplete.Normalize(g.GameErogs.Name); name != "" {
+			list.names[name] = struct{}{}
+		}
+	}
+	return list, nil
+}
+
+func (b blackList) hasName(titles ...string) bool {
+	if len(b.names) == 0 {
+		return false
+	}
+	for _, title := range titles {
+		if _, ok := b.names[autocomplete.Normalize(title)]; ok && title != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// 用VNDB ID對應的批評空間遊戲比對
+//
+// 先用快取的對應；沒有快取時只有標題和黑名單中的名稱相同才查詢上游確認，
+// 避免每次抽選都對批評空間發出查詢
+func (b blackList) hasVndb(ctx context.Context, p provider.Erogs, vnID string, titles ...string) bool {
+	if len(b.erogsKeys) == 0 {
+		return false
+	}
+	key, ok := executor.CachedErogsKeyForVndb(vnID)
+	if !ok {
+		if !b.hasName(titles...) {
+			return false
+		}
+		key = executor.ErogsKeyForVndb(ctx, p, vnID, titles...)
+	}
+	_, blocked := b.erogsKeys[key]
+	return key != "" && blocked
+}
@@ -0,0 +1,71 @@
+package random
+
+import (
+	"context"
+	"testing"
+
+	"kurohelperservice/provider/erogs"
+
+	"kurohelper/internal/autocomplete"
+	"kurohelper/internal/cache"
+	"kurohelper/internal/provider"
+)
+
+// 記錄標題查詢次數的批評空間
+type countingErogs struct {
+	provider.Erogs
+	calls int
+}
+
+func (c *countingErogs) SearchGameByKeyword(ctx context.Context, keywords []string) (*erogs.Game, error) {
+	c.calls++
+	return c.Erogs.SearchGameByKeyword(ctx, keywords)
+}
+
+func TestBlackListHasVndb(t *testing.T) {
+	fixture := &provider.FixtureErogs{
+		GameByKeyword: map[string]erogs.Game{
+			"blocked": {ID: 91001, Gamename: "Blocked", VndbId: "v91001"},
+			"other":   {ID: 91002, Gamename: "Other", VndbId: "v91002"},
+		},
+	}
+	list := blackList{
+		erogsKeys: map[string]struct{}{"e91001": {}, "e91002": {}},
+		names:     map[string]struct{}{autocomplete.Normalize("Blocked"): {}},
+	}
+
+	tests := []struct {
+		name      string
+		list      blackList
+		cached    string
+		vnID      string
+		titles    []string
+		want      bool
+		wantCalls int
+	}{
+		{name: "empty blacklist", list: blackList{}, vnID: "v91001", titles: []string{"Blocked"}},
+		{name: "cached mapping", list: list, cached: "e91002", vnID: "v91002", titles: []string{"Unrelated"}, want: true},
+		{name: "cached mapping not blacklisted", list: list, cached: "e91999", vnID: "v91999", titles: []string{"Blocked"}},
+		{name: "title not blacklisted", list: list, vnID: "v91002", titles: []string{"Other"}},
+		{name: "title matches", list: list, vnID: "v91001", titles: []string{"Blocked"}, want: true, wantCalls: 1},
+		// 名稱相同但VNDB ID對不上，不算同一款
+		{name: "title matches another game", list: list, vnID: "v91003", titles: []string{"Blocked"}, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache.VndbErogsMapStore.Delete(tt.vnID)
+			t.Cleanup(func() { cache.VndbErogsMapStore.Delete(tt.vnID) })
+			if tt.cached != "" {
+				cache.VndbErogsMapStore.Set(tt.vnID, tt.cached)
+			}
+
+			p := &countingErogs{Erogs: fixture}
+			if got := tt.list.hasVndb(context.Background(), p, tt.vnID, tt.titles...); got != tt.want {
+				t.Errorf("hasVndb() = %v, want %v", got, tt.want)
+			}
+			if p.calls != tt.wantCalls {
+				t.Errorf("upstream called %d times, want %d", p.calls, tt.wantCalls)
+			}
+		})
+	}
+}
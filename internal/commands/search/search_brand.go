@@ -1,18 +1,21 @@
 package search
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
 
+	"kurohelper/internal/autocomplete"
 	"kurohelper/internal/cache"
 	kurohelperrerrors "kurohelper/internal/errors"
 	"kurohelper/internal/executor"
 	common "kurohelper/internal/executor"
+	"kurohelper/internal/provider"
 	"kurohelper/internal/store"
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
 	"kurohelperservice"
 	kurohelperdb "kurohelperservice/db"
@@ -33,7 +36,9 @@ var (
 	searchBrandColor = 0x00AA90
 )
 
-type SearchBrand struct{}
+type SearchBrand struct {
+	Providers *provider.Providers
+}
 
 func (sb *SearchBrand) Definition() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
@@ -67,12 +72,21 @@ func (sb *SearchBrand) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
-func (sb *SearchBrand) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	sb.HandleComponent(s, i, nil)
+// 查詢結果只有本人可以翻頁、切換
+func (sb *SearchBrand) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
+}
+
+func (sb *SearchBrand) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	// 記錄Autocomplete熱門度
+	if keyword, err := utils.GetOptions(i, "keyword"); err == nil {
+		autocomplete.Brands.Hit(keyword)
+	}
+	sb.HandleComponent(ctx, s, i, nil)
 }
 
 // 查詢公司品牌Handler(新版API)
-func (sb *SearchBrand) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func (sb *SearchBrand) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	if cid == nil {
 		optDB, err := utils.GetOptions(i, "查詢資料庫選項")
 		if err != nil && errors.Is(err, kurohelperrerrors.ErrOptionTranslateFail) {
@@ -81,45 +95,55 @@ func (sb *SearchBrand) HandleComponent(s *discordgo.Session, i *discordgo.Intera
 		}
 		switch optDB {
 		case "1":
-			common.SearchList(s, i, cache.VndbBrandStore, "vndb查詢公司品牌", func() (*vndb.ProducerSearchResponse, error) {
+			common.SearchList(ctx, s, i, cache.VndbBrandStore, "vndb查詢公司品牌", func(ctx context.Context) (*vndb.ProducerSearchResponse, error) {
 				keyword, err := utils.GetOptions(i, "keyword")
 				if err != nil {
 					return nil, err
 				}
-				return vndb.GetProducerByFuzzy(keyword, "")
+				return sb.Providers.Vndb.GetProducerByFuzzy(ctx, keyword, "")
 			}, buildSearchBrandComponents)
 		case "2":
-			erogsSearchBrandV2(s, i)
+			erogsSearchBrandV2(ctx, s, i, sb.Providers)
 		default:
 			// 預設走批評空間
-			erogsSearchBrandV2(s, i)
+			erogsSearchBrandV2(ctx, s, i, sb.Providers)
 		}
 	} else {
 		// 選擇不同行為的進入點
 		switch (switchMode{cid.GetRouteKey(), cid.GetBehaviorID()}) {
 		case switchMode{searchBrandVNDBRouteKey, utils.PageBehavior}:
-			vndbSearchBrandWithCIDV2(s, i, cid)
+			vndbSearchBrandWithCIDV2(ctx, s, i, cid)
 		case switchMode{searchBrandVNDBRouteKey, utils.SelectMenuBehavior}:
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseDeferredMessageUpdate,
 			})
-			vndbSearchBrandWithSelectMenuCIDV2(s, i, cid)
+			vndbSearchBrandWithSelectMenuCIDV2(ctx, s, i, sb.Providers, cid)
 		case switchMode{searchBrandVNDBRouteKey, utils.BackToHomeBehavior}:
-			common.BackToHome(s, i, cid.ToBackToHomeCIDV2(), cache.VndbBrandStore, buildSearchBrandComponents)
+			common.BackToHome(ctx, s, i, cid.ToBackToHomeCIDV2(), cache.VndbBrandStore, buildSearchBrandComponents)
 		case switchMode{searchBrandErogsRouteKey, utils.PageBehavior}:
-			erogsSearchBrandWithCIDV2(s, i, cid)
+			erogsSearchBrandWithCIDV2(ctx, s, i, cid)
 		case switchMode{searchBrandErogsRouteKey, utils.SelectMenuBehavior}:
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseDeferredMessageUpdate,
 			})
-			erogsSearchGameWithSelectMenuCIDV2(s, i, cid, searchBrandCommandName, searchBrandErogsRouteKey)
+			erogsSearchGameWithSelectMenuCIDV2(ctx, s, i, sb.Providers, cid, searchBrandCommandName, searchBrandErogsRouteKey)
+		case switchMode{searchBrandVNDBRouteKey, utils.SwitchSourceBehavior}, switchMode{searchBrandErogsRouteKey, utils.SwitchSourceBehavior}:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseDeferredMessageUpdate,
+			})
+			switchCID := cid.ToSwitchSourceCIDV2()
+			if isVndbID(switchCID.Value) {
+				vndbSearchBrandGameDetail(ctx, s, i, sb.Providers, switchCID.Value, switchCID.RouteKey, switchCID.CacheID)
+			} else {
+				erogsSearchGameDetail(ctx, s, i, sb.Providers, switchCID.Value, searchBrandCommandName, switchCID.RouteKey, switchCID.CacheID)
+			}
 		case switchMode{searchBrandErogsRouteKey, utils.BackToHomeBehavior}:
-			common.BackToHome(s, i, cid.ToBackToHomeCIDV2(), cache.ErogsBrandStore, func(cacheValue *erogs.Brand, page int, cacheID string) ([]discordgo.MessageComponent, error) {
-				statusMap, inWishMap, err := utils.LoadGameStateMaps(utils.GetUserID(i))
+			common.BackToHome(ctx, s, i, cid.ToBackToHomeCIDV2(), cache.ErogsBrandStore, func(cacheValue *erogs.Brand, page int, cacheID string) ([]discordgo.MessageComponent, error) {
+				statusMap, inWishMap, blackListMap, err := utils.LoadGameStateMaps(utils.GetUserID(i))
 				if err != nil {
 					return nil, err
 				}
-				return buildSearchBrandErogsComponents(cacheValue, page, cacheID, statusMap, inWishMap)
+				return buildSearchBrandErogsComponents(cacheValue, page, cacheID, statusMap, inWishMap, blackListMap)
 			})
 		default:
 			utils.HandleErrorV2(kurohelperrerrors.ErrCIDBehaviorMismatch, s, i, utils.InteractionRespondEditComplex)
@@ -128,10 +152,10 @@ func (sb *SearchBrand) HandleComponent(s *discordgo.Session, i *discordgo.Intera
 	}
 }
 
-func (sb *SearchBrand) Autocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	choices, err := executor.GetAutocomplete(s, i, erogs.BrandsName, erogs.BrandInvertedIndex)
+func (sb *SearchBrand) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	choices, err := executor.GetAutocomplete(s, i, autocomplete.Brands)
 	if err != nil {
-		slog.Warn(err.Error())
+		tracing.Logger(ctx).Warn(err.Error())
 		return
 	}
 
@@ -144,13 +168,13 @@ func (sb *SearchBrand) Autocomplete(s *discordgo.Session, i *discordgo.Interacti
 }
 
 // vndbSearchBrandWithCIDV2 查詢公司品牌(有CID版本)，目前只有翻頁事件
-func vndbSearchBrandWithCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func vndbSearchBrandWithCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	pageCID, err := cid.ToPageCIDV2()
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
-	common.ChangePage(s, i, pageCID, cache.VndbBrandStore, buildSearchBrandComponents)
+	common.ChangePage(ctx, s, i, pageCID, cache.VndbBrandStore, buildSearchBrandComponents)
 }
 
 // 產生查詢公司品牌的Components
@@ -219,7 +243,10 @@ func buildSearchBrandComponents(res *vndb.ProducerSearchResponse, currentPage in
 	}
 
 	// 產生選單組件
-	selectMenuComponents := utils.MakeSelectMenuComponent(brandMenuItems, searchBrandCommandName, searchBrandVNDBRouteKey, cacheID, "選擇遊戲查看詳細")
+	selectMenuComponents, err := utils.MakeSelectMenuComponent(brandMenuItems, searchBrandCommandName, searchBrandVNDBRouteKey, cacheID, "選擇遊戲查看詳細")
+	if err != nil {
+		return nil, err
+	}
 
 	// 產生翻頁組件
 	pageComponents, err := utils.MakeChangePageComponent(searchBrandCommandName, searchBrandVNDBRouteKey, currentPage, totalPages, cacheID)
@@ -242,16 +269,22 @@ func buildSearchBrandComponents(res *vndb.ProducerSearchResponse, currentPage in
 	}, nil
 }
 
-func vndbSearchBrandWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func vndbSearchBrandWithSelectMenuCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers, cid *utils.CIDV2) {
 	if cid.GetBehaviorID() != utils.SelectMenuBehavior {
 		utils.HandleErrorV2(errors.New("handlers: cid behavior id error"), s, i, utils.InteractionRespondEditComplex)
 		return
 	}
 
 	selectMenuCID := cid.ToSelectMenuCIDV2()
+	vndbSearchBrandGameDetail(ctx, s, i, p, selectMenuCID.Value, selectMenuCID.RouteKey, selectMenuCID.CacheID)
+}
 
+// 查詢品牌底下的單一 VNDB 遊戲資料
+//
+// backToHomeRouteKey與cacheID是原本列表的，從批評空間切換過來時會回到批評空間的品牌列表
+func vndbSearchBrandGameDetail(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers, vnID, backToHomeRouteKey, cacheID string) {
 	// 檢查 CID 快取是否存在
-	if _, err := cache.CIDV2Store.Get(selectMenuCID.CacheID); err != nil {
+	if _, err := cache.CIDV2Store.Get(cacheID); err != nil {
 		utils.HandleErrorV2(kurohelperservice.ErrCacheLost, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
@@ -267,24 +300,13 @@ func vndbSearchBrandWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Inter
 	})
 
 	// 嘗試從快取取得單一遊戲資料
-	res, err := cache.VndbGameStore.Get(selectMenuCID.Value)
+	res, err := cache.VndbGameStore.GetOrLoad(ctx, vnID, func(ctx context.Context) (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error) {
+		tracing.Logger(ctx).Info("vndb搜尋遊戲", "vnID", vnID)
+		return p.Vndb.GetVNByFuzzy(ctx, vnID)
+	})
 	if err != nil {
-		if errors.Is(err, kurohelperservice.ErrCacheLost) {
-			slog.Info("vndb搜尋遊戲", "vnID", selectMenuCID.Value)
-
-			res, err = vndb.GetVNByFuzzy(selectMenuCID.Value)
-			if err != nil {
-				utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-				return
-			}
-
-			// 將查詢結果存入快取
-			cache.VndbGameStore.Set(selectMenuCID.Value, res)
-
-		} else {
-			utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-			return
-		}
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+		return
 	}
 	/* 處理回傳結構 */
 
@@ -388,7 +410,7 @@ func vndbSearchBrandWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Inter
 	imageURL := res.Results[0].Image.Url
 	if res.Results[0].Image.Sexual >= 1 || res.Results[0].Image.Violence >= 1 {
 		imageURL = ""
-		slog.Info("封面已過濾圖片顯示", "gameTitle", gameTitle, "guildID", i.GuildID)
+		tracing.Logger(ctx).Info("封面已過濾圖片顯示", "gameTitle", gameTitle)
 	} else {
 		// 檢查是否允許顯示圖片
 		if i.GuildID != "" {
@@ -466,6 +488,13 @@ func vndbSearchBrandWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Inter
 		},
 	}
 
+	erogsKey := executor.ErogsKeyForVndbWithin(ctx, p.Erogs, res.Results[0].ID, res.Results[0].Alttitle, res.Results[0].Title)
+	navComponent, err := makeDetailNavComponent(searchBrandCommandName, backToHomeRouteKey, cacheID, erogsKey, "")
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+		return
+	}
+
 	containerComponents := []discordgo.MessageComponent{
 		discordgo.TextDisplay{
 			Content: fmt.Sprintf("# %s", gameTitle),
@@ -473,7 +502,7 @@ func vndbSearchBrandWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Inter
 		discordgo.Separator{Divider: &divider},
 		section,
 		discordgo.Separator{Divider: &divider},
-		utils.MakeBackToHomeComponent(searchBrandCommandName, searchBrandVNDBRouteKey, selectMenuCID.CacheID),
+		navComponent,
 	}
 
 	components := []discordgo.MessageComponent{
@@ -488,44 +517,47 @@ func vndbSearchBrandWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Inter
 
 // 批評空間
 
-func erogsSearchBrandV2(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	common.SearchList(s, i, cache.ErogsBrandStore, "erogs查詢公司品牌", func() (*erogs.Brand, error) {
+func erogsSearchBrandV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers) {
+	common.SearchList(ctx, s, i, cache.ErogsBrandStore, "erogs查詢公司品牌", func(ctx context.Context) (*erogs.Brand, error) {
 		keyword, err := utils.GetOptions(i, "keyword")
 		if err != nil {
 			return nil, err
 		}
-		return erogs.SearchBrandByKeyword([]string{keyword})
+		return p.Erogs.SearchBrandByKeyword(ctx, []string{keyword})
 	}, func(cacheValue *erogs.Brand, page int, cacheID string) ([]discordgo.MessageComponent, error) {
-		statusMap, inWishMap, err := utils.LoadGameStateMaps(utils.GetUserID(i))
+		statusMap, inWishMap, blackListMap, err := utils.LoadGameStateMaps(utils.GetUserID(i))
 		if err != nil {
 			return nil, err
 		}
-		return buildSearchBrandErogsComponents(cacheValue, page, cacheID, statusMap, inWishMap)
+		return buildSearchBrandErogsComponents(cacheValue, page, cacheID, statusMap, inWishMap, blackListMap)
 	})
 }
 
-func erogsSearchBrandWithCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func erogsSearchBrandWithCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	pageCID, err := cid.ToPageCIDV2()
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
-	common.ChangePage(s, i, pageCID, cache.ErogsBrandStore, func(cacheValue *erogs.Brand, page int, cacheID string) ([]discordgo.MessageComponent, error) {
-		statusMap, inWishMap, err := utils.LoadGameStateMaps(utils.GetUserID(i))
+	common.ChangePage(ctx, s, i, pageCID, cache.ErogsBrandStore, func(cacheValue *erogs.Brand, page int, cacheID string) ([]discordgo.MessageComponent, error) {
+		statusMap, inWishMap, blackListMap, err := utils.LoadGameStateMaps(utils.GetUserID(i))
 		if err != nil {
 			return nil, err
 		}
-		return buildSearchBrandErogsComponents(cacheValue, page, cacheID, statusMap, inWishMap)
+		return buildSearchBrandErogsComponents(cacheValue, page, cacheID, statusMap, inWishMap, blackListMap)
 	})
 }
 
-func buildSearchBrandErogsComponents(res *erogs.Brand, currentPage int, cacheID string, statusMap map[int]kurohelperdb.UserGameStatus, inWishMap map[int]struct{}) ([]discordgo.MessageComponent, error) {
+func buildSearchBrandErogsComponents(res *erogs.Brand, currentPage int, cacheID string, statusMap map[int]kurohelperdb.UserGameStatus, inWishMap map[int]struct{}, blackListMap map[int]struct{}) ([]discordgo.MessageComponent, error) {
 	if statusMap == nil {
 		statusMap = make(map[int]kurohelperdb.UserGameStatus)
 	}
 	if inWishMap == nil {
 		inWishMap = make(map[int]struct{})
 	}
+	if blackListMap == nil {
+		blackListMap = make(map[int]struct{})
+	}
 	totalItems := len(res.GameList)
 	totalPages := (totalItems + searchBrandItemsPerPage - 1) / searchBrandItemsPerPage
 
@@ -545,7 +577,7 @@ func buildSearchBrandErogsComponents(res *erogs.Brand, currentPage int, cacheID
 	if linkLine != "" {
 		linkSection = linkLine + "\n"
 	}
-	headerContent := fmt.Sprintf("# %s\n%s遊戲筆數: **%d**\n✅: 已完成 🎮: 遊玩中 ⏸️: 擱置 🗑️: 棄坑 ❤️: 願望清單\n⭐: 批評空間分數(中位數/樣本差) 📊:投票人數 📅: 發售日期", brandTitle, linkSection, totalItems)
+	headerContent := fmt.Sprintf("# %s\n%s遊戲筆數: **%d**\n✅: 已完成 🎮: 遊玩中 ⏸️: 擱置 🗑️: 棄坑 ❤️: 願望清單 🚫: 黑名單\n⭐: 批評空間分數(中位數/樣本差) 📊:投票人數 📅: 發售日期", brandTitle, linkSection, totalItems)
 
 	divider := true
 	containerComponents := []discordgo.MessageComponent{
@@ -567,7 +599,8 @@ func buildSearchBrandErogsComponents(res *erogs.Brand, currentPage int, cacheID
 		itemNum := start + idx + 1
 		status := statusMap[item.ID]
 		_, inWish := inWishMap[item.ID]
-		statusSuffix := utils.FormatGameFlags(status, inWish)
+		_, blackListed := blackListMap[item.ID]
+		statusSuffix := utils.FormatGameFlags(status, inWish, blackListed)
 		if statusSuffix != "" {
 			statusSuffix = " " + statusSuffix
 		}
@@ -601,7 +634,10 @@ func buildSearchBrandErogsComponents(res *erogs.Brand, currentPage int, cacheID
 	}
 
 	// 產生選單組件
-	selectMenuComponents := utils.MakeSelectMenuComponent(brandMenuItems, searchBrandCommandName, searchBrandErogsRouteKey, cacheID, "選擇遊戲查看詳細")
+	selectMenuComponents, err := utils.MakeSelectMenuComponent(brandMenuItems, searchBrandCommandName, searchBrandErogsRouteKey, cacheID, "選擇遊戲查看詳細")
+	if err != nil {
+		return nil, err
+	}
 
 	// 產生翻頁組件
 	pageComponents, err := utils.MakeChangePageComponent(searchBrandCommandName, searchBrandErogsRouteKey, currentPage, totalPages, cacheID)
@@ -1,22 +1,24 @@
 package search
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
 
+	"kurohelper/internal/autocomplete"
 	"kurohelper/internal/cache"
 	kurohelperrerrors "kurohelper/internal/errors"
 	"kurohelper/internal/executor"
+	"kurohelper/internal/provider"
 	"kurohelper/internal/store"
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
-	"kurohelperservice"
 	"kurohelperservice/provider/bangumi"
 	"kurohelperservice/provider/vndb"
 )
@@ -27,7 +29,9 @@ const (
 	searchCharacterVNDBRouteKey     = "vndb"
 )
 
-type SearchCharacter struct{}
+type SearchCharacter struct {
+	Providers *provider.Providers
+}
 
 func (sc *SearchCharacter) Definition() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
@@ -35,10 +39,11 @@ func (sc *SearchCharacter) Definition() *discordgo.ApplicationCommand {
 		Description: "根據關鍵字查詢角色資料(VNDB, Bangumi)",
 		Options: []*discordgo.ApplicationCommandOption{
 			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "keyword",
-				Description: "關鍵字",
-				Required:    true,
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "keyword",
+				Description:  "關鍵字",
+				Autocomplete: true,
+				Required:     true,
 			},
 			{
 				Type:        discordgo.ApplicationCommandOptionString,
@@ -65,11 +70,35 @@ func (sc *SearchCharacter) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
-func (sc *SearchCharacter) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	sc.HandleComponent(s, i, nil)
+// 查詢結果只有本人可以翻頁、切換
+func (sc *SearchCharacter) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
+}
+
+func (sc *SearchCharacter) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	// 記錄Autocomplete熱門度
+	if keyword, err := utils.GetOptions(i, "keyword"); err == nil {
+		autocomplete.Characters.Hit(keyword)
+	}
+	sc.HandleComponent(ctx, s, i, nil)
 }
 
-func (sc *SearchCharacter) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func (sc *SearchCharacter) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	choices, err := executor.GetAutocomplete(s, i, autocomplete.Characters)
+	if err != nil {
+		tracing.Logger(ctx).Warn(err.Error())
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
+
+func (sc *SearchCharacter) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	if cid == nil {
 		optDB, err := utils.GetOptions(i, "查詢資料庫選項")
 		if err != nil && errors.Is(err, kurohelperrerrors.ErrOptionTranslateFail) {
@@ -78,38 +107,50 @@ func (sc *SearchCharacter) HandleComponent(s *discordgo.Session, i *discordgo.In
 		}
 		switch optDB {
 		case "1":
-			vndbSearchCharacterV2(s, i)
+			vndbSearchCharacterV2(ctx, s, i, sc.Providers)
 		case "3":
-			bangumiSearchCharacter(s, i)
+			bangumiSearchCharacter(ctx, s, i, sc.Providers)
 		default:
 			// 預設走 vndb 列表
-			vndbSearchCharacterV2(s, i)
+			vndbSearchCharacterV2(ctx, s, i, sc.Providers)
 		}
 	} else {
 		// 選擇不同行為的進入點
 		switch (switchMode{cid.GetRouteKey(), cid.GetBehaviorID()}) {
 		case switchMode{searchCharacterVNDBRouteKey, utils.PageBehavior}:
-			vndbSearchCharacterWithCIDV2(s, i, cid)
+			vndbSearchCharacterWithCIDV2(ctx, s, i, cid)
 		case switchMode{searchCharacterVNDBRouteKey, utils.SelectMenuBehavior}:
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseDeferredMessageUpdate,
 			})
-			vndbSearchCharacterWithSelectMenuCIDV2(s, i, cid)
+			vndbSearchCharacterWithSelectMenuCIDV2(ctx, s, i, sc.Providers, cid)
 		case switchMode{searchCharacterVNDBRouteKey, utils.BackToHomeBehavior}:
-			executor.BackToHome(s, i, cid.ToBackToHomeCIDV2(), cache.VndbCharacterListStore, buildSearchCharacterComponents)
+			executor.BackToHome(ctx, s, i, cid.ToBackToHomeCIDV2(), cache.VndbCharacterListStore, buildSearchCharacterComponents)
 		default:
 			utils.HandleErrorV2(kurohelperrerrors.ErrCIDBehaviorMismatch, s, i, utils.InteractionRespondEditComplex)
 		}
 	}
 }
 
-func vndbSearchCharacterV2(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	executor.SearchList(s, i, cache.VndbCharacterListStore, "vndb查詢角色列表", func() ([]vndb.CharacterSearchResponse, error) {
+func vndbSearchCharacterV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers) {
+	executor.SearchList(ctx, s, i, cache.VndbCharacterListStore, "vndb查詢角色列表", func(ctx context.Context) ([]vndb.CharacterSearchResponse, error) {
 		keyword, err := utils.GetOptions(i, "keyword")
 		if err != nil {
 			return nil, err
 		}
-		return vndb.GetCharacterListByFuzzy(keyword)
+		res, err := p.Vndb.GetCharacterListByFuzzy(ctx, keyword)
+		if err == nil {
+			names := make([]string, 0, len(res))
+			for _, c := range res {
+				if c.Original != "" {
+					names = append(names, c.Original)
+				} else {
+					names = append(names, c.Name)
+				}
+			}
+			autocomplete.Characters.Learn(names...)
+		}
+		return res, err
 	}, buildSearchCharacterComponents)
 }
 
@@ -194,9 +235,14 @@ func buildSearchCharacterComponents(res []vndb.CharacterSearchResponse, currentP
 		return nil, err
 	}
 
+	selectMenuComponents, err := utils.MakeSelectMenuComponent(menuItems, searchCharacterCommandName, searchCharacterVNDBRouteKey, cacheID, "選擇角色查看詳情")
+	if err != nil {
+		return nil, err
+	}
+
 	containerComponents = append(containerComponents,
 		discordgo.Separator{Divider: &divider},
-		utils.MakeSelectMenuComponent(menuItems, searchCharacterCommandName, searchCharacterVNDBRouteKey, cacheID, "選擇角色查看詳情"),
+		selectMenuComponents,
 		pageComponents,
 	)
 
@@ -209,17 +255,17 @@ func buildSearchCharacterComponents(res []vndb.CharacterSearchResponse, currentP
 	}, nil
 }
 
-func vndbSearchCharacterWithCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func vndbSearchCharacterWithCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	pageCID, err := cid.ToPageCIDV2()
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
-	executor.ChangePage(s, i, pageCID, cache.VndbCharacterListStore, buildSearchCharacterComponents)
+	executor.ChangePage(ctx, s, i, pageCID, cache.VndbCharacterListStore, buildSearchCharacterComponents)
 }
 
 // 查詢單一 VNDB 角色資料(有CID版本，從選單選擇)
-func vndbSearchCharacterWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func vndbSearchCharacterWithSelectMenuCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers, cid *utils.CIDV2) {
 	if cid.GetBehaviorID() != utils.SelectMenuBehavior {
 		utils.HandleErrorV2(errors.New("handlers: cid behavior id error"), s, i, utils.InteractionRespondEditComplex)
 		return
@@ -237,21 +283,13 @@ func vndbSearchCharacterWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.I
 		},
 	})
 
-	var res *vndb.CharacterSearchResponse
-	res, err := cache.VndbCharacterStore.Get(selectMenuCID.Value)
+	res, err := cache.VndbCharacterStore.GetOrLoad(ctx, selectMenuCID.Value, func(ctx context.Context) (*vndb.CharacterSearchResponse, error) {
+		tracing.Logger(ctx).Info("vndb查詢角色ID", "charID", selectMenuCID.Value)
+		return p.Vndb.GetCharacterByID(ctx, selectMenuCID.Value)
+	})
 	if err != nil {
-		if errors.Is(err, kurohelperservice.ErrCacheLost) {
-			slog.Info("vndb查詢角色ID", "charID", selectMenuCID.Value)
-			res, err = vndb.GetCharacterByID(selectMenuCID.Value)
-			if err != nil {
-				utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-				return
-			}
-			cache.VndbCharacterStore.Set(selectMenuCID.Value, res)
-		} else {
-			utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-			return
-		}
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+		return
 	}
 
 	// 處理回傳結構
@@ -389,7 +427,12 @@ func vndbSearchCharacterWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.I
 		section,
 		discordgo.Separator{Divider: &divider},
 	}
-	containerComponents = append(containerComponents, utils.MakeBackToHomeComponent(searchCharacterCommandName, searchCharacterVNDBRouteKey, selectMenuCID.CacheID))
+	backToHome, err := utils.MakeBackToHomeComponent(searchCharacterCommandName, searchCharacterVNDBRouteKey, selectMenuCID.CacheID)
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+		return
+	}
+	containerComponents = append(containerComponents, backToHome)
 
 	components := []discordgo.MessageComponent{
 		discordgo.Container{
@@ -402,7 +445,7 @@ func vndbSearchCharacterWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.I
 }
 
 // Bangumi查詢角色處理
-func bangumiSearchCharacter(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func bangumiSearchCharacter(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers) {
 	keyword, err := utils.GetOptions(i, "keyword")
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondV2)
@@ -412,18 +455,17 @@ func bangumiSearchCharacter(s *discordgo.Session, i *discordgo.InteractionCreate
 	// 將 keyword 轉成 base64 作為快取鍵（無 CID 事件，直接用關鍵字對應實際資料）
 	cacheKey := base64.RawURLEncoding.EncodeToString([]byte(keyword))
 
-	var res *bangumi.Character
-	cacheValue, err := cache.BangumiCharacterStore.Get(cacheKey)
-	if err == nil {
-		res = cacheValue
-	} else {
-		res, err = bangumi.GetCharacterByFuzzy(keyword)
-		if err != nil {
-			utils.HandleErrorV2(err, s, i, utils.InteractionRespondV2)
-			return
+	res, err := cache.BangumiCharacterStore.GetOrLoad(ctx, cacheKey, func(ctx context.Context) (*bangumi.Character, error) {
+		tracing.Logger(ctx).Info("Bangumi查詢角色", "keyword", keyword)
+		res, err := p.Bangumi.GetCharacterByFuzzy(ctx, keyword)
+		if err == nil && res != nil {
+			autocomplete.Characters.Learn(res.Name)
 		}
-		slog.Info("Bangumi查詢角色", "keyword", keyword, "guildID", i.GuildID)
-		cache.BangumiCharacterStore.Set(cacheKey, res)
+		return res, err
+	})
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondV2)
+		return
 	}
 
 	nameData := res.Name
@@ -1,9 +1,9 @@
 package search
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
@@ -11,9 +11,12 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/uuid"
 
+	"kurohelper/internal/autocomplete"
 	"kurohelper/internal/cache"
 	kurohelperrerrors "kurohelper/internal/errors"
 	"kurohelper/internal/executor"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
 	"kurohelperservice"
 
@@ -31,7 +34,9 @@ const (
 
 var searchCreatorColor = 0xF8F8DF
 
-type SearchCreator struct{}
+type SearchCreator struct {
+	Providers *provider.Providers
+}
 
 func (sc *SearchCreator) Definition() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
@@ -39,27 +44,56 @@ func (sc *SearchCreator) Definition() *discordgo.ApplicationCommand {
 		Description: "根據關鍵字查詢創作者資料(批評空間)",
 		Options: []*discordgo.ApplicationCommandOption{
 			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "keyword",
-				Description: "關鍵字",
-				Required:    true,
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "keyword",
+				Description:  "關鍵字",
+				Autocomplete: true,
+				Required:     true,
 			},
 		},
 	}
 }
 
-func (sc *SearchCreator) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	sc.HandleComponent(s, i, nil)
+// 查詢結果只有本人可以翻頁、切換
+func (sc *SearchCreator) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
 }
 
-func (sc *SearchCreator) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func (sc *SearchCreator) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	// 記錄Autocomplete熱門度
+	if keyword, err := utils.GetOptions(i, "keyword"); err == nil {
+		autocomplete.Creators.Hit(keyword)
+	}
+	sc.HandleComponent(ctx, s, i, nil)
+}
+
+func (sc *SearchCreator) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	choices, err := executor.GetAutocomplete(s, i, autocomplete.Creators)
+	if err != nil {
+		tracing.Logger(ctx).Warn(err.Error())
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
+
+func (sc *SearchCreator) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	if cid == nil {
-		executor.SearchList(s, i, cache.ErogsCreatorListStore, "erogs查詢創作者列表", func() ([]erogs.CreatorList, error) {
+		executor.SearchList(ctx, s, i, cache.ErogsCreatorListStore, "erogs查詢創作者列表", func(ctx context.Context) ([]erogs.CreatorList, error) {
 			keyword, err := utils.GetOptions(i, "keyword")
 			if err != nil {
 				return nil, err
 			}
-			return erogs.SearchCreatorListByKeyword([]string{keyword, kurohelperservice.ZhTwToJp(keyword)})
+			res, err := sc.Providers.Erogs.SearchCreatorListByKeyword(ctx, []string{keyword, kurohelperservice.ZhTwToJp(keyword)})
+			if err == nil {
+				learnCreatorNames(autocomplete.Creators, res)
+			}
+			return res, err
 		}, buildSearchCreatorListComponents)
 	} else {
 		routeKey, behaviorID := cid.GetRouteKey(), cid.GetBehaviorID()
@@ -68,22 +102,22 @@ func (sc *SearchCreator) HandleComponent(s *discordgo.Session, i *discordgo.Inte
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseDeferredMessageUpdate,
 			})
-			erogsSearchGameWithSelectMenuCIDV2(s, i, cid, searchCreatorCommandName, searchCreatorDetailRouteKey)
+			erogsSearchGameWithSelectMenuCIDV2(ctx, s, i, sc.Providers, cid, searchCreatorCommandName, searchCreatorDetailRouteKey)
 		case routeKey == searchCreatorDetailRouteKey && behaviorID == utils.BackToHomeBehavior:
-			executor.BackToHome(s, i, cid.ToBackToHomeCIDV2(), cache.ErogsCreatorStore, buildSearchCreatorDetailComponents)
+			executor.BackToHome(ctx, s, i, cid.ToBackToHomeCIDV2(), cache.ErogsCreatorStore, buildSearchCreatorDetailComponents)
 		case behaviorID == utils.PageBehavior:
 			if routeKey == searchCreatorDetailRouteKey {
-				erogsSearchCreatorDetailWithCIDV2(s, i, cid)
+				erogsSearchCreatorDetailWithCIDV2(ctx, s, i, cid)
 			} else {
-				erogsSearchCreatorListWithCIDV2(s, i, cid)
+				erogsSearchCreatorListWithCIDV2(ctx, s, i, cid)
 			}
 		case behaviorID == utils.DetailBtnBehavior:
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseDeferredMessageUpdate,
 			})
-			erogsSearchCreatorWithSelectMenuCIDV2(s, i, cid)
+			erogsSearchCreatorWithSelectMenuCIDV2(ctx, s, i, sc.Providers, cid)
 		case behaviorID == utils.BackToHomeBehavior:
-			executor.BackToHome(s, i, cid.ToBackToHomeCIDV2(), cache.ErogsCreatorListStore, buildSearchCreatorListComponents)
+			executor.BackToHome(ctx, s, i, cid.ToBackToHomeCIDV2(), cache.ErogsCreatorListStore, buildSearchCreatorListComponents)
 		default:
 			utils.HandleErrorV2(kurohelperrerrors.ErrCIDBehaviorMismatch, s, i, utils.InteractionRespondEditComplex)
 		}
@@ -91,27 +125,27 @@ func (sc *SearchCreator) HandleComponent(s *discordgo.Session, i *discordgo.Inte
 }
 
 // erogsSearchCreatorListWithCIDV2 創作者列表翻頁
-func erogsSearchCreatorListWithCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func erogsSearchCreatorListWithCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	pageCID, err := cid.ToPageCIDV2()
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
-	executor.ChangePage(s, i, pageCID, cache.ErogsCreatorListStore, buildSearchCreatorListComponents)
+	executor.ChangePage(ctx, s, i, pageCID, cache.ErogsCreatorListStore, buildSearchCreatorListComponents)
 }
 
 // erogsSearchCreatorDetailWithCIDV2 創作者詳情歷代作品翻頁（僅詳情，與列表完全無關）
-func erogsSearchCreatorDetailWithCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func erogsSearchCreatorDetailWithCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	pageCID, err := cid.ToPageCIDV2()
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
-	executor.ChangePage(s, i, pageCID, cache.ErogsCreatorStore, buildSearchCreatorDetailComponents)
+	executor.ChangePage(ctx, s, i, pageCID, cache.ErogsCreatorStore, buildSearchCreatorDetailComponents)
 }
 
 // erogsSearchCreatorWithSelectMenuCIDV2 以 CID 的 value 作為查詢 id 顯示創作者詳情（選單或按鈕「查看詳情」進入，統一取 cid value）
-func erogsSearchCreatorWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func erogsSearchCreatorWithSelectMenuCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers, cid *utils.CIDV2) {
 	detailCID := cid.ToDetailBtnCIDV2()
 	creatorKey := detailCID.Value
 
@@ -125,32 +159,24 @@ func erogsSearchCreatorWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.In
 		},
 	})
 
-	res, err := cache.ErogsCreatorStore.Get(creatorKey)
-	if err != nil {
-		if errors.Is(err, kurohelperservice.ErrCacheLost) {
-			slog.Info("erogs查詢創作者", "creatorKey", creatorKey)
-			cleanStr := strings.TrimPrefix(creatorKey, "E")
-			cleanStr = strings.TrimPrefix(cleanStr, "e")
-			creatorID, err := strconv.Atoi(cleanStr)
-			if err != nil {
-				utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-				return
-			}
-			res, err = erogs.SearchCreatorByID(creatorID)
-			if err != nil {
-				utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-				return
-			}
-			cache.ErogsCreatorStore.Set(creatorKey, res)
-		} else {
-			utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-			return
+	res, err := cache.ErogsCreatorStore.GetOrLoad(ctx, creatorKey, func(ctx context.Context) (*erogs.Creator, error) {
+		tracing.Logger(ctx).Info("erogs查詢創作者", "creatorKey", creatorKey)
+		cleanStr := strings.TrimPrefix(creatorKey, "E")
+		cleanStr = strings.TrimPrefix(cleanStr, "e")
+		creatorID, err := strconv.Atoi(cleanStr)
+		if err != nil {
+			return nil, err
 		}
+		return p.Erogs.SearchCreatorByID(ctx, creatorID)
+	})
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+		return
 	}
 
 	// 選擇後與原列表脫鉤，僅用 PageCID：cacheID 只存 creatorKey，後續翻頁完全獨立
 	detailCacheID := uuid.New().String()
-	cache.CIDV2Store.Set(detailCacheID, creatorKey)
+	cache.CIDV2Store.Set(detailCacheID, cache.CIDEntry{Key: creatorKey, OwnerID: utils.GetUserID(i)})
 
 	components, err := buildSearchCreatorDetailComponents(res, 1, detailCacheID)
 	if err != nil {
@@ -246,7 +272,10 @@ func buildSearchCreatorDetailComponents(res *erogs.Creator, currentPage int, pag
 	}
 
 	// 與 search_game_v2 相同：選單選擇遊戲可跳轉遊戲詳情，並可回到上一頁（創作者詳情）
-	selectMenuComponents := utils.MakeSelectMenuComponent(gameMenuItems, searchCreatorCommandName, searchCreatorGameSelectRouteKey, pageCacheID, "選擇遊戲查看詳細")
+	selectMenuComponents, err := utils.MakeSelectMenuComponent(gameMenuItems, searchCreatorCommandName, searchCreatorGameSelectRouteKey, pageCacheID, "選擇遊戲查看詳細")
+	if err != nil {
+		return nil, err
+	}
 	containerComponents = append(containerComponents, discordgo.Separator{Divider: &divider}, selectMenuComponents)
 
 	if totalItems > searchCreatorItemsPerPage {
@@ -291,6 +320,10 @@ func buildSearchCreatorListComponents(res []erogs.CreatorList, currentPage int,
 	creatorMenuItems := make([]utils.SelectMenuItem, 0, len(pagedResults))
 	for idx, r := range pagedResults {
 		itemNum := start + idx + 1
+		detailCID, err := utils.MakeDetailBtnCIDV2(searchCreatorCommandName, searchCreatorListRouteKey, cacheID, "e"+strconv.Itoa(r.ID))
+		if err != nil {
+			return nil, err
+		}
 		containerComponents = append(containerComponents, discordgo.Section{
 			Components: []discordgo.MessageComponent{
 				discordgo.TextDisplay{
@@ -300,7 +333,7 @@ func buildSearchCreatorListComponents(res []erogs.CreatorList, currentPage int,
 			Accessory: discordgo.Button{
 				Label:    "查看詳情",
 				Style:    discordgo.PrimaryButton,
-				CustomID: utils.MakeDetailBtnCIDV2(searchCreatorCommandName, searchCreatorListRouteKey, cacheID, "e"+strconv.Itoa(r.ID)),
+				CustomID: detailCID,
 			},
 		})
 		creatorMenuItems = append(creatorMenuItems, utils.SelectMenuItem{
@@ -325,3 +358,12 @@ func buildSearchCreatorListComponents(res []erogs.CreatorList, currentPage int,
 		},
 	}, nil
 }
+
+// 把創作者(歌手)列表的名稱加入Autocomplete
+func learnCreatorNames(source *autocomplete.Source, list []erogs.CreatorList) {
+	names := make([]string, 0, len(list))
+	for _, c := range list {
+		names = append(names, c.Name)
+	}
+	source.Learn(names...)
+}
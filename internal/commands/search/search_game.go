@@ -1,25 +1,30 @@
 package search
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"kurohelper/internal/autocomplete"
 	"kurohelper/internal/cache"
 	kurohelperrerrors "kurohelper/internal/errors"
 	"kurohelper/internal/executor"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/ratelimit"
 	"kurohelper/internal/store"
+	"kurohelper/internal/tracing"
+	"kurohelper/internal/userdata"
 	"kurohelper/internal/utils"
 	"kurohelperservice"
 	kurohelperdb "kurohelperservice/db"
 	"kurohelperservice/provider/erogs"
 	"kurohelperservice/provider/seiya"
 	"kurohelperservice/provider/vndb"
-	"kurohelperservice/provider/ymgal"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/siongui/gojianfan"
@@ -43,7 +48,9 @@ type switchMode struct {
 	BehaviorID utils.BehaviorID
 }
 
-type SearchGame struct{}
+type SearchGame struct {
+	Providers *provider.Providers
+}
 
 func (sg *SearchGame) Definition() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
@@ -77,12 +84,26 @@ func (sg *SearchGame) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
-func (sg *SearchGame) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	sg.HandleComponent(s, i, nil)
+// 查詢結果只有本人可以翻頁、切換
+func (sg *SearchGame) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
+}
+
+// 查詢遊戲每分鐘最多10次
+func (sg *SearchGame) RateLimit() ratelimit.Rule {
+	return ratelimit.Rule{Burst: 10, Per: time.Minute}
+}
+
+func (sg *SearchGame) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	// 記錄Autocomplete熱門度
+	if keyword, err := utils.GetOptions(i, "keyword"); err == nil {
+		autocomplete.Games.Hit(keyword)
+	}
+	sg.HandleComponent(ctx, s, i, nil)
 }
 
 // 查詢遊戲Handler進入點
-func (sg *SearchGame) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func (sg *SearchGame) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	if cid == nil {
 		optDB, err := utils.GetOptions(i, "查詢資料庫選項")
 		if err != nil && errors.Is(err, kurohelperrerrors.ErrOptionTranslateFail) {
@@ -91,45 +112,55 @@ func (sg *SearchGame) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 		}
 		switch optDB {
 		case "1":
-			executor.SearchList(s, i, cache.VndbGameListStore, "vndb查詢遊戲列表", func() ([]vndb.GetVnIDUseListResponse, error) {
+			executor.SearchList(ctx, s, i, cache.VndbGameListStore, "vndb查詢遊戲列表", func(ctx context.Context) ([]vndb.GetVnIDUseListResponse, error) {
 				keyword, err := utils.GetOptions(i, "keyword")
 				if err != nil {
 					return nil, err
 				}
-				return vndb.GetVnID(keyword)
+				return sg.Providers.Vndb.GetVnID(ctx, keyword)
 			}, buildVndbSearchGameComponents)
 		case "2":
-			erogsSearchGameListV2(s, i)
+			erogsSearchGameListV2(ctx, s, i, sg.Providers)
 		default:
 			// 預設走批評空間
-			erogsSearchGameListV2(s, i)
+			erogsSearchGameListV2(ctx, s, i, sg.Providers)
 		}
 	} else {
 		// 選擇不同行為的進入點
 		switch (switchMode{cid.GetRouteKey(), cid.GetBehaviorID()}) {
 		case switchMode{searchGameVndbRouteKey, utils.PageBehavior}:
-			vndbSearchGameListWithCIDV2(s, i, cid)
+			vndbSearchGameListWithCIDV2(ctx, s, i, cid)
 		case switchMode{searchGameErogsRouteKey, utils.PageBehavior}:
-			erogsSearchGameListWithCIDV2(s, i, cid)
+			erogsSearchGameListWithCIDV2(ctx, s, i, cid)
 		case switchMode{searchGameVndbRouteKey, utils.SelectMenuBehavior}:
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseDeferredMessageUpdate,
 			})
-			vndbSearchGameWithSelectMenuCIDV2(s, i, cid)
+			vndbSearchGameWithSelectMenuCIDV2(ctx, s, i, sg.Providers, cid)
 		case switchMode{searchGameErogsRouteKey, utils.SelectMenuBehavior}:
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseDeferredMessageUpdate,
 			})
-			erogsSearchGameWithSelectMenuCIDV2(s, i, cid, searchGameCommandName, searchGameErogsRouteKey)
+			erogsSearchGameWithSelectMenuCIDV2(ctx, s, i, sg.Providers, cid, searchGameCommandName, searchGameErogsRouteKey)
+		case switchMode{searchGameVndbRouteKey, utils.SwitchSourceBehavior}, switchMode{searchGameErogsRouteKey, utils.SwitchSourceBehavior}:
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseDeferredMessageUpdate,
+			})
+			switchCID := cid.ToSwitchSourceCIDV2()
+			if isVndbID(switchCID.Value) {
+				vndbSearchGameDetail(ctx, s, i, sg.Providers, switchCID.Value, switchCID.RouteKey, switchCID.CacheID)
+			} else {
+				erogsSearchGameDetail(ctx, s, i, sg.Providers, switchCID.Value, searchGameCommandName, switchCID.RouteKey, switchCID.CacheID)
+			}
 		case switchMode{searchGameVndbRouteKey, utils.BackToHomeBehavior}:
-			executor.BackToHome(s, i, cid.ToBackToHomeCIDV2(), cache.VndbGameListStore, buildVndbSearchGameComponents)
+			executor.BackToHome(ctx, s, i, cid.ToBackToHomeCIDV2(), cache.VndbGameListStore, buildVndbSearchGameComponents)
 		case switchMode{searchGameErogsRouteKey, utils.BackToHomeBehavior}:
-			executor.BackToHome(s, i, cid.ToBackToHomeCIDV2(), cache.ErogsGameListStore, func(cacheValue []erogs.GameList, page int, cacheID string) ([]discordgo.MessageComponent, error) {
-				statusMap, inWishMap, err := utils.LoadGameStateMaps(utils.GetUserID(i))
+			executor.BackToHome(ctx, s, i, cid.ToBackToHomeCIDV2(), cache.ErogsGameListStore, func(cacheValue []erogs.GameList, page int, cacheID string) ([]discordgo.MessageComponent, error) {
+				statusMap, inWishMap, blackListMap, err := utils.LoadGameStateMaps(utils.GetUserID(i))
 				if err != nil {
 					return nil, err
 				}
-				return buildSearchGameComponents(cacheValue, page, cacheID, statusMap, inWishMap)
+				return buildSearchGameComponents(cacheValue, page, cacheID, statusMap, inWishMap, blackListMap)
 			})
 		default:
 			utils.HandleErrorV2(kurohelperrerrors.ErrCIDBehaviorMismatch, s, i, utils.InteractionRespondEditComplex)
@@ -137,10 +168,10 @@ func (sg *SearchGame) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 	}
 }
 
-func (sg *SearchGame) Autocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	choices, err := executor.GetAutocomplete(s, i, erogs.GamesName, erogs.GameInvertedIndex)
+func (sg *SearchGame) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	choices, err := executor.GetAutocomplete(s, i, autocomplete.Games)
 	if err != nil {
-		slog.Warn(err.Error())
+		tracing.Logger(ctx).Warn(err.Error())
 		return
 	}
 
@@ -153,57 +184,63 @@ func (sg *SearchGame) Autocomplete(s *discordgo.Session, i *discordgo.Interactio
 }
 
 // 查詢遊戲列表
-func erogsSearchGameListV2(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	executor.SearchList(s, i, cache.ErogsGameListStore, "erogs查詢遊戲列表", func() ([]erogs.GameList, error) {
+func erogsSearchGameListV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers) {
+	executor.SearchList(ctx, s, i, cache.ErogsGameListStore, "erogs查詢遊戲列表", func(ctx context.Context) ([]erogs.GameList, error) {
 		keyword, err := utils.GetOptions(i, "keyword")
 		if err != nil {
 			return nil, err
 		}
 		if utils.IsAllHanziOrDigit(keyword) && strings.EqualFold(os.Getenv("USE_YMGAL_OPTIMIZATION"), "true") {
-			slog.Info("ymgal查詢遊戲(跳板)", "keyword", keyword, "guildID", i.GuildID)
-			ymgalKeyword, ymgalErr := ymgalGetGameString(keyword)
+			tracing.Logger(ctx).Info("ymgal查詢遊戲(跳板)", "keyword", keyword)
+			ymgalKeyword, ymgalErr := ymgalGetGameString(ctx, p, keyword)
 			if ymgalErr != nil {
-				slog.Warn(ymgalErr.Error(), "guildID", i.GuildID)
+				tracing.Logger(ctx).Warn(ymgalErr.Error())
 			}
 			if strings.TrimSpace(ymgalKeyword) != "" {
 				keyword = ymgalKeyword
 			}
 		}
-		return erogs.SearchGameListByKeyword([]string{keyword, kurohelperservice.ZhTwToJp(keyword)})
+		return p.Erogs.SearchGameListByKeyword(ctx, []string{keyword, kurohelperservice.ZhTwToJp(keyword)})
 	}, func(cacheValue []erogs.GameList, page int, cacheID string) ([]discordgo.MessageComponent, error) {
-		statusMap, inWishMap, err := utils.LoadGameStateMaps(utils.GetUserID(i))
+		statusMap, inWishMap, blackListMap, err := utils.LoadGameStateMaps(utils.GetUserID(i))
 		if err != nil {
 			return nil, err
 		}
-		return buildSearchGameComponents(cacheValue, page, cacheID, statusMap, inWishMap)
+		return buildSearchGameComponents(cacheValue, page, cacheID, statusMap, inWishMap, blackListMap)
 	})
 }
 
 // 查詢遊戲列表(有CID版本)
-func erogsSearchGameListWithCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func erogsSearchGameListWithCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	pageCID, err := cid.ToPageCIDV2()
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
-	executor.ChangePage(s, i, pageCID, cache.ErogsGameListStore, func(cacheValue []erogs.GameList, page int, cacheID string) ([]discordgo.MessageComponent, error) {
-		statusMap, inWishMap, err := utils.LoadGameStateMaps(utils.GetUserID(i))
+	executor.ChangePage(ctx, s, i, pageCID, cache.ErogsGameListStore, func(cacheValue []erogs.GameList, page int, cacheID string) ([]discordgo.MessageComponent, error) {
+		statusMap, inWishMap, blackListMap, err := utils.LoadGameStateMaps(utils.GetUserID(i))
 		if err != nil {
 			return nil, err
 		}
-		return buildSearchGameComponents(cacheValue, page, cacheID, statusMap, inWishMap)
+		return buildSearchGameComponents(cacheValue, page, cacheID, statusMap, inWishMap, blackListMap)
 	})
 }
 
 // 查詢單一遊戲資料(有CID版本，從選單選擇)
-func erogsSearchGameWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2, backToHomeCommandName, backToHomeRouteKey string) {
+func erogsSearchGameWithSelectMenuCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers, cid *utils.CIDV2, backToHomeCommandName, backToHomeRouteKey string) {
 	if cid.GetBehaviorID() != utils.SelectMenuBehavior {
 		utils.HandleErrorV2(errors.New("handlers: cid behavior id error"), s, i, utils.InteractionRespondEditComplex)
 		return
 	}
 
 	selectMenuCID := cid.ToSelectMenuCIDV2()
+	erogsSearchGameDetail(ctx, s, i, p, selectMenuCID.Value, backToHomeCommandName, backToHomeRouteKey, selectMenuCID.CacheID)
+}
 
+// 查詢單一遊戲資料，gameKey為"e"+批評空間遊戲ID
+//
+// backToHomeRouteKey與cacheID是原本列表的，從選單或切換來源進來都可以回到同一個列表
+func erogsSearchGameDetail(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers, gameKey, backToHomeCommandName, backToHomeRouteKey, cacheID string) {
 	utils.WebhookEditRespond(s, i, []discordgo.MessageComponent{
 		discordgo.Container{
 			Components: []discordgo.MessageComponent{
@@ -214,55 +251,39 @@ func erogsSearchGameWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Inter
 		},
 	})
 
-	res, err := cache.ErogsGameStore.Get(selectMenuCID.Value)
-	if err != nil {
-		if errors.Is(err, kurohelperservice.ErrCacheLost) {
-			slog.Info("erogs查詢遊戲", "gameID", selectMenuCID.Value, "guildID", i.GuildID)
-
-			cleanStr := strings.TrimPrefix(selectMenuCID.Value, "E")
-			cleanStr = strings.TrimPrefix(cleanStr, "e")
-			erogsID, err := strconv.Atoi(cleanStr)
-			if err != nil {
-				utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-				return
-			}
-
-			res, err = erogs.SearchGameByID(erogsID)
-			if err != nil {
-				utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-				return
-			}
-
-			cache.ErogsGameStore.Set(selectMenuCID.Value, res)
+	res, err := cache.ErogsGameStore.GetOrLoad(ctx, gameKey, func(ctx context.Context) (*erogs.Game, error) {
+		tracing.Logger(ctx).Info("erogs查詢遊戲", "gameID", gameKey)
 
-		} else {
-			utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-			return
+		cleanStr := strings.TrimPrefix(gameKey, "E")
+		cleanStr = strings.TrimPrefix(cleanStr, "e")
+		erogsID, err := strconv.Atoi(cleanStr)
+		if err != nil {
+			return nil, err
 		}
+
+		return p.Erogs.SearchGameByID(ctx, erogsID)
+	})
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+		return
 	}
 
 	// 處理使用者資訊
 	discordID := utils.GetUserID(i)
-	var userData strings.Builder
-	userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, discordID)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+	userData, userDetail, err := loadUserGameLine(discordID, res.ID)
+	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
-	for _, item := range userGames {
-		if item.GameErogsID != res.ID {
-			continue
-		}
-		userData.WriteString(utils.FormatGameFlags(item.Status, item.WishListMark))
-		break
-	}
 
 	// 獲取 VNDB 資料
 	vndbRating := 0.0
 	vndbVotecount := 0
 	var resVndb *vndb.BasicResponse[vndb.GetVnUseIDResponse]
 	if strings.TrimSpace(res.VndbId) != "" {
-		resVndb, err = vndb.GetVNByID(res.VndbId)
+		resVndb, err = cache.VndbGameStore.GetOrLoad(ctx, res.VndbId, func(ctx context.Context) (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error) {
+			return p.Vndb.GetVNByID(ctx, res.VndbId)
+		})
 		if err != nil {
 			utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 			return
@@ -448,6 +469,11 @@ func erogsSearchGameWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Inter
 		contentParts = append(contentParts, fmt.Sprintf("**類型**\n%s", res.Genre))
 	}
 
+	// 個人心得
+	if userDetail.Notes != "" {
+		contentParts = append(contentParts, fmt.Sprintf("**個人心得**\n%s", userDetail.Notes))
+	}
+
 	// 其他資訊
 	contentParts = append(contentParts, fmt.Sprintf("**其他資訊**\n%s", otherInfo))
 
@@ -477,14 +503,19 @@ func erogsSearchGameWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Inter
 
 	containerComponents := []discordgo.MessageComponent{
 		discordgo.TextDisplay{
-			Content: fmt.Sprintf("# **%s(%s)**%s", res.Gamename, res.SellDay, userData.String()),
+			Content: fmt.Sprintf("# **%s(%s)**%s", res.Gamename, res.SellDay, userData),
 		},
 		discordgo.Separator{Divider: &divider},
 		section,
 		discordgo.Separator{Divider: &divider},
 	}
 
-	containerComponents = append(containerComponents, utils.MakeBackToHomeComponent(backToHomeCommandName, backToHomeRouteKey, selectMenuCID.CacheID))
+	navComponent, err := makeDetailNavComponent(backToHomeCommandName, backToHomeRouteKey, cacheID, "", strings.TrimSpace(res.VndbId))
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+		return
+	}
+	containerComponents = append(containerComponents, navComponent)
 
 	components := []discordgo.MessageComponent{
 		discordgo.Container{
@@ -497,20 +528,23 @@ func erogsSearchGameWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Inter
 }
 
 // 產生查詢遊戲列表的Components
-func buildSearchGameComponents(res []erogs.GameList, currentPage int, cacheID string, statusMap map[int]kurohelperdb.UserGameStatus, inWishMap map[int]struct{}) ([]discordgo.MessageComponent, error) {
+func buildSearchGameComponents(res []erogs.GameList, currentPage int, cacheID string, statusMap map[int]kurohelperdb.UserGameStatus, inWishMap map[int]struct{}, blackListMap map[int]struct{}) ([]discordgo.MessageComponent, error) {
 	if statusMap == nil {
 		statusMap = make(map[int]kurohelperdb.UserGameStatus)
 	}
 	if inWishMap == nil {
 		inWishMap = make(map[int]struct{})
 	}
+	if blackListMap == nil {
+		blackListMap = make(map[int]struct{})
+	}
 	totalItems := len(res)
 	totalPages := (totalItems + searchGameListItemsPerPage - 1) / searchGameListItemsPerPage
 
 	divider := true
 	containerComponents := []discordgo.MessageComponent{
 		discordgo.TextDisplay{
-			Content: fmt.Sprintf("# 遊戲搜尋\n搜尋筆數: **%d**\n✅: 已完成 🎮: 遊玩中 ⏸️: 擱置 🗑️: 棄坑 ❤️: 願望清單\n⭐: 批評空間分數 📊: 投票人數 ⏱️: 遊玩時數 🥰: 開始理解遊戲樂趣時數", totalItems),
+			Content: fmt.Sprintf("# 遊戲搜尋\n搜尋筆數: **%d**\n✅: 已完成 🎮: 遊玩中 ⏸️: 擱置 🗑️: 棄坑 ❤️: 願望清單 🚫: 黑名單\n⭐: 批評空間分數 📊: 投票人數 ⏱️: 遊玩時數 🥰: 開始理解遊戲樂趣時數", totalItems),
 		},
 		discordgo.Separator{Divider: &divider},
 	}
@@ -527,7 +561,8 @@ func buildSearchGameComponents(res []erogs.GameList, currentPage int, cacheID st
 		itemNum := start + idx + 1
 		status := statusMap[r.ID]
 		_, inWish := inWishMap[r.ID]
-		statusSuffix := utils.FormatGameFlags(status, inWish)
+		_, blackListed := blackListMap[r.ID]
+		statusSuffix := utils.FormatGameFlags(status, inWish, blackListed)
 		if statusSuffix != "" {
 			statusSuffix = " **|** " + statusSuffix
 		}
@@ -568,7 +603,10 @@ func buildSearchGameComponents(res []erogs.GameList, currentPage int, cacheID st
 	}
 
 	// 產生選單組件
-	selectMenuComponents := utils.MakeSelectMenuComponent(gameMenuItems, searchGameCommandName, searchGameErogsRouteKey, cacheID, "選擇遊戲查看詳細")
+	selectMenuComponents, err := utils.MakeSelectMenuComponent(gameMenuItems, searchGameCommandName, searchGameErogsRouteKey, cacheID, "選擇遊戲查看詳細")
+	if err != nil {
+		return nil, err
+	}
 
 	// 產生翻頁組件
 	pageComponents, err := utils.MakeChangePageComponent(searchGameCommandName, searchGameErogsRouteKey, currentPage, totalPages, cacheID)
@@ -592,10 +630,10 @@ func buildSearchGameComponents(res []erogs.GameList, currentPage int, cacheID st
 }
 
 // 月幕查詢遊戲名稱處理
-func ymgalGetGameString(keyword string) (string, error) {
-	slog.Debug("ymgal查詢遊戲", "keyword", keyword)
+func ymgalGetGameString(ctx context.Context, p *provider.Providers, keyword string) (string, error) {
+	tracing.Logger(ctx).Debug("ymgal查詢遊戲", "keyword", keyword)
 
-	searchGameRes, err := ymgal.SearchGame(gojianfan.T2S(keyword))
+	searchGameRes, err := p.Ymgal.SearchGame(ctx, gojianfan.T2S(keyword))
 	if err != nil {
 		return "", err
 	}
@@ -608,24 +646,30 @@ func ymgalGetGameString(keyword string) (string, error) {
 }
 
 // 查詢 VNDB 遊戲列表(有CID版本)
-func vndbSearchGameListWithCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func vndbSearchGameListWithCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	pageCID, err := cid.ToPageCIDV2()
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
-	executor.ChangePage(s, i, pageCID, cache.VndbGameListStore, buildVndbSearchGameComponents)
+	executor.ChangePage(ctx, s, i, pageCID, cache.VndbGameListStore, buildVndbSearchGameComponents)
 }
 
 // 查詢單一 VNDB 遊戲資料(有CID版本，從選單選擇)
-func vndbSearchGameWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func vndbSearchGameWithSelectMenuCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers, cid *utils.CIDV2) {
 	if cid.GetBehaviorID() != utils.SelectMenuBehavior {
 		utils.HandleErrorV2(errors.New("handlers: cid behavior id error"), s, i, utils.InteractionRespondEditComplex)
 		return
 	}
 
 	selectMenuCID := cid.ToSelectMenuCIDV2()
+	vndbSearchGameDetail(ctx, s, i, p, selectMenuCID.Value, selectMenuCID.RouteKey, selectMenuCID.CacheID)
+}
 
+// 查詢單一 VNDB 遊戲資料
+//
+// backToHomeRouteKey與cacheID是原本列表的，從批評空間切換過來時會回到批評空間的列表
+func vndbSearchGameDetail(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers, vnID, backToHomeRouteKey, cacheID string) {
 	utils.WebhookEditRespond(s, i, []discordgo.MessageComponent{
 		discordgo.Container{
 			Components: []discordgo.MessageComponent{
@@ -636,23 +680,13 @@ func vndbSearchGameWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Intera
 		},
 	})
 
-	res, err := cache.VndbGameStore.Get(selectMenuCID.Value)
+	res, err := cache.VndbGameStore.GetOrLoad(ctx, vnID, func(ctx context.Context) (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error) {
+		tracing.Logger(ctx).Info("vndb查詢遊戲", "vnID", vnID)
+		return p.Vndb.GetVNByID(ctx, vnID)
+	})
 	if err != nil {
-		if errors.Is(err, kurohelperservice.ErrCacheLost) {
-			slog.Info("vndb查詢遊戲", "vnID", selectMenuCID.Value, "guildID", i.GuildID)
-
-			res, err = vndb.GetVNByID(selectMenuCID.Value)
-			if err != nil {
-				utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-				return
-			}
-
-			cache.VndbGameStore.Set(selectMenuCID.Value, res)
-
-		} else {
-			utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-			return
-		}
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+		return
 	}
 
 	// 處理回傳結構
@@ -797,6 +831,22 @@ func vndbSearchGameWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Intera
 		contentParts = append(contentParts, fmt.Sprintf("**角色列表**\n%s", strings.Join(characters, " / ")))
 	}
 
+	// 使用者資料記錄在對應的批評空間遊戲上
+	erogsKey := executor.ErogsKeyForVndbWithin(ctx, p.Erogs, res.Results[0].ID, res.Results[0].Alttitle, res.Results[0].Title)
+	var userData string
+	if erogsID, err := strconv.Atoi(strings.TrimPrefix(erogsKey, "e")); err == nil {
+		var userDetail userdata.UserGameDetail
+		userData, userDetail, err = loadUserGameLine(utils.GetUserID(i), erogsID)
+		if err != nil {
+			utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+			return
+		}
+		// 個人心得
+		if userDetail.Notes != "" {
+			contentParts = append(contentParts, fmt.Sprintf("**個人心得**\n%s", userDetail.Notes))
+		}
+	}
+
 	// 相關遊戲
 	relationsGameDisplay := strings.Join(relationsGame, ", ")
 	if strings.TrimSpace(relationsGameDisplay) == "" {
@@ -821,7 +871,7 @@ func vndbSearchGameWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Intera
 	// 過濾色情/暴力圖片
 	if res.Results[0].Image.Sexual >= 1 || res.Results[0].Image.Violence >= 1 {
 		thumbnailURL = ""
-		slog.Info("封面已過濾圖片顯示", "gameTitle", gameTitle, "guildID", i.GuildID)
+		tracing.Logger(ctx).Info("封面已過濾圖片顯示", "gameTitle", gameTitle)
 	}
 
 	// 檢查是否允許顯示圖片
@@ -852,14 +902,19 @@ func vndbSearchGameWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Intera
 
 	containerComponents := []discordgo.MessageComponent{
 		discordgo.TextDisplay{
-			Content: fmt.Sprintf("# %s", gameTitle),
+			Content: fmt.Sprintf("# %s%s", gameTitle, userData),
 		},
 		discordgo.Separator{Divider: &divider},
 		section,
 		discordgo.Separator{Divider: &divider},
 	}
 
-	containerComponents = append(containerComponents, utils.MakeBackToHomeComponent(searchGameCommandName, searchGameVndbRouteKey, selectMenuCID.CacheID))
+	navComponent, err := makeDetailNavComponent(searchGameCommandName, backToHomeRouteKey, cacheID, erogsKey, "")
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+		return
+	}
+	containerComponents = append(containerComponents, navComponent)
 
 	components := []discordgo.MessageComponent{
 		discordgo.Container{
@@ -871,6 +926,38 @@ func vndbSearchGameWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Intera
 	utils.InteractionRespondEditComplex(s, i, components)
 }
 
+// 使用者對批評空間遊戲的標記與評分(接在標題後面)，以及完整的個人資料
+//
+// 沒有建檔的使用者不需要查詢資料庫，沒有紀錄時回傳空字串
+func loadUserGameLine(discordID string, gameErogsID int) (string, userdata.UserGameDetail, error) {
+	var detail userdata.UserGameDetail
+	if _, ok := store.UserStore[discordID]; !ok {
+		return "", detail, nil
+	}
+	userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, discordID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", detail, nil
+		}
+		return "", detail, err
+	}
+	for _, item := range userGames {
+		if item.GameErogsID != gameErogsID {
+			continue
+		}
+		line := utils.FormatGameFlags(item.Status, item.WishListMark, item.BlackListMark)
+		detail, err = userdata.GetUserGameDetail(kurohelperdb.Dbs, item.UserID, item.GameErogsID)
+		if err != nil {
+			return "", detail, err
+		}
+		if review := detail.FormatReview(); review != "" {
+			line += " " + review
+		}
+		return line, detail, nil
+	}
+	return "", detail, nil
+}
+
 // 產生查詢 VNDB 遊戲列表的Components
 func buildVndbSearchGameComponents(res []vndb.GetVnIDUseListResponse, currentPage int, cacheID string) ([]discordgo.MessageComponent, error) {
 	totalItems := len(res)
@@ -948,7 +1035,10 @@ func buildVndbSearchGameComponents(res []vndb.GetVnIDUseListResponse, currentPag
 	}
 
 	// 產生選單組件
-	selectMenuComponents := utils.MakeSelectMenuComponent(gameMenuItems, searchGameCommandName, searchGameVndbRouteKey, cacheID, "選擇遊戲查看詳細")
+	selectMenuComponents, err := utils.MakeSelectMenuComponent(gameMenuItems, searchGameCommandName, searchGameVndbRouteKey, cacheID, "選擇遊戲查看詳細")
+	if err != nil {
+		return nil, err
+	}
 
 	// 產生翻頁組件
 	pageComponents, err := utils.MakeChangePageComponent(searchGameCommandName, searchGameVndbRouteKey, currentPage, totalPages, cacheID)
@@ -1,17 +1,20 @@
 package search
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"strconv"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
 
+	"kurohelper/internal/autocomplete"
 	"kurohelper/internal/cache"
 	kurohelperrerrors "kurohelper/internal/errors"
 	"kurohelper/internal/executor"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
 
 	"kurohelperservice"
@@ -25,7 +28,9 @@ const (
 
 var searchMusicColor = 0xF8F8DF
 
-type SearchMusic struct{}
+type SearchMusic struct {
+	Providers *provider.Providers
+}
 
 func (sm *SearchMusic) Definition() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
@@ -43,41 +48,50 @@ func (sm *SearchMusic) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
-func (sm *SearchMusic) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	sm.HandleComponent(s, i, nil)
+// 查詢結果只有本人可以翻頁、切換
+func (sm *SearchMusic) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
+}
+
+func (sm *SearchMusic) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	// 記錄Autocomplete熱門度
+	if keyword, err := utils.GetOptions(i, "keyword"); err == nil {
+		autocomplete.Musics.Hit(keyword)
+	}
+	sm.HandleComponent(ctx, s, i, nil)
 }
 
 // 查詢音樂指令入口
-func (sm *SearchMusic) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func (sm *SearchMusic) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	if cid == nil {
-		executor.SearchList(s, i, cache.ErogsMusicListStore, "erogs查詢音樂列表", func() ([]erogs.MusicList, error) {
+		executor.SearchList(ctx, s, i, cache.ErogsMusicListStore, "erogs查詢音樂列表", func(ctx context.Context) ([]erogs.MusicList, error) {
 			keyword, err := utils.GetOptions(i, "keyword")
 			if err != nil {
 				return nil, err
 			}
-			return erogs.SearchMusicListByKeyword([]string{keyword, kurohelperservice.ZhTwToJp(keyword)})
+			return sm.Providers.Erogs.SearchMusicListByKeyword(ctx, []string{keyword, kurohelperservice.ZhTwToJp(keyword)})
 		}, buildSearchMusicComponents)
 	} else {
 		switch cid.GetBehaviorID() {
 		case utils.PageBehavior:
-			erogsSearchMusicListWithCIDV2(s, i, cid)
+			erogsSearchMusicListWithCIDV2(ctx, s, i, cid)
 		case utils.SelectMenuBehavior:
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseDeferredMessageUpdate,
 			})
-			erogsSearchMusicWithSelectMenuCIDV2(s, i, cid, searchMusicCommandName, searchMusicRouteKey)
+			erogsSearchMusicWithSelectMenuCIDV2(ctx, s, i, sm.Providers, cid, searchMusicCommandName, searchMusicRouteKey)
 		case utils.BackToHomeBehavior:
-			executor.BackToHome(s, i, cid.ToBackToHomeCIDV2(), cache.ErogsMusicListStore, buildSearchMusicComponents)
+			executor.BackToHome(ctx, s, i, cid.ToBackToHomeCIDV2(), cache.ErogsMusicListStore, buildSearchMusicComponents)
 		default:
 			utils.HandleErrorV2(kurohelperrerrors.ErrCIDBehaviorMismatch, s, i, utils.InteractionRespondEditComplex)
 		}
 	}
 }
 
-func (sm *SearchMusic) Autocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	choices, err := executor.GetAutocomplete(s, i, erogs.MusicsName, erogs.MusicInvertedIndex)
+func (sm *SearchMusic) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	choices, err := executor.GetAutocomplete(s, i, autocomplete.Musics)
 	if err != nil {
-		slog.Warn(err.Error())
+		tracing.Logger(ctx).Warn(err.Error())
 		return
 	}
 
@@ -90,17 +104,17 @@ func (sm *SearchMusic) Autocomplete(s *discordgo.Session, i *discordgo.Interacti
 }
 
 // 查詢音樂列表(有CID版本)
-func erogsSearchMusicListWithCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func erogsSearchMusicListWithCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	pageCID, err := cid.ToPageCIDV2()
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
-	executor.ChangePage(s, i, pageCID, cache.ErogsMusicListStore, buildSearchMusicComponents)
+	executor.ChangePage(ctx, s, i, pageCID, cache.ErogsMusicListStore, buildSearchMusicComponents)
 }
 
 // 查詢指定音樂(有CID版本)；backHomeCommandName/backHomeRouteKey 用於「返回」鈕對應的路由
-func erogsSearchMusicWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2, backHomeCommandName, backHomeRouteKey string) {
+func erogsSearchMusicWithSelectMenuCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers, cid *utils.CIDV2, backHomeCommandName, backHomeRouteKey string) {
 	if cid.GetBehaviorID() != utils.SelectMenuBehavior {
 		utils.HandleErrorV2(errors.New("handlers: cid behavior id error"), s, i, utils.InteractionRespondEditComplex)
 		return
@@ -118,31 +132,21 @@ func erogsSearchMusicWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Inte
 		},
 	})
 
-	res, err := cache.ErogsMusicStore.Get(selectMenuCID.Value)
-	if err != nil {
-		if errors.Is(err, kurohelperservice.ErrCacheLost) {
-			slog.Info("erogs查詢音樂", "musicID", selectMenuCID.Value)
-
-			cleanStr := strings.TrimPrefix(selectMenuCID.Value, "E")
-			cleanStr = strings.TrimPrefix(cleanStr, "e")
-			erogsID, err := strconv.Atoi(cleanStr)
-			if err != nil {
-				utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-				return
-			}
-
-			res, err = erogs.SearchMusicByID(erogsID)
-			if err != nil {
-				utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-				return
-			}
-
-			cache.ErogsMusicStore.Set(selectMenuCID.Value, res)
+	res, err := cache.ErogsMusicStore.GetOrLoad(ctx, selectMenuCID.Value, func(ctx context.Context) (*erogs.Music, error) {
+		tracing.Logger(ctx).Info("erogs查詢音樂", "musicID", selectMenuCID.Value)
 
-		} else {
-			utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-			return
+		cleanStr := strings.TrimPrefix(selectMenuCID.Value, "E")
+		cleanStr = strings.TrimPrefix(cleanStr, "e")
+		erogsID, err := strconv.Atoi(cleanStr)
+		if err != nil {
+			return nil, err
 		}
+
+		return p.Erogs.SearchMusicByID(ctx, erogsID)
+	})
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+		return
 	}
 
 	// 處理資料
@@ -232,7 +236,12 @@ func erogsSearchMusicWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Inte
 		discordgo.Separator{Divider: &divider},
 	}
 
-	containerComponents = append(containerComponents, utils.MakeBackToHomeComponent(backHomeCommandName, backHomeRouteKey, selectMenuCID.CacheID))
+	backToHome, err := utils.MakeBackToHomeComponent(backHomeCommandName, backHomeRouteKey, selectMenuCID.CacheID)
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+		return
+	}
+	containerComponents = append(containerComponents, backToHome)
 
 	utils.InteractionRespondEditComplex(s, i, []discordgo.MessageComponent{
 		discordgo.Container{
@@ -314,7 +323,10 @@ func buildSearchMusicComponents(res []erogs.MusicList, currentPage int, cacheID
 	}
 
 	// 產生選單組件
-	selectMenuComponents := utils.MakeSelectMenuComponent(gameMenuItems, searchMusicCommandName, searchMusicRouteKey, cacheID, "選擇音樂查看詳細")
+	selectMenuComponents, err := utils.MakeSelectMenuComponent(gameMenuItems, searchMusicCommandName, searchMusicRouteKey, cacheID, "選擇音樂查看詳細")
+	if err != nil {
+		return nil, err
+	}
 
 	// 產生翻頁組件
 	pageComponents, err := utils.MakeChangePageComponent(searchMusicCommandName, searchMusicRouteKey, currentPage, totalPages, cacheID)
@@ -1,9 +1,9 @@
 package search
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
@@ -11,9 +11,12 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/uuid"
 
+	"kurohelper/internal/autocomplete"
 	"kurohelper/internal/cache"
 	kurohelperrerrors "kurohelper/internal/errors"
 	"kurohelper/internal/executor"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
 	"kurohelperservice"
 
@@ -31,7 +34,9 @@ const (
 
 var searchSingerColor = 0x7DD3FC
 
-type SearchSinger struct{}
+type SearchSinger struct {
+	Providers *provider.Providers
+}
 
 func (ss *SearchSinger) Definition() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
@@ -39,27 +44,56 @@ func (ss *SearchSinger) Definition() *discordgo.ApplicationCommand {
 		Description: "[新]根據關鍵字查詢歌手相關資料(批評空間)",
 		Options: []*discordgo.ApplicationCommandOption{
 			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "keyword",
-				Description: "關鍵字",
-				Required:    true,
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "keyword",
+				Description:  "關鍵字",
+				Autocomplete: true,
+				Required:     true,
 			},
 		},
 	}
 }
 
-func (ss *SearchSinger) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	ss.HandleComponent(s, i, nil)
+// 查詢結果只有本人可以翻頁、切換
+func (ss *SearchSinger) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
+}
+
+func (ss *SearchSinger) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	// 記錄Autocomplete熱門度
+	if keyword, err := utils.GetOptions(i, "keyword"); err == nil {
+		autocomplete.Singers.Hit(keyword)
+	}
+	ss.HandleComponent(ctx, s, i, nil)
+}
+
+func (ss *SearchSinger) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	choices, err := executor.GetAutocomplete(s, i, autocomplete.Singers)
+	if err != nil {
+		tracing.Logger(ctx).Warn(err.Error())
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
 }
 
-func (ss *SearchSinger) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func (ss *SearchSinger) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	if cid == nil {
-		executor.SearchList(s, i, cache.ErogsSingerListStore, "erogs查詢歌手列表", func() ([]erogs.CreatorList, error) {
+		executor.SearchList(ctx, s, i, cache.ErogsSingerListStore, "erogs查詢歌手列表", func(ctx context.Context) ([]erogs.CreatorList, error) {
 			keyword, err := utils.GetOptions(i, "keyword")
 			if err != nil {
 				return nil, err
 			}
-			return erogs.SearchSingerListByKeyword([]string{keyword, kurohelperservice.ZhTwToJp(keyword)})
+			res, err := ss.Providers.Erogs.SearchSingerListByKeyword(ctx, []string{keyword, kurohelperservice.ZhTwToJp(keyword)})
+			if err == nil {
+				learnCreatorNames(autocomplete.Singers, res)
+			}
+			return res, err
 		}, buildSearchSingerListComponents)
 	} else {
 		routeKey, behaviorID := cid.GetRouteKey(), cid.GetBehaviorID()
@@ -68,47 +102,47 @@ func (ss *SearchSinger) HandleComponent(s *discordgo.Session, i *discordgo.Inter
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseDeferredMessageUpdate,
 			})
-			erogsSearchMusicWithSelectMenuCIDV2(s, i, cid, searchSingerCommandName, searchSingerDetailRouteKey)
+			erogsSearchMusicWithSelectMenuCIDV2(ctx, s, i, ss.Providers, cid, searchSingerCommandName, searchSingerDetailRouteKey)
 		case routeKey == searchSingerDetailRouteKey && behaviorID == utils.BackToHomeBehavior:
-			executor.BackToHome(s, i, cid.ToBackToHomeCIDV2(), cache.ErogsSingerStore, buildSearchSingerDetailComponents)
+			executor.BackToHome(ctx, s, i, cid.ToBackToHomeCIDV2(), cache.ErogsSingerStore, buildSearchSingerDetailComponents)
 		case behaviorID == utils.PageBehavior:
 			if routeKey == searchSingerDetailRouteKey {
-				erogsSearchSingerDetailWithCIDV2(s, i, cid)
+				erogsSearchSingerDetailWithCIDV2(ctx, s, i, cid)
 			} else {
-				erogsSearchSingerListWithCIDV2(s, i, cid)
+				erogsSearchSingerListWithCIDV2(ctx, s, i, cid)
 			}
 		case behaviorID == utils.DetailBtnBehavior:
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseDeferredMessageUpdate,
 			})
-			erogsSearchSingerWithSelectMenuCIDV2(s, i, cid)
+			erogsSearchSingerWithSelectMenuCIDV2(ctx, s, i, ss.Providers, cid)
 		case behaviorID == utils.BackToHomeBehavior:
-			executor.BackToHome(s, i, cid.ToBackToHomeCIDV2(), cache.ErogsSingerListStore, buildSearchSingerListComponents)
+			executor.BackToHome(ctx, s, i, cid.ToBackToHomeCIDV2(), cache.ErogsSingerListStore, buildSearchSingerListComponents)
 		default:
 			utils.HandleErrorV2(kurohelperrerrors.ErrCIDBehaviorMismatch, s, i, utils.InteractionRespondEditComplex)
 		}
 	}
 }
 
-func erogsSearchSingerListWithCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func erogsSearchSingerListWithCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	pageCID, err := cid.ToPageCIDV2()
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
-	executor.ChangePage(s, i, pageCID, cache.ErogsSingerListStore, buildSearchSingerListComponents)
+	executor.ChangePage(ctx, s, i, pageCID, cache.ErogsSingerListStore, buildSearchSingerListComponents)
 }
 
-func erogsSearchSingerDetailWithCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func erogsSearchSingerDetailWithCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	pageCID, err := cid.ToPageCIDV2()
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
-	executor.ChangePage(s, i, pageCID, cache.ErogsSingerStore, buildSearchSingerDetailComponents)
+	executor.ChangePage(ctx, s, i, pageCID, cache.ErogsSingerStore, buildSearchSingerDetailComponents)
 }
 
-func erogsSearchSingerWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func erogsSearchSingerWithSelectMenuCIDV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers, cid *utils.CIDV2) {
 	detailCID := cid.ToDetailBtnCIDV2()
 	singerKey := detailCID.Value
 
@@ -122,31 +156,23 @@ func erogsSearchSingerWithSelectMenuCIDV2(s *discordgo.Session, i *discordgo.Int
 		},
 	})
 
-	res, err := cache.ErogsSingerStore.Get(singerKey)
-	if err != nil {
-		if errors.Is(err, kurohelperservice.ErrCacheLost) {
-			slog.Info("erogs查詢歌手", "singerKey", singerKey)
-			cleanStr := strings.TrimPrefix(singerKey, "E")
-			cleanStr = strings.TrimPrefix(cleanStr, "e")
-			singerID, convErr := strconv.Atoi(cleanStr)
-			if convErr != nil {
-				utils.HandleErrorV2(convErr, s, i, utils.InteractionRespondEditComplex)
-				return
-			}
-			res, err = erogs.SearchSingerByKeyword(singerID)
-			if err != nil {
-				utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-				return
-			}
-			cache.ErogsSingerStore.Set(singerKey, res)
-		} else {
-			utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
-			return
+	res, err := cache.ErogsSingerStore.GetOrLoad(ctx, singerKey, func(ctx context.Context) (*erogs.Singer, error) {
+		tracing.Logger(ctx).Info("erogs查詢歌手", "singerKey", singerKey)
+		cleanStr := strings.TrimPrefix(singerKey, "E")
+		cleanStr = strings.TrimPrefix(cleanStr, "e")
+		singerID, convErr := strconv.Atoi(cleanStr)
+		if convErr != nil {
+			return nil, convErr
 		}
+		return p.Erogs.SearchSingerByKeyword(ctx, singerID)
+	})
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+		return
 	}
 
 	detailCacheID := uuid.New().String()
-	cache.CIDV2Store.Set(detailCacheID, singerKey)
+	cache.CIDV2Store.Set(detailCacheID, cache.CIDEntry{Key: singerKey, OwnerID: utils.GetUserID(i)})
 
 	components, err := buildSearchSingerDetailComponents(res, 1, detailCacheID)
 	if err != nil {
@@ -247,7 +273,10 @@ func buildSearchSingerDetailComponents(res *erogs.Singer, currentPage int, pageC
 	}
 
 	if len(musicMenuItems) > 0 {
-		selectMenuComponents := utils.MakeSelectMenuComponent(musicMenuItems, searchSingerCommandName, searchSingerMusicSelectRouteKey, pageCacheID, "選擇音樂查看詳細")
+		selectMenuComponents, err := utils.MakeSelectMenuComponent(musicMenuItems, searchSingerCommandName, searchSingerMusicSelectRouteKey, pageCacheID, "選擇音樂查看詳細")
+		if err != nil {
+			return nil, err
+		}
 		containerComponents = append(containerComponents, discordgo.Separator{Divider: &divider}, selectMenuComponents)
 	}
 
@@ -291,6 +320,10 @@ func buildSearchSingerListComponents(res []erogs.CreatorList, currentPage int, c
 
 	for idx, r := range pagedResults {
 		itemNum := start + idx + 1
+		detailCID, err := utils.MakeDetailBtnCIDV2(searchSingerCommandName, searchSingerListRouteKey, cacheID, "e"+strconv.Itoa(r.ID))
+		if err != nil {
+			return nil, err
+		}
 		containerComponents = append(containerComponents, discordgo.Section{
 			Components: []discordgo.MessageComponent{
 				discordgo.TextDisplay{
@@ -300,7 +333,7 @@ func buildSearchSingerListComponents(res []erogs.CreatorList, currentPage int, c
 			Accessory: discordgo.Button{
 				Label:    "查看詳情",
 				Style:    discordgo.PrimaryButton,
-				CustomID: utils.MakeDetailBtnCIDV2(searchSingerCommandName, searchSingerListRouteKey, cacheID, "e"+strconv.Itoa(r.ID)),
+				CustomID: detailCID,
 			},
 		})
 	}
@@ -0,0 +1,54 @@
+package search
+
+import (
+	"strconv"
+	"strings"
+
+	"kurohelper/internal/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 切換資料來源(批評空間 <-> VNDB)
+//
+// 切換來源CID沿用原本列表的RouteKey與CacheID，所以切換後「回到主頁」仍然回到原本的列表；
+// Value放目標資料來源的ID：批評空間為 "e" + 遊戲ID，VNDB為 vndb ID(v開頭)
+//
+// 月幕目前只有關鍵字搜尋，沒有可以對應的ID，所以沒有月幕的切換按鈕
+
+const (
+	switchToErogsLabel = "🔀批評空間"
+	switchToVndbLabel  = "🔀VNDB"
+)
+
+// 是否為VNDB遊戲ID(v + 數字)
+func isVndbID(id string) bool {
+	if !strings.HasPrefix(id, "v") {
+		return false
+	}
+	_, err := strconv.Atoi(id[1:])
+	return err == nil
+}
+
+// 詳細頁底部的按鈕列：回到主頁，以及有對應資料時切換到另一個資料來源
+func makeDetailNavComponent(commandName, routeKey, cacheID, erogsKey, vndbID string) (*discordgo.ActionsRow, error) {
+	row, err := utils.MakeBackToHomeComponent(commandName, routeKey, cacheID)
+	if err != nil {
+		return nil, err
+	}
+	if erogsKey != "" {
+		btn, err := utils.MakeSwitchSourceButton(switchToErogsLabel, commandName, routeKey, cacheID, erogsKey)
+		if err != nil {
+			return nil, err
+		}
+		row.Components = append(row.Components, btn)
+	}
+	if vndbID != "" {
+		btn, err := utils.MakeSwitchSourceButton(switchToVndbLabel, commandName, routeKey, cacheID, vndbID)
+		if err != nil {
+			return nil, err
+		}
+		row.Components = append(row.Components, btn)
+	}
+	return row, nil
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kurohelper/internal/autocomplete"
+	"kurohelper/internal/executor"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/tracing"
+	"kurohelper/internal/utils"
+)
+
+type AddBlackList struct {
+	Providers *provider.Providers
+}
+
+const addBlackListCommandName = "加黑名單"
+
+func (a *AddBlackList) Definition() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "加黑名單",
+		Description: "把遊戲加到黑名單，隨機遊戲不會再抽到(ErogameScape)",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "keyword",
+				Description:  "關鍵字",
+				Autocomplete: true,
+				Required:     true,
+			},
+		},
+	}
+}
+
+// 確認按鈕只有本人可以按
+func (a *AddBlackList) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
+}
+
+func (a *AddBlackList) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	a.HandleComponent(ctx, s, i, nil)
+}
+
+// 加黑名單Handler
+func (a *AddBlackList) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+	handleUserGameMark(ctx, s, i, cid, a.Providers, blackListMark)
+}
+
+func (a *AddBlackList) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	choices, err := executor.GetAutocomplete(s, i, autocomplete.Games)
+	if err != nil {
+		tracing.Logger(ctx).Warn(err.Error())
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
@@ -1,9 +1,9 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"regexp"
 	"strconv"
 	"strings"
@@ -18,18 +18,25 @@ import (
 	kurohelperdb "kurohelperservice/db"
 	"kurohelperservice/provider/erogs"
 
+	"kurohelper/internal/autocomplete"
 	"kurohelper/internal/cache"
 	kurohelpererrors "kurohelper/internal/errors"
 	"kurohelper/internal/executor"
+	"kurohelper/internal/provider"
 	"kurohelper/internal/store"
+	"kurohelper/internal/tracing"
+	"kurohelper/internal/userdata"
 	"kurohelper/internal/utils"
 )
 
-type AddHasPlayed struct{}
+type AddHasPlayed struct {
+	Providers *provider.Providers
+}
 
 type addHasPlayedCacheData struct {
 	Game             erogs.Game
 	CompleteDateText *string
+	Review           reviewInput
 }
 
 const addHasPlayedCommandName = "加已玩"
@@ -38,7 +45,7 @@ func (a *AddHasPlayed) Definition() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
 		Name:        "加已玩",
 		Description: "把遊戲加到已玩(ErogameScape)",
-		Options: []*discordgo.ApplicationCommandOption{
+		Options: append([]*discordgo.ApplicationCommandOption{
 			{
 				Type:         discordgo.ApplicationCommandOptionString,
 				Name:         "keyword",
@@ -52,15 +59,20 @@ func (a *AddHasPlayed) Definition() *discordgo.ApplicationCommand {
 				Description: "遊玩結束日期",
 				Required:    false,
 			},
-		},
+		}, reviewOptions()...),
 	}
 }
 
-func (a *AddHasPlayed) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	a.HandleComponent(s, i, nil)
+// 確認按鈕只有本人可以按
+func (a *AddHasPlayed) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
 }
 
-func (a *AddHasPlayed) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func (a *AddHasPlayed) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	a.HandleComponent(ctx, s, i, nil)
+}
+
+func (a *AddHasPlayed) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -130,10 +142,35 @@ func (a *AddHasPlayed) HandleComponent(s *discordgo.Session, i *discordgo.Intera
 				if err := kurohelperdb.EnsureUserGame(tx, user.ID, res.ID); err != nil {
 					return err
 				}
+				userGames, err := kurohelperdb.GetUserGameByDiscordID(tx, userID)
+				if err != nil {
+					return err
+				}
+				fromStatus := kurohelperdb.UserGameStatusNone
+				if ug := findUserGame(userGames, res.ID); ug != nil {
+					fromStatus = ug.Status
+				}
 				if err := kurohelperdb.UpdateUserGameFinished(tx, user.ID, res.ID, completeDate); err != nil {
 					return err
 				}
 
+				// 5. 記錄結束日期、評分心得與狀態變更
+				detail, err := userdata.GetUserGameDetail(tx, user.ID, res.ID)
+				if err != nil {
+					return err
+				}
+				date, err := detail.ApplyStatus(fromStatus, kurohelperdb.UserGameStatusFinished, nil, completeDate, time.Now())
+				if err != nil {
+					return err
+				}
+				cacheData.Review.apply(&detail)
+				if err := userdata.SaveUserGameDetail(tx, &detail); err != nil {
+					return err
+				}
+				if err := userdata.RecordStatusChange(tx, user.ID, res.ID, fromStatus, kurohelperdb.UserGameStatusFinished, date); err != nil {
+					return err
+				}
+
 				return nil // commit
 			})
 			if err != nil {
@@ -151,7 +188,7 @@ func (a *AddHasPlayed) HandleComponent(s *discordgo.Session, i *discordgo.Intera
 				Color: 0x7BA23F,
 			}
 			utils.InteractionEmbedRespondForSelf(s, i, embed, nil, true)
-			slog.Info("加已玩成功", "使用者ID", userID, "遊戲ID", res.ID, "遊戲名稱", res.Gamename)
+			tracing.Logger(ctx).Info("加已玩成功", "使用者ID", userID, "遊戲ID", res.ID, "遊戲名稱", res.Gamename)
 		} else { // 找不到使用者，此狀況應該會是Discord官方問題或是程式碼邏輯問題
 			embed := &discordgo.MessageEmbed{
 				Title: "找不到使用者！",
@@ -167,6 +204,7 @@ func (a *AddHasPlayed) HandleComponent(s *discordgo.Session, i *discordgo.Intera
 			utils.HandleError(err, s, i)
 			return
 		}
+		autocomplete.Games.Hit(keyword)
 
 		completeDate, err := utils.GetOptions(i, "complete_date")
 		if err != nil && !errors.Is(err, kurohelpererrors.ErrOptionNotFound) {
@@ -174,6 +212,12 @@ func (a *AddHasPlayed) HandleComponent(s *discordgo.Session, i *discordgo.Intera
 			return
 		}
 
+		review, err := getReviewOptions(i)
+		if err != nil {
+			utils.HandleError(err, s, i)
+			return
+		}
+
 		var t time.Time
 		if completeDate != "" {
 			t, err = utils.ParseYYYYMMDD(completeDate)
@@ -191,9 +235,9 @@ func (a *AddHasPlayed) HandleComponent(s *discordgo.Session, i *discordgo.Intera
 		idSearch, _ := regexp.MatchString(`^e\d+$`, keyword)
 		if idSearch {
 			num, _ := strconv.Atoi(keyword[1:])
-			res, err = erogs.SearchGameByID(num)
+			res, err = a.Providers.Erogs.SearchGameByID(ctx, num)
 		} else {
-			res, err = erogs.SearchGameByKeyword([]string{keyword, kurohelperservice.ZhTwToJp(keyword)})
+			res, err = a.Providers.Erogs.SearchGameByKeyword(ctx, []string{keyword, kurohelperservice.ZhTwToJp(keyword)})
 		}
 		if err != nil {
 			utils.HandleError(err, s, i)
@@ -206,54 +250,67 @@ func (a *AddHasPlayed) HandleComponent(s *discordgo.Session, i *discordgo.Intera
 
 		idStr := uuid.New().String()
 		cacheData := addHasPlayedCacheData{
-			Game: *res,
+			Game:   *res,
+			Review: review,
 		}
 		if !t.IsZero() {
 			completeDateText := t.Format("20060102")
 			cacheData.CompleteDateText = &completeDateText
 		}
 		cache.UserInfoCache.Set(idStr, cacheData)
+		cache.CIDV2Store.Set(idStr, cache.CIDEntry{OwnerID: utils.GetUserID(i)})
+
+		confirmCID, err := utils.MakeUserDataOperationCIDV2(addHasPlayedCommandName, idStr, res.ID)
+		if err != nil {
+			utils.HandleError(err, s, i)
+			return
+		}
 
 		messageComponent := []discordgo.MessageComponent{
 			discordgo.Button{
 				Label:    "✅",
 				Style:    discordgo.PrimaryButton,
-				CustomID: utils.MakeUserDataOperationCIDV2(addHasPlayedCommandName, idStr, res.ID),
+				CustomID: confirmCID,
 			},
 		}
 		actionsRow := utils.MakeActionsRow(messageComponent)
 
 		image := utils.GenerateImage(i, res.BannerUrl)
 
+		fields := []*discordgo.MessageEmbedField{
+			{
+				Name:   "發行機種",
+				Value:  res.Model,
+				Inline: false,
+			},
+		}
+		var preview userdata.UserGameDetail
+		review.apply(&preview)
+		fields = append(fields, reviewFields(preview)...)
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "確認",
+			Value:  "你確定要加入已玩嗎?",
+			Inline: false,
+		})
+
 		embed := &discordgo.MessageEmbed{
 			Author: &discordgo.MessageEmbedAuthor{
 				Name: res.BrandName,
 			},
-			Title: fmt.Sprintf("**%s(%s)**", res.Gamename, res.SellDay),
-			URL:   res.Shoukai,
-			Color: 0x7BA23F,
-			Fields: []*discordgo.MessageEmbedField{
-				{
-					Name:   "發行機種",
-					Value:  res.Model,
-					Inline: false,
-				},
-				{
-					Name:   "確認",
-					Value:  "你確定要加入已玩嗎?",
-					Inline: false,
-				},
-			},
-			Image: image,
+			Title:  fmt.Sprintf("**%s(%s)**", res.Gamename, res.SellDay),
+			URL:    res.Shoukai,
+			Color:  0x7BA23F,
+			Fields: fields,
+			Image:  image,
 		}
 		utils.InteractionEmbedRespondForSelf(s, i, embed, actionsRow, true)
 	}
 }
 
-func (a *AddHasPlayed) Autocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	choices, err := executor.GetAutocomplete(s, i, erogs.GamesName, erogs.GameInvertedIndex)
+func (a *AddHasPlayed) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	choices, err := executor.GetAutocomplete(s, i, autocomplete.Games)
 	if err != nil {
-		slog.Warn(err.Error())
+		tracing.Logger(ctx).Warn(err.Error())
 		return
 	}
 
@@ -1,29 +1,20 @@
 package user
 
 import (
-	"fmt"
-	"log/slog"
-	"regexp"
-	"strconv"
-	"strings"
-
-	"gorm.io/gorm"
+	"context"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/google/uuid"
-
-	"kurohelperservice"
-	kurohelperdb "kurohelperservice/db"
-	"kurohelperservice/provider/erogs"
 
-	"kurohelper/internal/cache"
-	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/autocomplete"
 	"kurohelper/internal/executor"
-	"kurohelper/internal/store"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
 )
 
-type AddInWish struct{}
+type AddInWish struct {
+	Providers *provider.Providers
+}
 
 const addInWishCommandName = "加收藏"
 
@@ -43,167 +34,24 @@ func (a *AddInWish) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
-func (a *AddInWish) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	a.HandleComponent(s, i, nil)
+// 確認按鈕只有本人可以按
+func (a *AddInWish) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
 }
 
-// 加收藏Handler
-func (a *AddInWish) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Flags: discordgo.MessageFlagsEphemeral,
-		},
-	})
-
-	if cid != nil {
-		if cid.GetBehaviorID() != utils.UserDataOperationBehavior {
-			utils.HandleError(kurohelpererrors.ErrCIDBehaviorMismatch, s, i)
-			return
-		}
-		userDataCID, err := cid.ToUserDataOperationCIDV2()
-		if err != nil {
-			utils.HandleError(err, s, i)
-			return
-		}
-
-		// get cache
-		cacheValue, err := cache.UserInfoCache.Get(userDataCID.CacheID)
-		if err != nil {
-			utils.HandleError(err, s, i)
-			return
-		}
-		resValue := cacheValue.(erogs.Game)
-		res := &resValue
-
-		userID := utils.GetUserID(i)
-		userName := utils.GetUsername(i)
-		if strings.TrimSpace(userID) != "" && strings.TrimSpace(userName) != "" {
-			err := kurohelperdb.Dbs.Transaction(func(tx *gorm.DB) error {
-				// 1. 確保 User 存在
-				if err := kurohelperdb.EnsureDiscordUser(tx, userID, userName); err != nil {
-					return err
-				}
-				user, err := kurohelperdb.GetUserByDiscordID(tx, userID)
-				if err != nil {
-					return err
-				}
-
-				// 2. 確保 Brand 存在
-				// 新增欄位資料先用預設值
-				if _, err := kurohelperdb.EnsureBrandErogs(tx, res.BrandID, res.BrandName, false, 0); err != nil {
-					return err
-				}
-
-				// 3. 確保 Game 存在
-				image := erogs.MakeDMMImageURL(res.DMM)
-				if strings.TrimSpace(res.DMM) == "" {
-					image = ""
-				}
-				if _, err := kurohelperdb.EnsureGameErogs(tx, res.ID, res.Gamename, image, res.BrandID, res.Model); err != nil {
-					return err
-				}
-
-				// 4. 先確保有空殼資料，再更新願望清單標記
-				if err := kurohelperdb.EnsureUserGame(tx, user.ID, res.ID); err != nil {
-					return err
-				}
-				if err := kurohelperdb.UpdateUserGameWishListMark(tx, user.ID, res.ID, true); err != nil {
-					return err
-				}
-
-				return nil // commit
-			})
-			if err != nil {
-				utils.HandleError(err, s, i)
-				return
-			}
-
-			// 確保新建立的使用者有加入快取
-			if _, ok := store.UserStore[userID]; !ok {
-				store.UserStore[userID] = struct{}{}
-			}
-
-			embed := &discordgo.MessageEmbed{
-				Title: "加入成功！",
-				Color: 0x90B44B,
-			}
-			utils.InteractionEmbedRespondForSelf(s, i, embed, nil, true)
-			slog.Info("加收藏成功", "使用者ID", userID, "遊戲ID", res.ID, "遊戲名稱", res.Gamename)
-		} else { // 找不到使用者，此狀況應該會是Discord官方問題或是程式碼邏輯問題
-			embed := &discordgo.MessageEmbed{
-				Title: "找不到使用者！",
-				Color: 0x90B44B,
-			}
-			utils.InteractionEmbedRespondForSelf(s, i, embed, nil, true)
-		}
-	} else {
-		var res *erogs.Game
-
-		keyword, err := utils.GetOptions(i, "keyword")
-		if err != nil {
-			utils.HandleError(err, s, i)
-			return
-		}
-
-		idSearch, _ := regexp.MatchString(`^e\d+$`, keyword)
-		if idSearch {
-			num, _ := strconv.Atoi(keyword[1:])
-			res, err = erogs.SearchGameByID(num)
-		} else {
-			res, err = erogs.SearchGameByKeyword([]string{keyword, kurohelperservice.ZhTwToJp(keyword)})
-		}
-		if err != nil {
-			utils.HandleError(err, s, i)
-			return
-		}
-		if res == nil {
-			utils.HandleError(kurohelperservice.ErrSearchNoContent, s, i)
-			return
-		}
-
-		idStr := uuid.New().String()
-		cache.UserInfoCache.Set(idStr, *res)
-
-		messageComponent := []discordgo.MessageComponent{
-			discordgo.Button{
-				Label:    "✅",
-				Style:    discordgo.PrimaryButton,
-				CustomID: utils.MakeUserDataOperationCIDV2(addInWishCommandName, idStr, res.ID),
-			},
-		}
-		actionsRow := utils.MakeActionsRow(messageComponent)
-
-		image := utils.GenerateImage(i, res.BannerUrl)
+func (a *AddInWish) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	a.HandleComponent(ctx, s, i, nil)
+}
 
-		embed := &discordgo.MessageEmbed{
-			Author: &discordgo.MessageEmbedAuthor{
-				Name: res.BrandName,
-			},
-			Title: fmt.Sprintf("**%s(%s)**", res.Gamename, res.SellDay),
-			URL:   res.Shoukai,
-			Color: 0x90B44B,
-			Fields: []*discordgo.MessageEmbedField{
-				{
-					Name:   "發行機種",
-					Value:  res.Model,
-					Inline: false,
-				},
-				{
-					Name:   "確認",
-					Value:  "你確定要加入收藏嗎?",
-					Inline: false,
-				},
-			},
-			Image: image,
-		}
-		utils.InteractionEmbedRespondForSelf(s, i, embed, actionsRow, true)
-	}
+// 加收藏Handler
+func (a *AddInWish) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+	handleUserGameMark(ctx, s, i, cid, a.Providers, wishMark)
 }
-func (a *AddInWish) Autocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	choices, err := executor.GetAutocomplete(s, i, erogs.GamesName, erogs.GameInvertedIndex)
+
+func (a *AddInWish) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	choices, err := executor.GetAutocomplete(s, i, autocomplete.Games)
 	if err != nil {
-		slog.Warn(err.Error())
+		tracing.Logger(ctx).Warn(err.Error())
 		return
 	}
 
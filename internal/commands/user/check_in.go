@@ -1,11 +1,12 @@
 package user
 
 import (
+	"context"
 	"fmt"
-	"log/slog"
 	"math/rand"
 	"time"
 
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
 
 	kurohelperdb "kurohelperservice/db"
@@ -40,11 +41,12 @@ func (c *CheckIn) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
-func (c *CheckIn) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (c *CheckIn) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
 	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	}); err != nil {
-		slog.Error("failed to defer check-in interaction", "error", err, "guildID", i.GuildID)
+		tracing.FromContext(ctx).RecordError(err)
+		tracing.Logger(ctx).Error("failed to defer check-in interaction", "error", err)
 		return
 	}
 
@@ -110,7 +112,7 @@ func (c *CheckIn) Handler(s *discordgo.Session, i *discordgo.InteractionCreate)
 		},
 	}
 
-	slog.Info(discordUser.Username+"使用了簽到功能",
+	tracing.Logger(ctx).Info(discordUser.Username+"使用了簽到功能",
 		"fortune", selected.Name,
 		"streak", checkIn.State.CurrentStreak,
 		"alreadyCheckedIn", checkIn.AlreadyCheckedIn,
@@ -0,0 +1,61 @@
+package user
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	kurohelperdb "kurohelperservice/db"
+
+	"kurohelper/internal/cache"
+	"kurohelper/internal/usertransfer"
+)
+
+// CIDV3Store 的值是any，每個存進去的型別都要 gob.Register 才能寫入快照並在重啟後還原
+func TestCIDV3StoreSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	cache.InitStorage(cache.StorageConfig{PersistDir: dir})
+	t.Cleanup(func() { cache.InitStorage(cache.StorageConfig{}) })
+
+	started := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	values := map[string]any{
+		uuid.NewString(): PreferenceCache{PrivateGameData: true, DiscordID: "1"},
+		uuid.NewString(): ImportPendingCache{
+			DiscordID: "1",
+			Items: []ImportAmbiguous{{
+				Entry: usertransfer.Entry{
+					VndbID:  "v17",
+					Titles:  []string{"Ever17"},
+					Status:  kurohelperdb.UserGameStatusFinished,
+					Started: &started,
+				},
+				Candidates: []ImportCandidate{{ID: 1, Name: "Ever17 -the out of infinity-"}},
+			}},
+		},
+	}
+	for key, v := range values {
+		cache.CIDV3Store.Set(key, v)
+	}
+
+	if err := cache.SaveAll(); err != nil {
+		t.Fatalf("SaveAll() error = %v", err)
+	}
+	for key := range values {
+		cache.CIDV3Store.Delete(key)
+	}
+	// 重新設定時會從快照還原
+	cache.InitStorage(cache.StorageConfig{PersistDir: dir})
+
+	for key, want := range values {
+		got, err := cache.CIDV3Store.Get(key)
+		if err != nil {
+			t.Errorf("%T not restored: %v", want, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("restored %T = %+v, want %+v", want, got, want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+
+	kurohelperdb "kurohelperservice/db"
+
+	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/tracing"
+	"kurohelper/internal/userdata"
+	"kurohelper/internal/utils"
+)
+
+// 編輯自己已建檔遊戲的評分、遊玩時數與心得，沒有填寫任何欄位時只顯示目前的紀錄
+type EditUserGame struct{}
+
+func (e *EditUserGame) Definition() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "編輯遊戲紀錄",
+		Description: "編輯已建檔遊戲的評分、遊玩時數與心得",
+		Options: append(append([]*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "keyword",
+				Description:  "關鍵字",
+				Autocomplete: true,
+				Required:     true,
+			},
+		}, reviewOptions()...), &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "clear",
+			Description: "清除欄位",
+			Required:    false,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "評分", Value: "rating"},
+				{Name: "遊玩時數", Value: "play_hours"},
+				{Name: "心得", Value: "notes"},
+				{Name: "全部", Value: "all"},
+			},
+		}),
+	}
+}
+
+func (e *EditUserGame) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	userID := utils.GetUserID(i)
+
+	keyword, err := utils.GetOptions(i, "keyword")
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	review, err := getReviewOptions(i)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	clearText, err := utils.GetOptions(i, "clear")
+	if err != nil && !errors.Is(err, kurohelpererrors.ErrOptionNotFound) {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, userID)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	target, err := findUserGameByKeyword(filterRemovableGames(userGames, 0), keyword)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	var detail userdata.UserGameDetail
+	edited := !review.empty() || clearText != ""
+	err = kurohelperdb.Dbs.Transaction(func(tx *gorm.DB) error {
+		detail, err = userdata.GetUserGameDetail(tx, target.UserID, target.GameErogsID)
+		if err != nil {
+			return err
+		}
+		if !edited {
+			return nil
+		}
+		// 先清除再寫入，同時指定時以新填寫的值為準
+		clearReview(&detail, clearText)
+		review.apply(&detail)
+		return userdata.SaveUserGameDetail(tx, &detail)
+	})
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	title := fmt.Sprintf("**%s**", target.GameErogs.Name)
+	if edited {
+		title = fmt.Sprintf("%s 編輯成功！", target.GameErogs.Name)
+	}
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name:   "遊玩狀態",
+			Value:  userdata.StatusName[target.Status] + " " + utils.FormatGameFlags(target.Status, target.WishListMark, target.BlackListMark),
+			Inline: false,
+		},
+	}
+	if dates := formatDateRange(detail.StartedDate, detail.EndedDate); dates != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "日期",
+			Value:  dates,
+			Inline: false,
+		})
+	}
+	fields = append(fields, reviewFields(detail)...)
+	if !detail.HasReview() {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "評分/遊玩時數",
+			Value:  "尚未填寫",
+			Inline: false,
+		})
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:  title,
+		Color:  0x7BA23F,
+		Fields: fields,
+	}
+	utils.InteractionEmbedRespondForSelf(s, i, embed, nil, true)
+	if edited {
+		tracing.Logger(ctx).Info("編輯遊戲紀錄成功", "使用者ID", userID, "遊戲ID", target.GameErogsID, "遊戲名稱", target.GameErogs.Name)
+	}
+}
+
+// 從使用者已建檔的遊戲中建議
+func (e *EditUserGame) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	var query string
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "keyword" {
+			query = opt.StringValue()
+		}
+	}
+
+	userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, utils.GetUserID(i))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		tracing.Logger(ctx).Warn(err.Error())
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: userGameChoices(filterRemovableGames(userGames, 0), query),
+		},
+	})
+}
+
+func clearReview(detail *userdata.UserGameDetail, clearText string) {
+	switch strings.TrimSpace(clearText) {
+	case "rating":
+		detail.Rating = 0
+	case "play_hours":
+		detail.PlayHours = 0
+	case "notes":
+		detail.Notes = ""
+	case "all":
+		detail.Rating = 0
+		detail.PlayHours = 0
+		detail.Notes = ""
+	}
+}
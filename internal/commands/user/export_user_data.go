@@ -0,0 +1,146 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	kurohelperdb "kurohelperservice/db"
+
+	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/tracing"
+	"kurohelper/internal/userdata"
+	"kurohelper/internal/usertransfer"
+	"kurohelper/internal/utils"
+)
+
+// 匯出建檔資料成檔案，格式可以直接用「匯入遊戲資料」匯回
+type ExportUserData struct{}
+
+func (e *ExportUserData) Definition() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "匯出資料",
+		Description: "把建檔的遊戲資料匯出成檔案",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "format",
+				Description: "檔案格式（預設JSON）",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "JSON", Value: "json"},
+					{Name: "CSV", Value: "csv"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "discord_id",
+				Description: "要匯出的使用者 Discord ID（選填）",
+				Required:    false,
+			},
+		},
+	}
+}
+
+func (e *ExportUserData) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	requesterID := utils.GetUserID(i)
+	targetDiscordID := requesterID
+	targetUserIDOption, err := utils.GetOptions(i, "discord_id")
+	if err != nil && !errors.Is(err, kurohelpererrors.ErrOptionNotFound) {
+		utils.HandleError(err, s, i)
+		return
+	}
+	if strings.TrimSpace(targetUserIDOption) != "" {
+		targetDiscordID = strings.TrimSpace(targetUserIDOption)
+	}
+	format, err := utils.GetOptions(i, "format")
+	if err != nil && !errors.Is(err, kurohelpererrors.ErrOptionNotFound) {
+		utils.HandleError(err, s, i)
+		return
+	}
+	if format != "csv" {
+		format = "json"
+	}
+
+	user, err := kurohelperdb.GetUserByDiscordID(kurohelperdb.Dbs, targetDiscordID)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	if targetDiscordID != requesterID && user.PrivateGameData {
+		utils.HandleError(kurohelpererrors.ErrPrivateGameData, s, i)
+		return
+	}
+
+	userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, targetDiscordID)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	userGames = filterDisplayUserGames(userGames)
+	details, err := userdata.GetUserGameDetails(kurohelperdb.Dbs, user.ID)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	brandIDs := make([]int, 0, len(userGames))
+	for _, ug := range userGames {
+		brandIDs = append(brandIDs, ug.GameErogs.BrandID)
+	}
+	brandNames, err := userdata.GetBrandNames(kurohelperdb.Dbs, brandIDs)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	records := make([]usertransfer.Record, 0, len(userGames))
+	for _, ug := range userGames {
+		records = append(records, usertransfer.NewRecord(ug, details[ug.GameErogsID], brandNames[ug.GameErogs.BrandID]))
+	}
+
+	var buf bytes.Buffer
+	contentType := "application/json"
+	if format == "csv" {
+		contentType = "text/csv"
+		err = usertransfer.WriteCSV(&buf, records)
+	} else {
+		err = usertransfer.WriteJSON(&buf, records)
+	}
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "匯出成功！",
+		Description: fmt.Sprintf("**%s** 共 %d 筆遊戲資料", user.Name, len(records)),
+		Color:       0x7BA23F,
+	}
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{embed},
+		Files: []*discordgo.File{
+			{
+				Name:        fmt.Sprintf("kurohelper_%s_%s.%s", targetDiscordID, time.Now().Format("20060102"), format),
+				ContentType: contentType,
+				Reader:      &buf,
+			},
+		},
+	})
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	tracing.Logger(ctx).Info("匯出資料成功", "使用者ID", requesterID, "匯出對象", targetDiscordID, "格式", format, "筆數", len(records))
+}
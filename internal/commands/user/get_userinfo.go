@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -10,6 +11,7 @@ import (
 
 	"kurohelper/internal/cache"
 	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/userdata"
 	"kurohelper/internal/utils"
 
 	kurohelperdb "kurohelperservice/db"
@@ -19,7 +21,10 @@ type UserInfo struct {
 	User            kurohelperdb.User
 	UserGames       []kurohelperdb.UserGame
 	BrandStatistics []kurohelperdb.BrandCount
+	Details         map[int]userdata.UserGameDetail
 	Avatar          string
+	// 遊戲列表只顯示黑名單
+	BlackListOnly bool
 }
 
 type GetUserinfo struct{}
@@ -49,15 +54,30 @@ func (g *GetUserinfo) Definition() *discordgo.ApplicationCommand {
 				Description: "要查詢的使用者 Discord ID（選填）",
 				Required:    false,
 			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "list",
+				Description: "遊戲列表類型（選填）",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "全部", Value: "all"},
+					{Name: "黑名單", Value: "blacklist"},
+				},
+			},
 		},
 	}
 }
 
-func (g *GetUserinfo) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	g.HandleComponent(s, i, nil)
+// 個人資料只有查詢的人可以翻頁
+func (g *GetUserinfo) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
+}
+
+func (g *GetUserinfo) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	g.HandleComponent(ctx, s, i, nil)
 }
 
-func (g *GetUserinfo) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+func (g *GetUserinfo) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	// 長時間查詢
 	if cid == nil {
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -70,7 +90,10 @@ func (g *GetUserinfo) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 	var brandStatistics []kurohelperdb.BrandCount
 	var completedCount int
 	var wishCount int
+	var blackListCount int
+	var blackListOnly bool
 	var avatar string
+	var details map[int]userdata.UserGameDetail
 	listUserGames := make([]string, 0, 10)
 
 	if cid != nil {
@@ -99,9 +122,17 @@ func (g *GetUserinfo) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 			if item.WishListMark {
 				wishCount++
 			}
+			if item.BlackListMark {
+				blackListCount++
+			}
+		}
+		blackListOnly = userInfo.BlackListOnly
+		if blackListOnly {
+			filteredUserGames = filterBlackListGames(filteredUserGames)
 		}
 		user = userInfo.User
 		brandStatistics = userInfo.BrandStatistics
+		details = userInfo.Details
 		avatar = userInfo.Avatar
 
 		// 取得資料頁
@@ -113,13 +144,24 @@ func (g *GetUserinfo) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 			hasMore = true
 		}
 
+		prevCID, err := utils.MakePageCIDV2(userInfoCommandName, "", pageIndex-1, pageCID.CacheID, false)
+		if err != nil {
+			utils.HandleError(err, s, i)
+			return
+		}
+		nextCID, err := utils.MakePageCIDV2(userInfoCommandName, "", pageIndex+1, pageCID.CacheID, false)
+		if err != nil {
+			utils.HandleError(err, s, i)
+			return
+		}
+
 		if hasMore {
 			if pageIndex == 0 {
 				messageComponent = []discordgo.MessageComponent{
 					discordgo.Button{
 						Label:    "▶️",
 						Style:    discordgo.PrimaryButton,
-						CustomID: utils.MakePageCIDV2(userInfoCommandName, "", 1, pageCID.CacheID, false),
+						CustomID: nextCID,
 					},
 				}
 			} else {
@@ -127,13 +169,13 @@ func (g *GetUserinfo) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 					discordgo.Button{
 						Label:    "◀️",
 						Style:    discordgo.PrimaryButton,
-						CustomID: utils.MakePageCIDV2(userInfoCommandName, "", pageIndex-1, pageCID.CacheID, false),
+						CustomID: prevCID,
 					},
 				}
 				messageComponent = append(messageComponent, discordgo.Button{
 					Label:    "▶️",
 					Style:    discordgo.PrimaryButton,
-					CustomID: utils.MakePageCIDV2(userInfoCommandName, "", pageIndex+1, pageCID.CacheID, false),
+					CustomID: nextCID,
 				})
 			}
 		} else {
@@ -141,7 +183,7 @@ func (g *GetUserinfo) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 				discordgo.Button{
 					Label:    "◀️",
 					Style:    discordgo.PrimaryButton,
-					CustomID: utils.MakePageCIDV2(userInfoCommandName, "", pageIndex-1, pageCID.CacheID, false),
+					CustomID: prevCID,
 				},
 			}
 		}
@@ -152,7 +194,7 @@ func (g *GetUserinfo) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 				break
 			}
 
-			listUserGames = append(listUserGames, formatUserGameLine(startNo+i, &ug))
+			listUserGames = append(listUserGames, formatUserGameLine(startNo+i, &ug, details[ug.GameErogsID]))
 		}
 	} else {
 		requesterID := utils.GetUserID(i)
@@ -166,6 +208,12 @@ func (g *GetUserinfo) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 		if strings.TrimSpace(targetUserIDOption) != "" {
 			targetDiscordID = strings.TrimSpace(targetUserIDOption)
 		}
+		listOption, err := utils.GetOptions(i, "list")
+		if err != nil && !errors.Is(err, kurohelpererrors.ErrOptionNotFound) {
+			utils.HandleError(err, s, i)
+			return
+		}
+		blackListOnly = listOption == "blacklist"
 
 		// User資料
 		userTmp, err := kurohelperdb.GetUserByDiscordID(kurohelperdb.Dbs, targetDiscordID)
@@ -201,6 +249,13 @@ func (g *GetUserinfo) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 			if item.WishListMark {
 				wishCount++
 			}
+			if item.BlackListMark {
+				blackListCount++
+			}
+		}
+		listGames := userGames
+		if blackListOnly {
+			listGames = filterBlackListGames(userGames)
 		}
 
 		// Brand資料統計
@@ -210,32 +265,47 @@ func (g *GetUserinfo) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 			return
 		}
 
+		// 評分、遊玩時數
+		details, err = userdata.GetUserGameDetails(kurohelperdb.Dbs, user.ID)
+		if err != nil {
+			utils.HandleError(err, s, i)
+			return
+		}
+
 		// 處理翻頁
-		if len(userGames) > 10 {
+		if len(listGames) > 10 {
 			userInfo := UserInfo{
 				User:            user,
 				UserGames:       userGames,
 				BrandStatistics: brandStatistics,
+				Details:         details,
 				Avatar:          avatarURL,
+				BlackListOnly:   blackListOnly,
 			}
 
 			idStr := uuid.New().String()
 			cache.UserInfoCache.Set(idStr, userInfo)
+			cache.CIDV2Store.Set(idStr, cache.CIDEntry{OwnerID: utils.GetUserID(i)})
 
+			nextCID, err := utils.MakePageCIDV2(userInfoCommandName, "", 1, idStr, false)
+			if err != nil {
+				utils.HandleError(err, s, i)
+				return
+			}
 			messageComponent = []discordgo.MessageComponent{
 				discordgo.Button{
 					Label:    "▶️",
 					Style:    discordgo.PrimaryButton,
-					CustomID: utils.MakePageCIDV2(userInfoCommandName, "", 1, idStr, false),
+					CustomID: nextCID,
 				},
 			}
 		}
 
-		for i, ug := range userGames {
+		for i, ug := range listGames {
 			if i == 10 {
 				break
 			}
-			listUserGames = append(listUserGames, formatUserGameLine(i+1, &ug))
+			listUserGames = append(listUserGames, formatUserGameLine(i+1, &ug, details[ug.GameErogsID]))
 		}
 	}
 
@@ -262,6 +332,30 @@ func (g *GetUserinfo) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 		listUserGames = append(listUserGames, "**無資料**")
 	}
 
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name:   "玩過最多(公司品牌)",
+			Value:  strings.Join(listData, "\n"),
+			Inline: false,
+		},
+	}
+	if reviewText := formatReviewSummary(userdata.SummarizeReviews(details)); reviewText != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "評分/遊玩時數",
+			Value:  reviewText,
+			Inline: false,
+		})
+	}
+	listTitle := fmt.Sprintf("遊戲列表（✅ 已玩 %d / ❤️ 收藏 %d / 🚫 黑名單 %d）", completedCount, wishCount, blackListCount)
+	if blackListOnly {
+		listTitle = fmt.Sprintf("黑名單（🚫 %d）", blackListCount)
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{
+		Name:   listTitle,
+		Value:  strings.Join(listUserGames, "\n"),
+		Inline: false,
+	})
+
 	embed := &discordgo.MessageEmbed{
 		Title:       fmt.Sprintf("**%s 的個人資料**", user.Name),
 		Color:       0xB481BB,
@@ -269,18 +363,7 @@ func (g *GetUserinfo) HandleComponent(s *discordgo.Session, i *discordgo.Interac
 		Thumbnail: &discordgo.MessageEmbedThumbnail{
 			URL: avatar,
 		},
-		Fields: []*discordgo.MessageEmbedField{
-			{
-				Name:   "玩過最多(公司品牌)",
-				Value:  strings.Join(listData, "\n"),
-				Inline: false,
-			},
-			{
-				Name:   fmt.Sprintf("遊戲列表（✅ 已玩 %d / ❤️ 收藏 %d）", completedCount, wishCount),
-				Value:  strings.Join(listUserGames, "\n"),
-				Inline: false,
-			},
-		},
+		Fields: fields,
 	}
 
 	actionsRow := utils.MakeActionsRow(messageComponent)
@@ -299,8 +382,11 @@ func getUserGameRecordTime(ug *kurohelperdb.UserGame) string {
 	return ""
 }
 
-func formatUserGameLine(index int, ug *kurohelperdb.UserGame) string {
-	flags := utils.FormatGameFlags(ug.Status, ug.WishListMark)
+func formatUserGameLine(index int, ug *kurohelperdb.UserGame, detail userdata.UserGameDetail) string {
+	flags := utils.FormatGameFlags(ug.Status, ug.WishListMark, ug.BlackListMark)
+	if review := detail.FormatReview(); review != "" {
+		flags = strings.TrimSpace(flags + " " + review)
+	}
 	line := fmt.Sprintf("%d. **%s**", index, ug.GameErogs.Name)
 	if flags != "" {
 		line += " **|** " + flags
@@ -312,3 +398,15 @@ func formatUserGameLine(index int, ug *kurohelperdb.UserGame) string {
 	}
 	return line
 }
+
+// 平均評分與總遊玩時數，沒有任何資料時回傳空字串
+func formatReviewSummary(summary userdata.ReviewSummary) string {
+	lines := make([]string, 0, 2)
+	if summary.RatedCount > 0 {
+		lines = append(lines, fmt.Sprintf("💯 平均評分 **%.1f**（%d 部）", summary.AverageRating, summary.RatedCount))
+	}
+	if summary.TotalHours > 0 {
+		lines = append(lines, fmt.Sprintf("🕒 總遊玩時數 **%s** 小時", userdata.FormatHours(summary.TotalHours)))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,399 @@
+package user
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+
+	"kurohelperservice"
+
+	"kurohelper/internal/cache"
+	"kurohelper/internal/cid"
+	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/executor"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/ratelimit"
+	"kurohelper/internal/tracing"
+	"kurohelper/internal/usertransfer"
+	"kurohelper/internal/utils"
+)
+
+// 等待使用者確認的匯入資料
+type ImportPendingCache struct {
+	DiscordID string
+	Items     []ImportAmbiguous
+}
+
+// CIDV3Store 的值是any，寫入硬碟快照前需要先註冊型別
+func init() {
+	gob.Register(ImportPendingCache{})
+}
+
+// 從上傳的檔案或VNDB清單批次匯入遊玩紀錄，支援的格式見 usertransfer 套件
+type ImportUserGame struct {
+	Providers *provider.Providers
+}
+
+const (
+	importUserGameCommandName = "匯入遊戲資料"
+	// 下拉選單的選項上限
+	importSelectLimit = 25
+	// 每處理幾筆更新一次進度
+	importProgressInterval = 25
+	// 整個匯入的時間上限，interaction token 15分鐘後失效，要留時間寫入資料與回應結果
+	importTimeout = 12 * time.Minute
+)
+
+var attachmentHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+func (im *ImportUserGame) Definition() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "匯入遊戲資料",
+		Description: "從VNDB清單或匯出檔案批次匯入遊玩紀錄",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionAttachment,
+				Name:        "file",
+				Description: "VNDB匯出的XML/JSON、ErogameScape的CSV或本機器人匯出的檔案",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "vndb_user",
+				Description: "VNDB使用者名稱或ID(u+數字)，清單需要公開",
+				Required:    false,
+			},
+		},
+	}
+}
+
+// 匯入會大量查詢資料來源
+func (im *ImportUserGame) RateLimit() ratelimit.Rule {
+	return ratelimit.Rule{Burst: 1, Per: 10 * time.Minute}
+}
+
+// 確認選單只有本人可以操作
+func (im *ImportUserGame) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
+}
+
+func (im *ImportUserGame) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	ctx, cancel := context.WithTimeout(ctx, importTimeout)
+	defer cancel()
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	userID := utils.GetUserID(i)
+	userName := utils.GetUsername(i)
+	if strings.TrimSpace(userID) == "" || strings.TrimSpace(userName) == "" {
+		utils.InteractionEmbedRespondForSelf(s, i, &discordgo.MessageEmbed{Title: "找不到使用者！", Color: 0x7BA23F}, nil, true)
+		return
+	}
+
+	entries, source, err := im.loadEntries(ctx, i)
+	if err != nil {
+		if errors.Is(err, kurohelpererrors.ErrOptionNotFound) {
+			utils.InteractionEmbedRespondForSelf(s, i, utils.MakeErrorEmbedMsg("請上傳檔案或填寫VNDB使用者"), nil, true)
+			return
+		}
+		utils.HandleError(err, s, i)
+		return
+	}
+	if len(entries) == 0 {
+		utils.InteractionEmbedRespondForSelf(s, i, &discordgo.MessageEmbed{Title: "檔案中沒有任何遊戲資料", Description: source, Color: 0x7BA23F}, nil, true)
+		return
+	}
+
+	result := resolveImportEntries(ctx, im.Providers, entries, func(done int) {
+		if done%importProgressInterval != 0 {
+			return
+		}
+		utils.InteractionEmbedRespondForSelf(s, i, &discordgo.MessageEmbed{
+			Title:       "⌛ 匯入中...",
+			Description: fmt.Sprintf("%s\n正在對應遊戲 %d / %d", source, done, len(entries)),
+			Color:       0x7BA23F,
+		}, nil, true)
+	})
+
+	saved, saveErr := saveImportMatches(userID, userName, result.Matched)
+	if saveErr != nil {
+		if saved == 0 {
+			utils.HandleError(saveErr, s, i)
+			return
+		}
+		// 前面的批次已經寫入，照常回報
+		utils.RecordError(saveErr, i)
+		result = partialImportResult(result, saved)
+	}
+
+	embed := importReportEmbed(source, saved, result)
+	if saveErr != nil {
+		markImportPartial(embed, saved)
+	}
+	var actionsRow *discordgo.ActionsRow
+	if len(result.Ambiguous) > 0 {
+		cacheID := uuid.New().String()
+		cache.CIDV3Store.Set(cacheID, ImportPendingCache{DiscordID: userID, Items: result.Ambiguous})
+		cache.CIDV2Store.Set(cacheID, cache.CIDEntry{OwnerID: userID})
+		// 已經寫入的部分照常回報，只是少了選單
+		if actionsRow, err = importAmbiguousSelect(cacheID, result.Ambiguous); err != nil {
+			tracing.Logger(ctx).Warn("匯入待確認選單建立失敗", "error", err)
+		}
+	}
+	utils.InteractionEmbedRespondForSelf(s, i, embed, actionsRow, true)
+	tracing.Logger(ctx).Info("匯入遊戲資料", "使用者ID", userID, "來源", source, "筆數", len(entries), "寫入", saved, "略過", len(result.Skipped), "待確認", len(result.Ambiguous))
+}
+
+// 確認需要選擇的項目，選到的候選直接匯入
+func (im *ImportUserGame) HandleComponentV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, uuid string) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	cacheValue, err := cache.CIDV3Store.Get(uuid)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	pending, ok := cacheValue.(ImportPendingCache)
+	if !ok || pending.DiscordID != utils.GetUserID(i) {
+		utils.HandleError(kurohelpererrors.ErrCIDBehaviorMismatch, s, i)
+		return
+	}
+
+	var matches []importMatch
+	var skipped []importSkip
+	// 同一筆資料只採用第一個選到的候選
+	chosen := make(map[int]bool)
+	for _, value := range i.MessageComponentData().Values {
+		idxText, idText, _ := strings.Cut(value, ":")
+		idx, err1 := strconv.Atoi(idxText)
+		gameID, err2 := strconv.Atoi(idText)
+		if err1 != nil || err2 != nil || idx < 0 || idx >= len(pending.Items) {
+			continue
+		}
+		item := pending.Items[idx]
+		if chosen[idx] {
+			skipped = append(skipped, importSkip{Name: item.Entry.Name(), Reason: "同一筆只能選擇一個候選"})
+			continue
+		}
+		chosen[idx] = true
+		game, err := executor.GetErogsGame(ctx, im.Providers.Erogs, gameID)
+		if err != nil || game == nil {
+			skipped = append(skipped, importSkip{Name: item.Entry.Name(), Reason: "找不到批評空間遊戲"})
+			continue
+		}
+		matches = append(matches, importMatch{Entry: item.Entry, Game: game})
+	}
+
+	result := importResult{Matched: matches, Skipped: skipped}
+	saved, err := saveImportMatches(pending.DiscordID, utils.GetUsername(i), matches)
+	if err != nil {
+		if saved == 0 {
+			utils.HandleError(err, s, i)
+			return
+		}
+		// 保留選單讓使用者可以重新送出，已經寫入的資料再寫一次會覆蓋成相同的內容
+		utils.RecordError(err, i)
+		embed := importReportEmbed("待確認項目", saved, partialImportResult(result, saved))
+		markImportPartial(embed, saved)
+		utils.InteractionEmbedRespondForSelf(s, i, embed, nil, true)
+		return
+	}
+	// 已經匯入，避免重複送出同一個選單
+	cache.CIDV3Store.Delete(uuid)
+	cache.CIDV2Store.Delete(uuid)
+
+	embed := importReportEmbed("待確認項目", saved, result)
+	utils.InteractionEmbedRespondForSelf(s, i, embed, nil, true)
+	tracing.Logger(ctx).Info("匯入遊戲資料確認", "使用者ID", pending.DiscordID, "寫入", saved)
+}
+
+// 讀取選項指定的來源，回傳解析後的資料與來源說明；兩個選項都沒填時回傳 ErrOptionNotFound
+func (im *ImportUserGame) loadEntries(ctx context.Context, i *discordgo.InteractionCreate) ([]usertransfer.Entry, string, error) {
+	attachmentID, err := utils.GetOptions(i, "file")
+	if err != nil && !errors.Is(err, kurohelpererrors.ErrOptionNotFound) {
+		return nil, "", err
+	}
+	if attachmentID != "" {
+		resolved := i.ApplicationCommandData().Resolved
+		if resolved == nil || resolved.Attachments[attachmentID] == nil {
+			return nil, "", kurohelpererrors.ErrOptionNotFound
+		}
+		attachment := resolved.Attachments[attachmentID]
+		if attachment.Size > usertransfer.MaxFileSize {
+			return nil, "", kurohelpererrors.ErrImportFileTooLarge
+		}
+		data, err := downloadAttachment(ctx, attachment.URL)
+		if err != nil {
+			return nil, "", err
+		}
+		entries, err := usertransfer.Parse(attachment.Filename, data)
+		return entries, "📄 " + attachment.Filename, err
+	}
+
+	vndbUser, err := utils.GetOptions(i, "vndb_user")
+	if err != nil {
+		return nil, "", err
+	}
+	items, err := im.Providers.Vndb.GetUserList(ctx, vndbUser)
+	if err != nil {
+		return nil, "", err
+	}
+	entries := usertransfer.FromVndbUList(items)
+	if len(entries) == 0 {
+		return nil, "", kurohelperservice.ErrSearchNoContent
+	}
+	if len(entries) > usertransfer.MaxEntries {
+		entries = entries[:usertransfer.MaxEntries]
+	}
+	return entries, "📚 VNDB " + vndbUser, nil
+}
+
+// 下載附件，超過 usertransfer.MaxFileSize 時回傳 ErrImportFileTooLarge
+func downloadAttachment(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := attachmentHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download attachment: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, usertransfer.MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > usertransfer.MaxFileSize {
+		return nil, kurohelpererrors.ErrImportFileTooLarge
+	}
+	return data, nil
+}
+
+func importReportEmbed(source string, saved int, result importResult) *discordgo.MessageEmbed {
+	matched := make([]string, 0, len(result.Matched))
+	for _, m := range result.Matched {
+		matched = append(matched, fmt.Sprintf("%s → %s", m.Entry.Name(), m.Game.Gamename))
+	}
+	skipped := make([]string, 0, len(result.Skipped))
+	for _, sk := range result.Skipped {
+		skipped = append(skipped, fmt.Sprintf("%s（%s）", sk.Name, sk.Reason))
+	}
+	ambiguous := make([]string, 0, len(result.Ambiguous))
+	for _, a := range result.Ambiguous {
+		names := make([]string, 0, len(a.Candidates))
+		for _, c := range a.Candidates {
+			names = append(names, c.Name)
+		}
+		ambiguous = append(ambiguous, fmt.Sprintf("%s → %s", a.Entry.Name(), strings.Join(names, " / ")))
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: fmt.Sprintf("✅ 已匯入（%d）", saved), Value: joinFieldLines(matched), Inline: false},
+		{Name: fmt.Sprintf("⏭️ 略過（%d）", len(result.Skipped)), Value: joinFieldLines(skipped), Inline: false},
+	}
+	if len(result.Ambiguous) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   fmt.Sprintf("❓ 待確認（%d）", len(result.Ambiguous)),
+			Value:  joinFieldLines(ambiguous),
+			Inline: false,
+		})
+	}
+
+	description := source
+	if len(result.Ambiguous) > importSelectLimit {
+		description += fmt.Sprintf("\n待確認項目只能選擇前%d筆，其他請用「加已玩」個別加入", importSelectLimit)
+	}
+	return &discordgo.MessageEmbed{
+		Title:       "匯入完成！",
+		Description: description,
+		Color:       0x7BA23F,
+		Fields:      fields,
+	}
+}
+
+// 寫入到一半失敗時的報告，註明只有前面的資料已經匯入
+func markImportPartial(embed *discordgo.MessageEmbed, saved int) {
+	embed.Title = "匯入未完成！"
+	embed.Color = 0xcc543a
+	embed.Description += fmt.Sprintf("\n寫入資料時發生錯誤，只有前%d筆已經匯入，其他資料請稍後重新匯入", saved)
+}
+
+// 待確認項目的下拉選單，值為 "項目索引:遊戲ID"
+func importAmbiguousSelect(cacheID string, items []ImportAmbiguous) (*discordgo.ActionsRow, error) {
+	options := make([]discordgo.SelectMenuOption, 0, importSelectLimit)
+	for idx, item := range items {
+		for _, c := range item.Candidates {
+			if len(options) >= importSelectLimit {
+				break
+			}
+			options = append(options, discordgo.SelectMenuOption{
+				Label:       truncateRunes(c.Name, 100),
+				Description: truncateRunes(item.Entry.Name(), 100),
+				Value:       fmt.Sprintf("%d:%d", idx, c.ID),
+			})
+		}
+	}
+
+	customID, err := cid.MakeCIDV3(importUserGameCommandName, cacheID)
+	if err != nil {
+		return nil, err
+	}
+
+	minValues := 1
+	return utils.MakeActionsRow([]discordgo.MessageComponent{
+		discordgo.SelectMenu{
+			CustomID:    customID,
+			Placeholder: "選擇要匯入的遊戲(可複選)",
+			MinValues:   &minValues,
+			MaxValues:   len(options),
+			Options:     options,
+		},
+	}), nil
+}
+
+// 超過embed欄位長度時截斷並標示剩餘筆數
+func joinFieldLines(lines []string) string {
+	if len(lines) == 0 {
+		return "無"
+	}
+	const limit = 1000
+	var sb strings.Builder
+	for idx, line := range lines {
+		more := fmt.Sprintf("…還有%d筆", len(lines)-idx)
+		if sb.Len()+len(line)+1 > limit-len(more) {
+			sb.WriteString(more)
+			break
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
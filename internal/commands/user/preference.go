@@ -1,6 +1,8 @@
 package user
 
 import (
+	"context"
+	"encoding/gob"
 	"fmt"
 
 	"github.com/bwmarrin/discordgo"
@@ -17,6 +19,11 @@ type PreferenceCache struct {
 	DiscordID       string
 }
 
+// CIDV3Store 的值是any，寫入硬碟快照前需要先註冊型別
+func init() {
+	gob.Register(PreferenceCache{})
+}
+
 type Preference struct{}
 
 const preferenceCommandName = "帳號設定"
@@ -28,7 +35,12 @@ func (p *Preference) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
-func (p *Preference) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+// 設定按鈕只有本人可以按
+func (p *Preference) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
+}
+
+func (p *Preference) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
 	userID := utils.GetUserID(i)
 	user, err := kurohelperdb.GetUserByDiscordID(kurohelperdb.Dbs, userID)
 	if err != nil {
@@ -41,6 +53,7 @@ func (p *Preference) Handler(s *discordgo.Session, i *discordgo.InteractionCreat
 		PrivateGameData: user.PrivateGameData,
 		DiscordID:       userID,
 	})
+	cache.CIDV2Store.Set(cacheID, cache.CIDEntry{OwnerID: userID})
 
 	privateGameDataLabel := "隱私遊戲資料"
 	privateGameDataButtonLabel := "已關閉（公開個人建檔資料）"
@@ -55,6 +68,11 @@ func (p *Preference) Handler(s *discordgo.Session, i *discordgo.InteractionCreat
 			discordgo.TextDisplay{Content: fmt.Sprintf("**帳號名稱**\n%s", user.Name)},
 		},
 	}
+	privateGameDataCID, err := cid.MakeCIDV3(preferenceCommandName, cacheID)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
 	privateGameDataSection := discordgo.Section{
 		Components: []discordgo.MessageComponent{
 			discordgo.TextDisplay{Content: fmt.Sprintf("**%s**", privateGameDataLabel)},
@@ -62,7 +80,7 @@ func (p *Preference) Handler(s *discordgo.Session, i *discordgo.InteractionCreat
 		Accessory: discordgo.Button{
 			Label:    privateGameDataButtonLabel,
 			Style:    privateGameDataButtonStyle,
-			CustomID: cid.MakeCIDV3(preferenceCommandName, cacheID),
+			CustomID: privateGameDataCID,
 		},
 	}
 
@@ -104,7 +122,7 @@ func (p *Preference) Handler(s *discordgo.Session, i *discordgo.InteractionCreat
 	}
 }
 
-func (p *Preference) HandleComponentV2(s *discordgo.Session, i *discordgo.InteractionCreate, uuid string) {
+func (p *Preference) HandleComponentV2(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, uuid string) {
 	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredMessageUpdate,
 	}); err != nil {
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -25,7 +26,7 @@ func (r *Register) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
-func (r *Register) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (r *Register) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -0,0 +1,108 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+
+	kurohelperdb "kurohelperservice/db"
+
+	"kurohelper/internal/tracing"
+	"kurohelper/internal/userdata"
+	"kurohelper/internal/utils"
+)
+
+// 移除黑名單，只是取消標記所以不需要確認
+type RemoveBlackList struct{}
+
+func (r *RemoveBlackList) Definition() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "移除黑名單",
+		Description: "把遊戲從黑名單移除",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "keyword",
+				Description:  "關鍵字",
+				Autocomplete: true,
+				Required:     true,
+			},
+		},
+	}
+}
+
+func (r *RemoveBlackList) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	userID := utils.GetUserID(i)
+
+	keyword, err := utils.GetOptions(i, "keyword")
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, userID)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	target, err := findUserGameByKeyword(filterBlackListGames(userGames), keyword)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	if err := userdata.UpdateUserGameBlackListMark(kurohelperdb.Dbs, target.UserID, target.GameErogsID, false); err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("%s 黑名單移除成功！", target.GameErogs.Name),
+		Color: 0x656765,
+	}
+	utils.InteractionEmbedRespondForSelf(s, i, embed, nil, true)
+	tracing.Logger(ctx).Info("移除黑名單成功", "使用者ID", userID, "遊戲ID", target.GameErogsID, "遊戲名稱", target.GameErogs.Name)
+}
+
+// 從使用者的黑名單中建議
+func (r *RemoveBlackList) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	var query string
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "keyword" {
+			query = opt.StringValue()
+		}
+	}
+
+	userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, utils.GetUserID(i))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		tracing.Logger(ctx).Warn(err.Error())
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: userGameChoices(filterBlackListGames(userGames), query),
+		},
+	})
+}
+
+func filterBlackListGames(userGames []kurohelperdb.UserGame) []kurohelperdb.UserGame {
+	out := make([]kurohelperdb.UserGame, 0, len(userGames))
+	for _, g := range userGames {
+		if g.BlackListMark {
+			out = append(out, g)
+		}
+	}
+	return out
+}
@@ -1,12 +1,21 @@
 package user
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kurohelper/internal/autocomplete"
 	"kurohelper/internal/cache"
 	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/tracing"
+	"kurohelper/internal/userdata"
 	"kurohelper/internal/utils"
-	"log/slog"
-	"strings"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/uuid"
@@ -27,18 +36,24 @@ const removeUserGameCommandName = "刪除使用者遊戲資料"
 const (
 	removeModeHasPlayed = 1
 	removeModeInWish    = 2
+	// 遊玩中、擱置、棄坑等任何狀態
+	removeModeStatus = 3
 )
 
+// Discord Autocomplete 最多25個選項
+const userGameAutocompleteLimit = 25
+
 func (r *RemoveUserGame) Definition() *discordgo.ApplicationCommand {
 	return &discordgo.ApplicationCommand{
 		Name:        "刪除使用者遊戲資料",
 		Description: "刪除個人建檔的遊戲資料",
 		Options: []*discordgo.ApplicationCommandOption{
 			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "keyword",
-				Description: "關鍵字",
-				Required:    true,
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "keyword",
+				Description:  "關鍵字",
+				Autocomplete: true,
+				Required:     true,
 			},
 			{
 				Type:        discordgo.ApplicationCommandOptionString,
@@ -48,13 +63,19 @@ func (r *RemoveUserGame) Definition() *discordgo.ApplicationCommand {
 				Choices: []*discordgo.ApplicationCommandOptionChoice{
 					{Name: "刪除已玩", Value: "played"},
 					{Name: "刪除最愛", Value: "inWish"},
+					{Name: "刪除遊玩狀態", Value: "status"},
 				},
 			},
 		},
 	}
 }
 
-func (r *RemoveUserGame) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+// 確認按鈕只有本人可以按
+func (r *RemoveUserGame) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
+}
+
+func (r *RemoveUserGame) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -75,13 +96,8 @@ func (r *RemoveUserGame) Handler(s *discordgo.Session, i *discordgo.InteractionC
 		return
 	}
 
-	removeMode := 0
-	switch modeText {
-	case "played":
-		removeMode = removeModeHasPlayed
-	case "inWish":
-		removeMode = removeModeInWish
-	default:
+	removeMode := parseRemoveMode(modeText)
+	if removeMode == 0 {
 		utils.HandleError(gorm.ErrRecordNotFound, s, i)
 		return
 	}
@@ -92,23 +108,9 @@ func (r *RemoveUserGame) Handler(s *discordgo.Session, i *discordgo.InteractionC
 		return
 	}
 
-	var target *kurohelperdb.UserGame
-	for idx := range userGames {
-		name := userGames[idx].GameErogs.Name
-		if !strings.Contains(strings.ToLower(name), strings.ToLower(opt)) {
-			continue
-		}
-		if removeMode == removeModeHasPlayed && userGames[idx].Status != 1 {
-			continue
-		}
-		if removeMode == removeModeInWish && !userGames[idx].WishListMark {
-			continue
-		}
-		target = &userGames[idx]
-		break
-	}
-	if target == nil {
-		utils.HandleError(gorm.ErrRecordNotFound, s, i)
+	target, err := findUserGameByKeyword(filterRemovableGames(userGames, removeMode), opt)
+	if err != nil {
+		utils.HandleError(err, s, i)
 		return
 	}
 
@@ -117,12 +119,19 @@ func (r *RemoveUserGame) Handler(s *discordgo.Session, i *discordgo.InteractionC
 		OwnerDiscordID: userID,
 		Game:           *target,
 	})
+	cache.CIDV2Store.Set(cacheID, cache.CIDEntry{OwnerID: userID})
+
+	confirmCID, err := utils.MakeUserDataOperationCIDV2(removeUserGameCommandName, cacheID, removeMode)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
 
 	messageComponent := []discordgo.MessageComponent{
 		discordgo.Button{
 			Label:    "✅",
 			Style:    discordgo.PrimaryButton,
-			CustomID: utils.MakeUserDataOperationCIDV2(removeUserGameCommandName, cacheID, removeMode),
+			CustomID: confirmCID,
 		},
 	}
 	actionsRow := utils.MakeActionsRow(messageComponent)
@@ -141,7 +150,148 @@ func (r *RemoveUserGame) Handler(s *discordgo.Session, i *discordgo.InteractionC
 	utils.InteractionEmbedRespondForSelf(s, i, embed, actionsRow, true)
 }
 
-func (r *RemoveUserGame) HandleComponent(s *discordgo.Session, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+// 從使用者自己的遊戲資料中建議，有選擇 remove_mode 時只列出符合的資料
+//
+// 選項的值為 "e" + 遊戲ID，避免名稱相似時刪錯遊戲
+func (r *RemoveUserGame) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	var query, modeText string
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "keyword":
+			query = opt.StringValue()
+		case "remove_mode":
+			modeText = opt.StringValue()
+		}
+	}
+
+	userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, utils.GetUserID(i))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		tracing.Logger(ctx).Warn(err.Error())
+		return
+	}
+	userGames = filterRemovableGames(userGames, parseRemoveMode(modeText))
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: userGameChoices(userGames, query),
+		},
+	})
+}
+
+// 從使用者自己的遊戲資料產生自動完成選項，選項的值為 "e" + 遊戲ID
+//
+// 還沒輸入關鍵字時列出最近更新的資料
+func userGameChoices(userGames []kurohelperdb.UserGame, query string) []*discordgo.ApplicationCommandOptionChoice {
+	var suggested []kurohelperdb.UserGame
+	if len([]rune(autocomplete.Normalize(query))) < autocomplete.MinQueryLength {
+		sort.Slice(userGames, func(a, b int) bool {
+			return userGames[a].UpdatedAt.After(userGames[b].UpdatedAt)
+		})
+		suggested = userGames[:min(len(userGames), userGameAutocompleteLimit)]
+	} else {
+		names := make([]string, 0, len(userGames))
+		byName := make(map[string]kurohelperdb.UserGame, len(userGames))
+		for _, g := range userGames {
+			names = append(names, g.GameErogs.Name)
+			if _, ok := byName[g.GameErogs.Name]; !ok {
+				byName[g.GameErogs.Name] = g
+			}
+		}
+		for _, name := range autocomplete.NewIndex(names).Search(query, userGameAutocompleteLimit) {
+			suggested = append(suggested, byName[name])
+		}
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(suggested))
+	for _, g := range suggested {
+		name := []rune(g.GameErogs.Name)
+		if len(name) > 100 {
+			name = name[:100]
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  string(name),
+			Value: "e" + strconv.Itoa(g.GameErogsID),
+		})
+	}
+	return choices
+}
+
+func parseRemoveMode(modeText string) int {
+	switch modeText {
+	case "played":
+		return removeModeHasPlayed
+	case "inWish":
+		return removeModeInWish
+	case "status":
+		return removeModeStatus
+	default:
+		return 0
+	}
+}
+
+// 依照移除類型過濾可以移除的資料，removeMode 為0時有狀態或最愛的都列出
+func filterRemovableGames(userGames []kurohelperdb.UserGame, removeMode int) []kurohelperdb.UserGame {
+	out := make([]kurohelperdb.UserGame, 0, len(userGames))
+	for _, g := range userGames {
+		hasStatus := g.Status != kurohelperdb.UserGameStatusNone
+		switch removeMode {
+		case removeModeHasPlayed:
+			if g.Status != kurohelperdb.UserGameStatusFinished {
+				continue
+			}
+		case removeModeInWish:
+			if !g.WishListMark {
+				continue
+			}
+		case removeModeStatus:
+			if !hasStatus {
+				continue
+			}
+		default:
+			if !hasStatus && !g.WishListMark {
+				continue
+			}
+		}
+		out = append(out, g)
+	}
+	return out
+}
+
+// 從使用者的遊戲資料找出關鍵字指定的遊戲
+//
+// 依序比對 自動完成的遊戲ID(e+數字) > 完全相同的名稱 > 包含關鍵字，包含關鍵字的資料不只一筆時回傳 ErrAmbiguousKeyword
+func findUserGameByKeyword(userGames []kurohelperdb.UserGame, keyword string) (*kurohelperdb.UserGame, error) {
+	if idSearch, _ := regexp.MatchString(`^e\d+$`, keyword); idSearch {
+		id, _ := strconv.Atoi(keyword[1:])
+		for idx := range userGames {
+			if userGames[idx].GameErogsID == id {
+				return &userGames[idx], nil
+			}
+		}
+	}
+
+	var matches []*kurohelperdb.UserGame
+	for idx := range userGames {
+		name := userGames[idx].GameErogs.Name
+		if strings.EqualFold(name, keyword) {
+			return &userGames[idx], nil
+		}
+		if strings.Contains(strings.ToLower(name), strings.ToLower(keyword)) {
+			matches = append(matches, &userGames[idx])
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, gorm.ErrRecordNotFound
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, kurohelpererrors.ErrAmbiguousKeyword
+	}
+}
+
+func (r *RemoveUserGame) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -181,8 +331,15 @@ func (r *RemoveUserGame) HandleComponent(s *discordgo.Session, i *discordgo.Inte
 	}
 
 	switch userDataCID.Value {
-	case removeModeHasPlayed:
-		if err := kurohelperdb.UpdateUserGameStatus(kurohelperdb.Dbs, cacheData.Game.UserID, cacheData.Game.GameErogsID, 0); err != nil {
+	case removeModeHasPlayed, removeModeStatus:
+		game := cacheData.Game
+		err := kurohelperdb.Dbs.Transaction(func(tx *gorm.DB) error {
+			if err := kurohelperdb.UpdateUserGameStatus(tx, game.UserID, game.GameErogsID, kurohelperdb.UserGameStatusNone); err != nil {
+				return err
+			}
+			return userdata.RecordStatusChange(tx, game.UserID, game.GameErogsID, game.Status, kurohelperdb.UserGameStatusNone, time.Now())
+		})
+		if err != nil {
 			utils.HandleError(err, s, i)
 			return
 		}
@@ -197,11 +354,13 @@ func (r *RemoveUserGame) HandleComponent(s *discordgo.Session, i *discordgo.Inte
 	}
 
 	successText := ""
-	if userDataCID.Value == removeModeHasPlayed {
+	switch userDataCID.Value {
+	case removeModeHasPlayed:
 		successText = "已玩移除成功！"
-	}
-	if userDataCID.Value == removeModeInWish {
+	case removeModeInWish:
 		successText = "最愛移除成功！"
+	case removeModeStatus:
+		successText = fmt.Sprintf("遊玩狀態(%s)移除成功！", userdata.StatusName[cacheData.Game.Status])
 	}
 
 	embed := &discordgo.MessageEmbed{
@@ -209,5 +368,5 @@ func (r *RemoveUserGame) HandleComponent(s *discordgo.Session, i *discordgo.Inte
 		Color: 0x7BA23F,
 	}
 	utils.InteractionEmbedRespondForSelf(s, i, embed, nil, true)
-	slog.Info("刪除使用者遊戲資料成功", "使用者ID", userID, "遊戲ID", cacheData.Game.GameErogsID, "遊戲名稱", cacheData.Game.GameErogs.Name, "移除模式", userDataCID.Value)
+	tracing.Logger(ctx).Info("刪除使用者遊戲資料成功", "使用者ID", userID, "遊戲ID", cacheData.Game.GameErogsID, "遊戲名稱", cacheData.Game.GameErogs.Name, "移除模式", userDataCID.Value)
 }
@@ -0,0 +1,487 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+
+	"kurohelperservice"
+	kurohelperdb "kurohelperservice/db"
+	"kurohelperservice/provider/erogs"
+
+	"kurohelper/internal/autocomplete"
+	"kurohelper/internal/cache"
+	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/executor"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/store"
+	"kurohelper/internal/tracing"
+	"kurohelper/internal/userdata"
+	"kurohelper/internal/utils"
+)
+
+type SetPlayStatus struct {
+	Providers *provider.Providers
+}
+
+type setPlayStatusCacheData struct {
+	Game          erogs.Game
+	StartDateText *string
+	EndDateText   *string
+}
+
+const setPlayStatusCommandName = "設定遊玩狀態"
+
+// 確認畫面顯示的狀態紀錄筆數
+const playStatusHistoryLimit = 5
+
+func (sp *SetPlayStatus) Definition() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "設定遊玩狀態",
+		Description: "設定遊戲的遊玩狀態(ErogameScape)",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "keyword",
+				Description:  "關鍵字",
+				Autocomplete: true,
+				Required:     true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "status",
+				Description: "遊玩狀態",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "遊玩中", Value: "playing"},
+					{Name: "已完成", Value: "finished"},
+					{Name: "擱置", Value: "stalled"},
+					{Name: "棄坑", Value: "dropped"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "start_date",
+				Description: "開始遊玩日期(YYYYMMDD)",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "end_date",
+				Description: "結束日期(YYYYMMDD，已完成或棄坑)",
+				Required:    false,
+			},
+		},
+	}
+}
+
+// 確認按鈕只有本人可以按
+func (sp *SetPlayStatus) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
+}
+
+func (sp *SetPlayStatus) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	sp.HandleComponent(ctx, s, i, nil)
+}
+
+func (sp *SetPlayStatus) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	if cid != nil {
+		sp.confirm(ctx, s, i, cid)
+		return
+	}
+
+	keyword, err := utils.GetOptions(i, "keyword")
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	autocomplete.Games.Hit(keyword)
+
+	statusText, err := utils.GetOptions(i, "status")
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	status, ok := parsePlayStatus(statusText)
+	if !ok {
+		utils.HandleError(kurohelpererrors.ErrOptionTranslateFail, s, i)
+		return
+	}
+
+	startDate, err := getDateOption(i, "start_date")
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	endDate, err := getDateOption(i, "end_date")
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	if startDate != nil && endDate != nil && endDate.Before(*startDate) {
+		utils.HandleError(kurohelpererrors.ErrEndDateBeforeStart, s, i)
+		return
+	}
+
+	var res *erogs.Game
+	idSearch, _ := regexp.MatchString(`^e\d+$`, keyword)
+	if idSearch {
+		num, _ := strconv.Atoi(keyword[1:])
+		res, err = sp.Providers.Erogs.SearchGameByID(ctx, num)
+	} else {
+		res, err = sp.Providers.Erogs.SearchGameByKeyword(ctx, []string{keyword, kurohelperservice.ZhTwToJp(keyword)})
+	}
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	if res == nil {
+		utils.HandleError(kurohelperservice.ErrSearchNoContent, s, i)
+		return
+	}
+
+	// 目前的狀態與紀錄，使用者還沒建檔時視為無狀態
+	userID := utils.GetUserID(i)
+	currentStatus := kurohelperdb.UserGameStatusNone
+	var history []userdata.UserGameStatusHistory
+	if _, ok := store.UserStore[userID]; ok {
+		userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, userID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.HandleError(err, s, i)
+			return
+		}
+		if ug := findUserGame(userGames, res.ID); ug != nil {
+			currentStatus = ug.Status
+			history, err = userdata.GetStatusHistory(kurohelperdb.Dbs, ug.UserID, ug.GameErogsID, playStatusHistoryLimit)
+			if err != nil {
+				utils.HandleError(err, s, i)
+				return
+			}
+		}
+	}
+
+	idStr := uuid.New().String()
+	cacheData := setPlayStatusCacheData{Game: *res}
+	if startDate != nil {
+		text := startDate.Format("20060102")
+		cacheData.StartDateText = &text
+	}
+	if endDate != nil {
+		text := endDate.Format("20060102")
+		cacheData.EndDateText = &text
+	}
+	cache.UserInfoCache.Set(idStr, cacheData)
+	cache.CIDV2Store.Set(idStr, cache.CIDEntry{OwnerID: userID})
+
+	confirmCID, err := utils.MakeUserDataOperationCIDV2(setPlayStatusCommandName, idStr, int(status))
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	messageComponent := []discordgo.MessageComponent{
+		discordgo.Button{
+			Label:    "✅",
+			Style:    discordgo.PrimaryButton,
+			CustomID: confirmCID,
+		},
+	}
+	actionsRow := utils.MakeActionsRow(messageComponent)
+
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name:   "發行機種",
+			Value:  res.Model,
+			Inline: false,
+		},
+		{
+			Name:   "遊玩狀態",
+			Value:  fmt.Sprintf("%s → %s", userdata.StatusName[currentStatus], userdata.StatusName[status]),
+			Inline: false,
+		},
+	}
+	if dates := formatDateRange(startDate, endDate); dates != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "日期",
+			Value:  dates,
+			Inline: false,
+		})
+	}
+	if len(history) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "狀態紀錄",
+			Value:  formatStatusHistory(history),
+			Inline: false,
+		})
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{
+		Name:   "確認",
+		Value:  "你確定要設定遊玩狀態嗎?",
+		Inline: false,
+	})
+
+	embed := &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: res.BrandName,
+		},
+		Title:  fmt.Sprintf("**%s(%s)**", res.Gamename, res.SellDay),
+		URL:    res.Shoukai,
+		Color:  0x7BA23F,
+		Fields: fields,
+		Image:  utils.GenerateImage(i, res.BannerUrl),
+	}
+	utils.InteractionEmbedRespondForSelf(s, i, embed, actionsRow, true)
+}
+
+// 按下確認按鈕後寫入狀態、日期與狀態紀錄
+func (sp *SetPlayStatus) confirm(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+	if cid.GetBehaviorID() != utils.UserDataOperationBehavior {
+		utils.HandleError(kurohelpererrors.ErrCIDBehaviorMismatch, s, i)
+		return
+	}
+	userDataCID, err := cid.ToUserDataOperationCIDV2()
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	status := kurohelperdb.UserGameStatus(userDataCID.Value)
+	if _, ok := userdata.StatusName[status]; !ok || status == kurohelperdb.UserGameStatusNone {
+		utils.HandleError(kurohelpererrors.ErrCIDGetParameterFailed, s, i)
+		return
+	}
+
+	cacheValue, err := cache.UserInfoCache.Get(userDataCID.CacheID)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	cacheData := cacheValue.(setPlayStatusCacheData)
+	res := &cacheData.Game
+
+	startDate, err := parseCachedDate(cacheData.StartDateText)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	endDate, err := parseCachedDate(cacheData.EndDateText)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	userID := utils.GetUserID(i)
+	userName := utils.GetUsername(i)
+	if strings.TrimSpace(userID) == "" || strings.TrimSpace(userName) == "" { // 找不到使用者，此狀況應該會是Discord官方問題或是程式碼邏輯問題
+		embed := &discordgo.MessageEmbed{
+			Title: "找不到使用者！",
+			Color: 0x7BA23F,
+		}
+		utils.InteractionEmbedRespondForSelf(s, i, embed, nil, true)
+		return
+	}
+
+	var fromStatus kurohelperdb.UserGameStatus
+	var detail userdata.UserGameDetail
+	err = kurohelperdb.Dbs.Transaction(func(tx *gorm.DB) error {
+		// 1. 確保 User 存在
+		if err := kurohelperdb.EnsureDiscordUser(tx, userID, userName); err != nil {
+			return err
+		}
+		user, err := kurohelperdb.GetUserByDiscordID(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		// 2. 確保 Brand 存在
+		// 新增欄位資料先用預設值
+		if _, err := kurohelperdb.EnsureBrandErogs(tx, res.BrandID, res.BrandName, false, 0); err != nil {
+			return err
+		}
+
+		// 3. 確保 Game 存在
+		image := erogs.MakeDMMImageURL(res.DMM)
+		if strings.TrimSpace(res.DMM) == "" {
+			image = ""
+		}
+		if _, err := kurohelperdb.EnsureGameErogs(tx, res.ID, res.Gamename, image, res.BrandID, res.Model); err != nil {
+			return err
+		}
+
+		// 4. 先確保有資料，取得原本的狀態
+		if err := kurohelperdb.EnsureUserGame(tx, user.ID, res.ID); err != nil {
+			return err
+		}
+		userGames, err := kurohelperdb.GetUserGameByDiscordID(tx, userID)
+		if err != nil {
+			return err
+		}
+		if ug := findUserGame(userGames, res.ID); ug != nil {
+			fromStatus = ug.Status
+		}
+
+		// 5. 更新開始/結束日期
+		detail, err = userdata.GetUserGameDetail(tx, user.ID, res.ID)
+		if err != nil {
+			return err
+		}
+		date, err := detail.ApplyStatus(fromStatus, status, startDate, endDate, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := userdata.SaveUserGameDetail(tx, &detail); err != nil {
+			return err
+		}
+
+		// 6. 更新狀態，已完成時一併寫入完成日期
+		if status == kurohelperdb.UserGameStatusFinished {
+			err = kurohelperdb.UpdateUserGameFinished(tx, user.ID, res.ID, detail.EndedDate)
+		} else {
+			err = kurohelperdb.UpdateUserGameStatus(tx, user.ID, res.ID, status)
+		}
+		if err != nil {
+			return err
+		}
+
+		// 7. 記錄狀態變更
+		return userdata.RecordStatusChange(tx, user.ID, res.ID, fromStatus, status, date)
+	})
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	// 確保新建立的使用者有加入快取
+	if _, ok := store.UserStore[userID]; !ok {
+		store.UserStore[userID] = struct{}{}
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name:   "遊玩狀態",
+			Value:  fmt.Sprintf("%s → %s", userdata.StatusName[fromStatus], userdata.StatusName[status]),
+			Inline: false,
+		},
+	}
+	if dates := formatDateRange(detail.StartedDate, detail.EndedDate); dates != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "日期",
+			Value:  dates,
+			Inline: false,
+		})
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("%s 設定成功！", res.Gamename),
+		Color:  0x7BA23F,
+		Fields: fields,
+	}
+	utils.InteractionEmbedRespondForSelf(s, i, embed, nil, true)
+	tracing.Logger(ctx).Info("設定遊玩狀態成功", "使用者ID", userID, "遊戲ID", res.ID, "遊戲名稱", res.Gamename, "原狀態", fromStatus, "新狀態", status)
+}
+
+func (sp *SetPlayStatus) Autocomplete(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	choices, err := executor.GetAutocomplete(s, i, autocomplete.Games)
+	if err != nil {
+		tracing.Logger(ctx).Warn(err.Error())
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
+
+func parsePlayStatus(statusText string) (kurohelperdb.UserGameStatus, bool) {
+	switch statusText {
+	case "playing":
+		return kurohelperdb.UserGameStatusPlaying, true
+	case "finished":
+		return kurohelperdb.UserGameStatusFinished, true
+	case "stalled":
+		return kurohelperdb.UserGameStatusStalled, true
+	case "dropped":
+		return kurohelperdb.UserGameStatusDropped, true
+	default:
+		return kurohelperdb.UserGameStatusNone, false
+	}
+}
+
+// 讀取選填的日期選項(YYYYMMDD)，沒有填寫時回傳nil
+func getDateOption(i *discordgo.InteractionCreate, name string) (*time.Time, error) {
+	text, err := utils.GetOptions(i, name)
+	if err != nil {
+		if errors.Is(err, kurohelpererrors.ErrOptionNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if text == "" {
+		return nil, nil
+	}
+	t, err := utils.ParseYYYYMMDD(text)
+	if err != nil {
+		return nil, err
+	}
+	if t.After(time.Now().AddDate(0, 0, 1)) {
+		return nil, kurohelpererrors.ErrDateExceedsTomorrow
+	}
+	return &t, nil
+}
+
+func parseCachedDate(text *string) (*time.Time, error) {
+	if text == nil {
+		return nil, nil
+	}
+	t, err := utils.ParseYYYYMMDD(*text)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func findUserGame(userGames []kurohelperdb.UserGame, gameErogsID int) *kurohelperdb.UserGame {
+	for idx := range userGames {
+		if userGames[idx].GameErogsID == gameErogsID {
+			return &userGames[idx]
+		}
+	}
+	return nil
+}
+
+func formatDateRange(start, end *time.Time) string {
+	var parts []string
+	if start != nil {
+		parts = append(parts, "開始: "+start.Format("2006-01-02"))
+	}
+	if end != nil {
+		parts = append(parts, "結束: "+end.Format("2006-01-02"))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// 狀態紀錄(新到舊)，一行一筆
+func formatStatusHistory(history []userdata.UserGameStatusHistory) string {
+	lines := make([]string, 0, len(history))
+	for _, h := range history {
+		lines = append(lines, fmt.Sprintf("%s %s → %s", h.Date.Format("2006-01-02"), userdata.StatusName[h.FromStatus], userdata.StatusName[h.ToStatus]))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,364 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"kurohelperservice"
+	kurohelperdb "kurohelperservice/db"
+	"kurohelperservice/provider/erogs"
+
+	"kurohelper/internal/executor"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/store"
+	"kurohelper/internal/userdata"
+	"kurohelper/internal/usertransfer"
+)
+
+const (
+	// 每個交易寫入的筆數
+	importBatchSize = 50
+	// 用標題搜尋時最多檢查幾個候選
+	importCandidateLimit = 3
+	// 單次匯入最多查詢上游的次數(快取命中也計入)
+	importLookupLimit = 600
+)
+
+// 查詢次數用完時回傳，剩下的資料全部略過
+var errImportLookupLimit = errors.New("import: lookup limit reached")
+
+// 單次匯入剩下的查詢次數
+type importBudget struct {
+	left int
+}
+
+// 消耗一次查詢，次數用完時回傳 errImportLookupLimit
+func (b *importBudget) take() error {
+	if b.left <= 0 {
+		return errImportLookupLimit
+	}
+	b.left--
+	return nil
+}
+
+// 對應到批評空間遊戲的匯入資料
+type importMatch struct {
+	Entry usertransfer.Entry
+	Game  *erogs.Game
+}
+
+// 略過的匯入資料
+type importSkip struct {
+	Name   string
+	Reason string
+}
+
+// 找到候選但無法確定是哪一個的匯入資料，等使用者確認
+type ImportAmbiguous struct {
+	Entry      usertransfer.Entry
+	Candidates []ImportCandidate
+}
+
+type ImportCandidate struct {
+	ID   int
+	Name string
+}
+
+type importResult struct {
+	Matched   []importMatch
+	Skipped   []importSkip
+	Ambiguous []ImportAmbiguous
+}
+
+// 把匯入資料對應到批評空間遊戲
+//
+// 有批評空間ID的直接查詢；只有VNDB ID的先找快取的對應，再用標題搜尋，
+// 候選遊戲記錄的VNDB ID一致時視為同一款，候選沒有記錄VNDB ID時交給使用者確認。
+// 遇到速率限制、逾時或查詢次數超過 importLookupLimit 時停止查詢，剩下的資料全部略過
+func resolveImportEntries(ctx context.Context, p *provider.Providers, entries []usertransfer.Entry, progress func(done int)) importResult {
+	var result importResult
+	budget := &importBudget{left: importLookupLimit}
+	lookupLimitReason := fmt.Sprintf("超過單次匯入的查詢上限(%d次)，請分批匯入", importLookupLimit)
+	seen := make(map[int]bool)
+	match := func(e usertransfer.Entry, game *erogs.Game) {
+		if seen[game.ID] {
+			result.Skipped = append(result.Skipped, importSkip{Name: e.Name(), Reason: "重複"})
+			return
+		}
+		seen[game.ID] = true
+		result.Matched = append(result.Matched, importMatch{Entry: e, Game: game})
+	}
+
+	for idx, e := range entries {
+		if progress != nil {
+			progress(idx)
+		}
+		if ctx.Err() != nil {
+			result.Skipped = append(result.Skipped, skipRemaining(entries[idx:], "查詢逾時")...)
+			break
+		}
+		if e.Started != nil && e.Finished != nil && e.Finished.Before(*e.Started) {
+			result.Skipped = append(result.Skipped, importSkip{Name: e.Name(), Reason: "結束日期早於開始日期"})
+			continue
+		}
+
+		if e.ErogsID > 0 {
+			if err := budget.take(); err != nil {
+				result.Skipped = append(result.Skipped, skipRemaining(entries[idx:], lookupLimitReason)...)
+				break
+			}
+			game, err := executor.GetErogsGame(ctx, p.Erogs, e.ErogsID)
+			if errors.Is(err, kurohelperservice.ErrRateLimit) {
+				result.Skipped = append(result.Skipped, skipRemaining(entries[idx:], "速率限制")...)
+				break
+			}
+			if err != nil || game == nil {
+				result.Skipped = append(result.Skipped, importSkip{Name: e.Name(), Reason: "找不到批評空間遊戲"})
+				continue
+			}
+			match(e, game)
+			continue
+		}
+
+		game, candidates, err := resolveVndbEntry(ctx, p, budget, &e)
+		if errors.Is(err, kurohelperservice.ErrRateLimit) {
+			result.Skipped = append(result.Skipped, skipRemaining(entries[idx:], "速率限制")...)
+			break
+		}
+		if errors.Is(err, errImportLookupLimit) {
+			result.Skipped = append(result.Skipped, skipRemaining(entries[idx:], lookupLimitReason)...)
+			break
+		}
+		switch {
+		case game != nil:
+			match(e, game)
+		case len(candidates) > 0:
+			result.Ambiguous = append(result.Ambiguous, ImportAmbiguous{Entry: e, Candidates: candidates})
+		default:
+			result.Skipped = append(result.Skipped, importSkip{Name: e.Name(), Reason: "批評空間找不到對應遊戲"})
+		}
+	}
+	return result
+}
+
+// 沒有標題時先從VNDB取得標題(會寫回 e.Titles)，每次查詢都會消耗 budget
+func resolveVndbEntry(ctx context.Context, p *provider.Providers, budget *importBudget, e *usertransfer.Entry) (*erogs.Game, []ImportCandidate, error) {
+	if len(e.Titles) == 0 {
+		if err := budget.take(); err != nil {
+			return nil, nil, err
+		}
+		res, err := p.Vndb.GetVNByID(ctx, e.VndbID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if res == nil || len(res.Results) == 0 {
+			return nil, nil, nil
+		}
+		for _, t := range []string{res.Results[0].Alttitle, res.Results[0].Title} {
+			if strings.TrimSpace(t) != "" {
+				e.Titles = append(e.Titles, t)
+			}
+		}
+	}
+
+	if err := budget.take(); err != nil {
+		return nil, nil, err
+	}
+	if key := executor.ErogsKeyForVndb(ctx, p.Erogs, e.VndbID, e.Titles...); key != "" {
+		if err := budget.take(); err != nil {
+			return nil, nil, err
+		}
+		id, _ := strconv.Atoi(strings.TrimPrefix(key, "e"))
+		game, err := executor.GetErogsGame(ctx, p.Erogs, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if game != nil {
+			return game, nil, nil
+		}
+	}
+
+	if len(e.Titles) == 0 {
+		return nil, nil, nil
+	}
+	if err := budget.take(); err != nil {
+		return nil, nil, err
+	}
+	list, err := p.Erogs.SearchGameListByKeyword(ctx, e.Titles)
+	if err != nil {
+		if errors.Is(err, kurohelperservice.ErrSearchNoContent) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	var candidates []ImportCandidate
+	for idx, item := range list {
+		if idx >= importCandidateLimit {
+			break
+		}
+		if err := budget.take(); err != nil {
+			return nil, nil, err
+		}
+		game, err := executor.GetErogsGame(ctx, p.Erogs, item.ID)
+		if err != nil {
+			if errors.Is(err, kurohelperservice.ErrRateLimit) {
+				return nil, nil, err
+			}
+			continue
+		}
+		switch game.VndbId {
+		case e.VndbID:
+			return game, nil, nil
+		case "":
+			candidates = append(candidates, ImportCandidate{ID: game.ID, Name: game.Gamename})
+		}
+	}
+	return nil, candidates, nil
+}
+
+func skipRemaining(entries []usertransfer.Entry, reason string) []importSkip {
+	skipped := make([]importSkip, 0, len(entries))
+	for _, e := range entries {
+		skipped = append(skipped, importSkip{Name: e.Name(), Reason: reason})
+	}
+	return skipped
+}
+
+// 寫入對應成功的資料，每 importBatchSize 筆一個交易，回傳寫入的筆數
+//
+// 已經有的資料會被覆蓋，匯入檔案沒有填寫的欄位(日期、評分、時數、心得)保留原本的值
+func saveImportMatches(userID, userName string, matches []importMatch) (int, error) {
+	if err := kurohelperdb.EnsureDiscordUser(kurohelperdb.Dbs, userID, userName); err != nil {
+		return 0, err
+	}
+	user, err := kurohelperdb.GetUserByDiscordID(kurohelperdb.Dbs, userID)
+	if err != nil {
+		return 0, err
+	}
+	// 確保新建立的使用者有加入快取
+	if _, ok := store.UserStore[userID]; !ok {
+		store.UserStore[userID] = struct{}{}
+	}
+
+	userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	fromStatus := make(map[int]kurohelperdb.UserGameStatus, len(userGames))
+	for _, ug := range userGames {
+		fromStatus[ug.GameErogsID] = ug.Status
+	}
+
+	saved := 0
+	for start := 0; start < len(matches); start += importBatchSize {
+		batch := matches[start:min(start+importBatchSize, len(matches))]
+		err := kurohelperdb.Dbs.Transaction(func(tx *gorm.DB) error {
+			for _, m := range batch {
+				if err := saveImportMatch(tx, user.ID, m, fromStatus[m.Game.ID]); err != nil {
+					return err
+				}
+			}
+			return nil // commit
+		})
+		if err != nil {
+			return saved, err
+		}
+		saved += len(batch)
+	}
+	return saved, nil
+}
+
+// 寫入到一半失敗時只有前 saved 筆已經寫入，其他對應成功的資料改列為略過
+func partialImportResult(result importResult, saved int) importResult {
+	unsaved := result.Matched[saved:]
+	result.Matched = result.Matched[:saved:saved]
+	skipped := make([]importSkip, 0, len(result.Skipped)+len(unsaved))
+	skipped = append(skipped, result.Skipped...)
+	for _, m := range unsaved {
+		skipped = append(skipped, importSkip{Name: m.Entry.Name(), Reason: "寫入失敗"})
+	}
+	result.Skipped = skipped
+	return result
+}
+
+func saveImportMatch(tx *gorm.DB, userID string, m importMatch, from kurohelperdb.UserGameStatus) error {
+	res, e := m.Game, m.Entry
+
+	// 新增欄位資料先用預設值
+	if _, err := kurohelperdb.EnsureBrandErogs(tx, res.BrandID, res.BrandName, false, 0); err != nil {
+		return err
+	}
+	image := erogs.MakeDMMImageURL(res.DMM)
+	if strings.TrimSpace(res.DMM) == "" {
+		image = ""
+	}
+	if _, err := kurohelperdb.EnsureGameErogs(tx, res.ID, res.Gamename, image, res.BrandID, res.Model); err != nil {
+		return err
+	}
+	if err := kurohelperdb.EnsureUserGame(tx, userID, res.ID); err != nil {
+		return err
+	}
+
+	to := e.Status
+	if to == kurohelperdb.UserGameStatusNone {
+		// 只有收藏或黑名單時不動原本的遊玩狀態
+		to = from
+	} else if to == kurohelperdb.UserGameStatusFinished {
+		if err := kurohelperdb.UpdateUserGameFinished(tx, userID, res.ID, e.Finished); err != nil {
+			return err
+		}
+	} else if err := kurohelperdb.UpdateUserGameStatus(tx, userID, res.ID, to); err != nil {
+		return err
+	}
+	if e.Wish || e.BlackList {
+		if err := kurohelperdb.UpdateUserGameWishListMark(tx, userID, res.ID, e.Wish); err != nil {
+			return err
+		}
+		if err := userdata.UpdateUserGameBlackListMark(tx, userID, res.ID, e.BlackList); err != nil {
+			return err
+		}
+	}
+
+	detail, err := userdata.GetUserGameDetail(tx, userID, res.ID)
+	if err != nil {
+		return err
+	}
+	// 匯入的是過去的紀錄，沒有日期時不補今天
+	if e.Started != nil {
+		detail.StartedDate = e.Started
+	}
+	if e.Finished != nil && to != kurohelperdb.UserGameStatusPlaying {
+		detail.EndedDate = e.Finished
+		if detail.StartedDate != nil && e.Finished.Before(*detail.StartedDate) {
+			detail.StartedDate = nil
+		}
+	}
+	if e.Rating > 0 {
+		detail.Rating = e.Rating
+	}
+	if e.PlayHours > 0 {
+		detail.PlayHours = e.PlayHours
+	}
+	if e.Notes != "" {
+		detail.Notes = e.Notes
+	}
+	if err := userdata.SaveUserGameDetail(tx, &detail); err != nil {
+		return err
+	}
+
+	date := time.Now()
+	switch {
+	case e.Finished != nil && to != kurohelperdb.UserGameStatusPlaying:
+		date = *e.Finished
+	case e.Started != nil:
+		date = *e.Started
+	}
+	return userdata.RecordStatusChange(tx, userID, res.ID, from, to, date)
+}
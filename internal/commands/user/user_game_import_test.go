@@ -0,0 +1,263 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"kurohelperservice"
+	"kurohelperservice/provider/erogs"
+	"kurohelperservice/provider/vndb"
+
+	"kurohelper/internal/provider"
+	"kurohelper/internal/usertransfer"
+)
+
+// 查詢指定ID時回傳速率限制的批評空間
+type rateLimitedErogs struct {
+	provider.Erogs
+	limitedID int
+}
+
+func (r rateLimitedErogs) SearchGameByID(ctx context.Context, id int) (*erogs.Game, error) {
+	if id == r.limitedID {
+		return nil, kurohelperservice.ErrRateLimit
+	}
+	return r.Erogs.SearchGameByID(ctx, id)
+}
+
+// 匯入測試用的假資料，ID與其他測試錯開避免共用快取
+func importTestProviders() *provider.Providers {
+	return &provider.Providers{
+		Erogs: &provider.FixtureErogs{
+			Game: map[string]erogs.Game{
+				"90001": {ID: 90001, Gamename: "Alpha", VndbId: "v90001"},
+				"90002": {ID: 90002, Gamename: "Beta"},
+				"90003": {ID: 90003, Gamename: "Gamma"},
+				"90004": {ID: 90004, Gamename: "Delta", VndbId: "v99999"},
+				"90005": {ID: 90005, Gamename: "Epsilon", VndbId: "v90005"},
+			},
+			// 快取對應(ErogsKeyForVndb)用的標題搜尋
+			GameByKeyword: map[string]erogs.Game{
+				"epsilon": {ID: 90005, Gamename: "Epsilon", VndbId: "v90005"},
+			},
+			GameList: map[string][]erogs.GameList{
+				"alpha search": {{ID: 90004}, {ID: 90001}},
+				"ambiguous":    {{ID: 90002}, {ID: 90003}, {ID: 90004}},
+				"rate limited": {{ID: 90066}},
+			},
+		},
+		Vndb: &provider.FixtureVndb{
+			Vn: map[string]vndb.BasicResponse[vndb.GetVnUseIDResponse]{
+				"v90001": {Results: []vndb.GetVnUseIDResponse{{Title: "Alpha Search"}}},
+			},
+		},
+	}
+}
+
+func TestResolveImportEntries(t *testing.T) {
+	started := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	finished := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	lookupLimitReason := fmt.Sprintf("超過單次匯入的查詢上限(%d次)，請分批匯入", importLookupLimit)
+
+	// 每筆消耗一次查詢，n筆剛好用掉n次
+	erogsEntries := func(n int) []usertransfer.Entry {
+		entries := make([]usertransfer.Entry, n)
+		for idx := range entries {
+			entries[idx] = usertransfer.Entry{ErogsID: 90001}
+		}
+		return entries
+	}
+	repeat := func(skip importSkip, n int) []importSkip {
+		out := make([]importSkip, n)
+		for idx := range out {
+			out[idx] = skip
+		}
+		return out
+	}
+
+	tests := []struct {
+		name          string
+		erogs         func(provider.Erogs) provider.Erogs
+		entries       []usertransfer.Entry
+		wantMatched   []int
+		wantSkipped   []importSkip
+		wantAmbiguous map[string][]int
+	}{
+		{
+			name:        "erogs id",
+			entries:     []usertransfer.Entry{{ErogsID: 90001}, {ErogsID: 90404}},
+			wantMatched: []int{90001},
+			wantSkipped: []importSkip{{Name: "e90404", Reason: "找不到批評空間遊戲"}},
+		},
+		{
+			name: "duplicate seen",
+			entries: []usertransfer.Entry{
+				{ErogsID: 90001},
+				{VndbID: "v90001", Titles: []string{"Alpha Search"}},
+			},
+			wantMatched: []int{90001},
+			wantSkipped: []importSkip{{Name: "Alpha Search", Reason: "重複"}},
+		},
+		{
+			name:        "cached vndb mapping",
+			entries:     []usertransfer.Entry{{VndbID: "v90005", Titles: []string{"Epsilon"}}},
+			wantMatched: []int{90005},
+		},
+		{
+			// 候選中VNDB ID一致的直接採用，不一致的不列入
+			name:        "vndb id candidate match",
+			entries:     []usertransfer.Entry{{VndbID: "v90001", Titles: []string{"Alpha Search"}}},
+			wantMatched: []int{90001},
+		},
+		{
+			name:        "title from vndb",
+			entries:     []usertransfer.Entry{{VndbID: "v90001"}},
+			wantMatched: []int{90001},
+		},
+		{
+			// 沒有VNDB ID的候選交給使用者確認，VNDB ID不同的排除
+			name:          "ambiguous",
+			entries:       []usertransfer.Entry{{VndbID: "v90002", Titles: []string{"Ambiguous"}}},
+			wantAmbiguous: map[string][]int{"Ambiguous": {90002, 90003}},
+		},
+		{
+			name:        "not found",
+			entries:     []usertransfer.Entry{{VndbID: "v90404", Titles: []string{"Nothing"}}},
+			wantSkipped: []importSkip{{Name: "Nothing", Reason: "批評空間找不到對應遊戲"}},
+		},
+		{
+			name:        "end before start",
+			entries:     []usertransfer.Entry{{ErogsID: 90001, Titles: []string{"Alpha"}, Started: &started, Finished: &finished}},
+			wantSkipped: []importSkip{{Name: "Alpha", Reason: "結束日期早於開始日期"}},
+		},
+		{
+			name:  "rate limit aborts the rest",
+			erogs: func(e provider.Erogs) provider.Erogs { return rateLimitedErogs{Erogs: e, limitedID: 90066} },
+			entries: []usertransfer.Entry{
+				{ErogsID: 90001},
+				{ErogsID: 90066, Titles: []string{"Limited"}},
+				{ErogsID: 90005, Titles: []string{"Epsilon"}},
+			},
+			wantMatched: []int{90001},
+			wantSkipped: []importSkip{{Name: "Limited", Reason: "速率限制"}, {Name: "Epsilon", Reason: "速率限制"}},
+		},
+		{
+			name:  "rate limit while checking candidates",
+			erogs: func(e provider.Erogs) provider.Erogs { return rateLimitedErogs{Erogs: e, limitedID: 90066} },
+			entries: []usertransfer.Entry{
+				{VndbID: "v90066", Titles: []string{"Rate Limited"}},
+				{ErogsID: 90001, Titles: []string{"Alpha"}},
+			},
+			wantSkipped: []importSkip{{Name: "Rate Limited", Reason: "速率限制"}, {Name: "Alpha", Reason: "速率限制"}},
+		},
+		{
+			name:        "lookup limit",
+			entries:     append(erogsEntries(importLookupLimit), usertransfer.Entry{ErogsID: 90005, Titles: []string{"Epsilon"}}),
+			wantMatched: []int{90001},
+			wantSkipped: append(
+				repeat(importSkip{Name: "e90001", Reason: "重複"}, importLookupLimit-1),
+				importSkip{Name: "Epsilon", Reason: lookupLimitReason},
+			),
+		},
+		{
+			// 標題搜尋到一半用完次數：對應(1)、搜尋(2)後檢查候選時超過
+			name: "lookup limit inside vndb entry",
+			entries: append(erogsEntries(importLookupLimit-2),
+				usertransfer.Entry{VndbID: "v90001", Titles: []string{"Alpha Search"}},
+				usertransfer.Entry{ErogsID: 90005, Titles: []string{"Epsilon"}},
+			),
+			wantMatched: []int{90001},
+			wantSkipped: append(
+				repeat(importSkip{Name: "e90001", Reason: "重複"}, importLookupLimit-3),
+				importSkip{Name: "Alpha Search", Reason: lookupLimitReason},
+				importSkip{Name: "Epsilon", Reason: lookupLimitReason},
+			),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := importTestProviders()
+			if tt.erogs != nil {
+				p.Erogs = tt.erogs(p.Erogs)
+			}
+
+			got := resolveImportEntries(context.Background(), p, tt.entries, nil)
+
+			var matched []int
+			for _, m := range got.Matched {
+				matched = append(matched, m.Game.ID)
+			}
+			if !reflect.DeepEqual(matched, tt.wantMatched) {
+				t.Errorf("matched = %v, want %v", matched, tt.wantMatched)
+			}
+			if !reflect.DeepEqual(got.Skipped, tt.wantSkipped) {
+				if len(got.Skipped) > 5 {
+					t.Errorf("skipped %d entries, last %v; want %d, last %v", len(got.Skipped), got.Skipped[len(got.Skipped)-1], len(tt.wantSkipped), tt.wantSkipped[len(tt.wantSkipped)-1])
+				} else {
+					t.Errorf("skipped = %v, want %v", got.Skipped, tt.wantSkipped)
+				}
+			}
+			ambiguous := make(map[string][]int)
+			for _, a := range got.Ambiguous {
+				for _, c := range a.Candidates {
+					ambiguous[a.Entry.Name()] = append(ambiguous[a.Entry.Name()], c.ID)
+				}
+			}
+			if tt.wantAmbiguous == nil {
+				tt.wantAmbiguous = map[string][]int{}
+			}
+			if !reflect.DeepEqual(ambiguous, tt.wantAmbiguous) {
+				t.Errorf("ambiguous = %v, want %v", ambiguous, tt.wantAmbiguous)
+			}
+		})
+	}
+}
+
+// 查詢逾時後剩下的資料全部略過
+func TestResolveImportEntriesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var done []int
+	entries := []usertransfer.Entry{{ErogsID: 90001}, {ErogsID: 90005}}
+	got := resolveImportEntries(ctx, importTestProviders(), entries, func(idx int) {
+		done = append(done, idx)
+		if idx == 1 {
+			cancel()
+		}
+	})
+	if len(got.Matched) != 1 || got.Matched[0].Game.ID != 90001 {
+		t.Errorf("matched = %+v, want only 90001", got.Matched)
+	}
+	if want := []importSkip{{Name: "e90005", Reason: "查詢逾時"}}; !reflect.DeepEqual(got.Skipped, want) {
+		t.Errorf("skipped = %v, want %v", got.Skipped, want)
+	}
+	if !reflect.DeepEqual(done, []int{0, 1}) {
+		t.Errorf("progress = %v, want [0 1]", done)
+	}
+}
+
+// 後面的批次寫入失敗時，沒寫入的資料改列為略過
+func TestPartialImportResult(t *testing.T) {
+	result := importResult{
+		Matched: []importMatch{
+			{Entry: usertransfer.Entry{Titles: []string{"a"}}, Game: &erogs.Game{ID: 1}},
+			{Entry: usertransfer.Entry{Titles: []string{"b"}}, Game: &erogs.Game{ID: 2}},
+			{Entry: usertransfer.Entry{Titles: []string{"c"}}, Game: &erogs.Game{ID: 3}},
+		},
+		Skipped: []importSkip{{Name: "x", Reason: "重複"}},
+	}
+	got := partialImportResult(result, 1)
+	if len(got.Matched) != 1 || got.Matched[0].Game.ID != 1 {
+		t.Errorf("matched = %+v, want only 1", got.Matched)
+	}
+	want := []importSkip{{Name: "x", Reason: "重複"}, {Name: "b", Reason: "寫入失敗"}, {Name: "c", Reason: "寫入失敗"}}
+	if !reflect.DeepEqual(got.Skipped, want) {
+		t.Errorf("skipped = %v, want %v", got.Skipped, want)
+	}
+	// 原本的結果不受影響
+	if len(result.Matched) != 3 || len(result.Skipped) != 1 {
+		t.Errorf("original result modified: %+v", result)
+	}
+}
@@ -0,0 +1,234 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+
+	"kurohelperservice"
+	kurohelperdb "kurohelperservice/db"
+	"kurohelperservice/provider/erogs"
+
+	"kurohelper/internal/autocomplete"
+	"kurohelper/internal/cache"
+	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/store"
+	"kurohelper/internal/tracing"
+	"kurohelper/internal/userdata"
+	"kurohelper/internal/utils"
+)
+
+// 加收藏、加黑名單共用的設定
+type userGameMark struct {
+	commandName string
+	color       int
+	// 確認時的提示
+	confirmText string
+	// 確認訊息是否顯示遊戲圖片
+	showImage bool
+	// 在交易內設定標記
+	apply func(tx *gorm.DB, userID string, gameID int) error
+}
+
+// 收藏與黑名單不會同時存在，設定其中一個時會清掉另一個
+var (
+	wishMark = userGameMark{
+		commandName: addInWishCommandName,
+		color:       0x90B44B,
+		confirmText: "你確定要加入收藏嗎?",
+		showImage:   true,
+		apply: func(tx *gorm.DB, userID string, gameID int) error {
+			if err := kurohelperdb.UpdateUserGameWishListMark(tx, userID, gameID, true); err != nil {
+				return err
+			}
+			return userdata.UpdateUserGameBlackListMark(tx, userID, gameID, false)
+		},
+	}
+	blackListMark = userGameMark{
+		commandName: addBlackListCommandName,
+		color:       0x656765,
+		confirmText: "你確定要加入黑名單嗎?(會同時移除收藏)",
+		apply: func(tx *gorm.DB, userID string, gameID int) error {
+			if err := userdata.UpdateUserGameBlackListMark(tx, userID, gameID, true); err != nil {
+				return err
+			}
+			return kurohelperdb.UpdateUserGameWishListMark(tx, userID, gameID, false)
+		},
+	}
+)
+
+// 沒有cid時搜尋遊戲並送出確認按鈕，按下確認按鈕後寫入標記
+func handleUserGameMark(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2, p *provider.Providers, mark userGameMark) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	if cid != nil {
+		saveUserGameMark(ctx, s, i, cid, mark)
+	} else {
+		confirmUserGameMark(ctx, s, i, p, mark)
+	}
+}
+
+func confirmUserGameMark(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, p *provider.Providers, mark userGameMark) {
+	var res *erogs.Game
+
+	keyword, err := utils.GetOptions(i, "keyword")
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	autocomplete.Games.Hit(keyword)
+
+	idSearch, _ := regexp.MatchString(`^e\d+$`, keyword)
+	if idSearch {
+		num, _ := strconv.Atoi(keyword[1:])
+		res, err = p.Erogs.SearchGameByID(ctx, num)
+	} else {
+		res, err = p.Erogs.SearchGameByKeyword(ctx, []string{keyword, kurohelperservice.ZhTwToJp(keyword)})
+	}
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	if res == nil {
+		utils.HandleError(kurohelperservice.ErrSearchNoContent, s, i)
+		return
+	}
+
+	idStr := uuid.New().String()
+	cache.UserInfoCache.Set(idStr, *res)
+	cache.CIDV2Store.Set(idStr, cache.CIDEntry{OwnerID: utils.GetUserID(i)})
+
+	confirmCID, err := utils.MakeUserDataOperationCIDV2(mark.commandName, idStr, res.ID)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	messageComponent := []discordgo.MessageComponent{
+		discordgo.Button{
+			Label:    "✅",
+			Style:    discordgo.PrimaryButton,
+			CustomID: confirmCID,
+		},
+	}
+	actionsRow := utils.MakeActionsRow(messageComponent)
+
+	embed := &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: res.BrandName,
+		},
+		Title: fmt.Sprintf("**%s(%s)**", res.Gamename, res.SellDay),
+		URL:   res.Shoukai,
+		Color: mark.color,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   "發行機種",
+				Value:  res.Model,
+				Inline: false,
+			},
+			{
+				Name:   "確認",
+				Value:  mark.confirmText,
+				Inline: false,
+			},
+		},
+	}
+	if mark.showImage {
+		embed.Image = utils.GenerateImage(i, res.BannerUrl)
+	}
+	utils.InteractionEmbedRespondForSelf(s, i, embed, actionsRow, true)
+}
+
+func saveUserGameMark(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2, mark userGameMark) {
+	if cid.GetBehaviorID() != utils.UserDataOperationBehavior {
+		utils.HandleError(kurohelpererrors.ErrCIDBehaviorMismatch, s, i)
+		return
+	}
+	userDataCID, err := cid.ToUserDataOperationCIDV2()
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	// get cache
+	cacheValue, err := cache.UserInfoCache.Get(userDataCID.CacheID)
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+	resValue := cacheValue.(erogs.Game)
+	res := &resValue
+
+	userID := utils.GetUserID(i)
+	userName := utils.GetUsername(i)
+	if strings.TrimSpace(userID) == "" || strings.TrimSpace(userName) == "" {
+		// 找不到使用者，此狀況應該會是Discord官方問題或是程式碼邏輯問題
+		embed := &discordgo.MessageEmbed{
+			Title: "找不到使用者！",
+			Color: mark.color,
+		}
+		utils.InteractionEmbedRespondForSelf(s, i, embed, nil, true)
+		return
+	}
+
+	err = kurohelperdb.Dbs.Transaction(func(tx *gorm.DB) error {
+		// 1. 確保 User 存在
+		if err := kurohelperdb.EnsureDiscordUser(tx, userID, userName); err != nil {
+			return err
+		}
+		user, err := kurohelperdb.GetUserByDiscordID(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		// 2. 確保 Brand 存在
+		// 新增欄位資料先用預設值
+		if _, err := kurohelperdb.EnsureBrandErogs(tx, res.BrandID, res.BrandName, false, 0); err != nil {
+			return err
+		}
+
+		// 3. 確保 Game 存在
+		image := erogs.MakeDMMImageURL(res.DMM)
+		if strings.TrimSpace(res.DMM) == "" {
+			image = ""
+		}
+		if _, err := kurohelperdb.EnsureGameErogs(tx, res.ID, res.Gamename, image, res.BrandID, res.Model); err != nil {
+			return err
+		}
+
+		// 4. 先確保有空殼資料，再更新標記
+		if err := kurohelperdb.EnsureUserGame(tx, user.ID, res.ID); err != nil {
+			return err
+		}
+		return mark.apply(tx, user.ID, res.ID) // commit
+	})
+	if err != nil {
+		utils.HandleError(err, s, i)
+		return
+	}
+
+	// 確保新建立的使用者有加入快取
+	if _, ok := store.UserStore[userID]; !ok {
+		store.UserStore[userID] = struct{}{}
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: "加入成功！",
+		Color: mark.color,
+	}
+	utils.InteractionEmbedRespondForSelf(s, i, embed, nil, true)
+	tracing.Logger(ctx).Info(mark.commandName+"成功", "使用者ID", userID, "遊戲ID", res.ID, "遊戲名稱", res.Gamename)
+}
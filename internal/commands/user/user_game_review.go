@@ -0,0 +1,124 @@
+package user
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+
+	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/userdata"
+	"kurohelper/internal/utils"
+)
+
+// 評分、心得、遊玩時數，nil代表沒有填寫
+type reviewInput struct {
+	Rating    *int
+	PlayHours *float64
+	Notes     *string
+}
+
+// 加已玩與編輯遊戲紀錄共用的選填選項
+func reviewOptions() []*discordgo.ApplicationCommandOption {
+	minRating := float64(userdata.MinRating)
+	minHours := 0.0
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "rating",
+			Description: "個人評分(1~100)",
+			MinValue:    &minRating,
+			MaxValue:    userdata.MaxRating,
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionNumber,
+			Name:        "play_hours",
+			Description: "遊玩時數(小時)",
+			MinValue:    &minHours,
+			MaxValue:    userdata.MaxPlayHours,
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "notes",
+			Description: "心得，劇透內容用 ||文字|| 或 [spoiler]文字[/spoiler] 包起來",
+			MaxLength:   userdata.MaxNotesLength,
+			Required:    false,
+		},
+	}
+}
+
+func getReviewOptions(i *discordgo.InteractionCreate) (reviewInput, error) {
+	var input reviewInput
+
+	rating, err := utils.GetNumberOptions(i, "rating")
+	if err == nil {
+		if rating < userdata.MinRating || rating > userdata.MaxRating {
+			return input, kurohelpererrors.ErrOptionTranslateFail
+		}
+		v := int(rating)
+		input.Rating = &v
+	} else if !errors.Is(err, kurohelpererrors.ErrOptionNotFound) {
+		return input, err
+	}
+
+	hours, err := utils.GetNumberOptions(i, "play_hours")
+	if err == nil {
+		if hours < 0 || hours > userdata.MaxPlayHours {
+			return input, kurohelpererrors.ErrOptionTranslateFail
+		}
+		input.PlayHours = &hours
+	} else if !errors.Is(err, kurohelpererrors.ErrOptionNotFound) {
+		return input, err
+	}
+
+	notes, err := utils.GetOptions(i, "notes")
+	if err == nil {
+		notes = userdata.NormalizeNotes(notes)
+		if utf8.RuneCountInString(notes) > userdata.MaxNotesLength {
+			return input, kurohelpererrors.ErrOptionTranslateFail
+		}
+		input.Notes = &notes
+	} else if !errors.Is(err, kurohelpererrors.ErrOptionNotFound) {
+		return input, err
+	}
+
+	return input, nil
+}
+
+func (r reviewInput) empty() bool {
+	return r.Rating == nil && r.PlayHours == nil && r.Notes == nil
+}
+
+func (r reviewInput) apply(detail *userdata.UserGameDetail) {
+	if r.Rating != nil {
+		detail.Rating = *r.Rating
+	}
+	if r.PlayHours != nil {
+		detail.PlayHours = *r.PlayHours
+	}
+	if r.Notes != nil {
+		detail.Notes = *r.Notes
+	}
+}
+
+// 評分、時數與心得的 Embed 欄位
+func reviewFields(detail userdata.UserGameDetail) []*discordgo.MessageEmbedField {
+	var fields []*discordgo.MessageEmbedField
+	if review := detail.FormatReview(); review != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "評分/遊玩時數",
+			Value:  review,
+			Inline: false,
+		})
+	}
+	if detail.Notes != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "心得",
+			Value:  detail.Notes,
+			Inline: false,
+		})
+	}
+	return fields
+}
@@ -0,0 +1,285 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+
+	kurohelperdb "kurohelperservice/db"
+	"kurohelperservice/provider/erogs"
+
+	"kurohelper/internal/cache"
+	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/executor"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/ratelimit"
+	"kurohelper/internal/tracing"
+	"kurohelper/internal/userdata"
+	"kurohelper/internal/userstats"
+	"kurohelper/internal/utils"
+)
+
+// 遊玩統計頁面，依序為總覽、完成紀錄、類型與品牌、創作者
+type UserStatistics struct {
+	Providers *provider.Providers
+}
+
+// UserInfoCache 中的統計資料
+type UserStatisticsCache struct {
+	Name  string
+	Stats userstats.Statistics
+}
+
+const (
+	userStatisticsCommandName = "個人統計"
+	userStatisticsTotalPages  = 4
+	// 快取沒有的遊戲資料，每次最多向批評空間查詢幾筆
+	userStatisticsFetchLimit = 30
+	// 長條圖寬度
+	userStatisticsBarWidth = 12
+)
+
+var userStatisticsColor = 0xB481BB
+
+func (us *UserStatistics) Definition() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "個人統計",
+		Description: "查看遊玩統計(完成數、類型、品牌、創作者)",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "discord_id",
+				Description: "要查詢的使用者 Discord ID（選填）",
+				Required:    false,
+			},
+		},
+	}
+}
+
+// 沒有快取的遊戲資料需要查詢批評空間
+func (us *UserStatistics) RateLimit() ratelimit.Rule {
+	return ratelimit.Rule{Burst: 3, Per: time.Minute}
+}
+
+// 統計頁面只有查詢的人可以翻頁
+func (us *UserStatistics) ComponentAccess() utils.ComponentAccess {
+	return utils.ComponentOwnerOnly
+}
+
+func (us *UserStatistics) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
+	us.HandleComponent(ctx, s, i, nil)
+}
+
+func (us *UserStatistics) HandleComponent(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate, cid *utils.CIDV2) {
+	if cid != nil {
+		if cid.GetBehaviorID() != utils.PageBehavior {
+			utils.HandleErrorV2(kurohelpererrors.ErrCIDBehaviorMismatch, s, i, utils.InteractionRespondEditComplex)
+			return
+		}
+		pageCID, err := cid.ToPageCIDV2()
+		if err != nil {
+			utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
+			return
+		}
+		executor.ChangePage(ctx, s, i, pageCID, cache.UserInfoCache, buildUserStatisticsComponents)
+		return
+	}
+
+	// 長時間查詢
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+
+	requesterID := utils.GetUserID(i)
+	targetDiscordID := requesterID
+	targetUserIDOption, err := utils.GetOptions(i, "discord_id")
+	if err != nil && !errors.Is(err, kurohelpererrors.ErrOptionNotFound) {
+		utils.HandleErrorV2(err, s, i, utils.WebhookEditRespond)
+		return
+	}
+	if strings.TrimSpace(targetUserIDOption) != "" {
+		targetDiscordID = strings.TrimSpace(targetUserIDOption)
+	}
+
+	user, err := kurohelperdb.GetUserByDiscordID(kurohelperdb.Dbs, targetDiscordID)
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.WebhookEditRespond)
+		return
+	}
+	if targetDiscordID != requesterID && user.PrivateGameData {
+		utils.HandleErrorV2(kurohelpererrors.ErrPrivateGameData, s, i, utils.WebhookEditRespond)
+		return
+	}
+
+	userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, targetDiscordID)
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.WebhookEditRespond)
+		return
+	}
+	userGames = filterDisplayUserGames(userGames)
+	details, err := userdata.GetUserGameDetails(kurohelperdb.Dbs, user.ID)
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.WebhookEditRespond)
+		return
+	}
+	brandIDs := make([]int, 0, len(userGames))
+	for _, ug := range userGames {
+		brandIDs = append(brandIDs, ug.GameErogs.BrandID)
+	}
+	brandNames, err := userdata.GetBrandNames(kurohelperdb.Dbs, brandIDs)
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.WebhookEditRespond)
+		return
+	}
+
+	games := us.loadPlayedGames(ctx, userGames)
+	statistics := UserStatisticsCache{
+		Name:  user.Name,
+		Stats: userstats.Compute(userGames, details, games, brandNames, time.Now()),
+	}
+
+	cacheID := uuid.New().String()
+	cache.UserInfoCache.Set(cacheID, statistics)
+	cache.CIDV2Store.Set(cacheID, cache.CIDEntry{OwnerID: requesterID})
+
+	components, err := buildUserStatisticsComponents(statistics, 1, cacheID)
+	if err != nil {
+		utils.HandleErrorV2(err, s, i, utils.WebhookEditRespond)
+		return
+	}
+	utils.WebhookEditRespond(s, i, components)
+	tracing.Logger(ctx).Info("個人統計", "使用者ID", requesterID, "查詢對象", targetDiscordID, "遊戲數", statistics.Stats.Played, "涵蓋", statistics.Stats.Covered)
+}
+
+// 取得有遊玩狀態的遊戲的批評空間資料，優先使用快取，
+// 快取沒有的最多查詢 userStatisticsFetchLimit 筆，查詢失敗的不列入統計
+func (us *UserStatistics) loadPlayedGames(ctx context.Context, userGames []kurohelperdb.UserGame) map[int]*erogs.Game {
+	games := make(map[int]*erogs.Game, len(userGames))
+	fetched := 0
+	for _, ug := range userGames {
+		if ug.Status == kurohelperdb.UserGameStatusNone {
+			continue
+		}
+		if game, err := cache.ErogsGameStore.Get("e" + strconv.Itoa(ug.GameErogsID)); err == nil && game != nil {
+			games[ug.GameErogsID] = game
+			continue
+		}
+		if fetched >= userStatisticsFetchLimit || ctx.Err() != nil {
+			continue
+		}
+		fetched++
+		game, err := executor.GetErogsGame(ctx, us.Providers.Erogs, ug.GameErogsID)
+		if err != nil || game == nil {
+			continue
+		}
+		games[ug.GameErogsID] = game
+	}
+	return games
+}
+
+func buildUserStatisticsComponents(value any, currentPage int, cacheID string) ([]discordgo.MessageComponent, error) {
+	statistics, ok := value.(UserStatisticsCache)
+	if !ok {
+		return nil, errors.New("handlers: user statistics cache data type mismatch")
+	}
+	stats := statistics.Stats
+	currentPage = min(max(currentPage, 1), userStatisticsTotalPages)
+
+	var title string
+	var sections []string
+	switch currentPage {
+	case 1:
+		title = "總覽"
+		sections = userStatisticsOverview(stats)
+	case 2:
+		title = "完成紀錄"
+		sections = []string{
+			"**每年完成數**\n" + chartOrEmpty(stats.Yearly),
+			fmt.Sprintf("**最近%d個月完成數**\n", userstats.MonthlyRange) + chartOrEmpty(stats.Monthly),
+		}
+	case 3:
+		title = "類型與品牌"
+		sections = []string{
+			"**類型分布**\n" + chartOrEmpty(stats.Genres),
+			"**品牌分布**\n" + chartOrEmpty(stats.Brands),
+		}
+	case 4:
+		title = "創作者"
+		sections = []string{
+			"**劇本**（依遊玩時數）\n" + chartOrEmpty(stats.Writers),
+			"**原畫**（依遊玩時數）\n" + chartOrEmpty(stats.Artists),
+		}
+	}
+
+	divider := true
+	containerComponents := []discordgo.MessageComponent{
+		discordgo.TextDisplay{
+			Content: fmt.Sprintf("# %s 的遊玩統計\n%s", statistics.Name, title),
+		},
+		discordgo.Separator{Divider: &divider},
+	}
+	for _, section := range sections {
+		containerComponents = append(containerComponents, discordgo.TextDisplay{Content: section})
+	}
+	if currentPage != 2 && stats.Covered < stats.Played {
+		containerComponents = append(containerComponents, discordgo.TextDisplay{
+			Content: fmt.Sprintf("-# 批評空間資料涵蓋 %d / %d 款，重新查詢會補上更多資料", stats.Covered, stats.Played),
+		})
+	}
+
+	pageComponents, err := utils.MakeChangePageComponent(userStatisticsCommandName, "", currentPage, userStatisticsTotalPages, cacheID)
+	if err != nil {
+		return nil, err
+	}
+	containerComponents = append(containerComponents,
+		discordgo.Separator{Divider: &divider},
+		pageComponents,
+	)
+
+	return []discordgo.MessageComponent{
+		discordgo.Container{
+			AccentColor: &userStatisticsColor,
+			Components:  containerComponents,
+		},
+	}, nil
+}
+
+func userStatisticsOverview(stats userstats.Statistics) []string {
+	statusLines := []string{
+		fmt.Sprintf("✅ 已完成 **%d**", stats.StatusCounts[kurohelperdb.UserGameStatusFinished]),
+		fmt.Sprintf("🎮 遊玩中 **%d**", stats.StatusCounts[kurohelperdb.UserGameStatusPlaying]),
+		fmt.Sprintf("⏸️ 擱置 **%d**", stats.StatusCounts[kurohelperdb.UserGameStatusStalled]),
+		fmt.Sprintf("🗑️ 棄坑 **%d**", stats.StatusCounts[kurohelperdb.UserGameStatusDropped]),
+		fmt.Sprintf("❤️ 收藏 **%d** / 🚫 黑名單 **%d**", stats.WishCount, stats.BlackListCount),
+		fmt.Sprintf("📚 待玩 **%d**（收藏未完成 + 遊玩中 + 擱置）", stats.Backlog),
+	}
+
+	scoreLines := make([]string, 0, 3)
+	if stats.MedianCount > 0 {
+		scoreLines = append(scoreLines, fmt.Sprintf("⭐ 批評空間中位數平均 **%.1f**（%d 部）", stats.MedianAverage, stats.MedianCount))
+	}
+	if reviewText := formatReviewSummary(stats.Review); reviewText != "" {
+		scoreLines = append(scoreLines, reviewText)
+	}
+	if len(scoreLines) == 0 {
+		scoreLines = append(scoreLines, "無資料")
+	}
+
+	return []string{
+		"**遊玩狀態**\n" + strings.Join(statusLines, "\n"),
+		"**分數與時數**\n" + strings.Join(scoreLines, "\n"),
+	}
+}
+
+func chartOrEmpty(counts []userstats.Count) string {
+	if chart := userstats.BarChart(counts, userStatisticsBarWidth); chart != "" {
+		return chart
+	}
+	return "無資料"
+}
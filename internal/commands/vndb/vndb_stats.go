@@ -1,6 +1,7 @@
 package vndb
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/bwmarrin/discordgo"
@@ -18,7 +19,7 @@ func (v *VNDBStats) Definition() *discordgo.ApplicationCommand {
 	}
 }
 
-func (v *VNDBStats) Handler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+func (v *VNDBStats) Handler(ctx context.Context, s utils.Responder, i *discordgo.InteractionCreate) {
 	r, err := vndb.GetStats()
 	if err != nil {
 		utils.HandleError(err, s, i)
@@ -0,0 +1,130 @@
+package discordtest
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 合成Interaction時預設的使用者與伺服器
+const (
+	DefaultUserID    = "100000000000000001"
+	DefaultUsername  = "tester"
+	DefaultGuildID   = "200000000000000001"
+	DefaultChannelID = "300000000000000001"
+	DefaultMessageID = "400000000000000001"
+)
+
+var interactionSeq atomic.Int64
+
+// 合成Interaction的可選設定
+type Option func(*discordgo.InteractionCreate)
+
+// 指定觸發的使用者(會放在Member或User，依照有無GuildID決定)
+func WithUser(userID, username string) Option {
+	return func(i *discordgo.InteractionCreate) {
+		u := &discordgo.User{ID: userID, Username: username}
+		if i.GuildID != "" {
+			i.Member = &discordgo.Member{User: u}
+			i.User = nil
+		} else {
+			i.User = u
+			i.Member = nil
+		}
+	}
+}
+
+// 指定伺服器，空字串代表私訊
+func WithGuild(guildID string) Option {
+	return func(i *discordgo.InteractionCreate) {
+		var u *discordgo.User
+		if i.Member != nil {
+			u = i.Member.User
+		} else {
+			u = i.User
+		}
+		i.GuildID = guildID
+		if guildID == "" {
+			i.Member = nil
+			i.User = u
+		} else {
+			i.Member = &discordgo.Member{User: u}
+			i.User = nil
+		}
+	}
+}
+
+// 字串選項
+func StringOption(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:  name,
+		Type:  discordgo.ApplicationCommandOptionString,
+		Value: value,
+	}
+}
+
+// 使用者正在輸入的字串選項(Autocomplete使用)
+func FocusedOption(name, value string) *discordgo.ApplicationCommandInteractionDataOption {
+	opt := StringOption(name, value)
+	opt.Focused = true
+	return opt
+}
+
+func newInteraction(t discordgo.InteractionType, data discordgo.InteractionData, opts []Option) *discordgo.InteractionCreate {
+	i := &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			ID:        strconv.FormatInt(interactionSeq.Add(1), 10),
+			Type:      t,
+			Data:      data,
+			GuildID:   DefaultGuildID,
+			ChannelID: DefaultChannelID,
+			Member: &discordgo.Member{
+				User: &discordgo.User{ID: DefaultUserID, Username: DefaultUsername},
+			},
+		},
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// 合成斜線指令
+func NewSlashCommand(name string, options []*discordgo.ApplicationCommandInteractionDataOption, opts ...Option) *discordgo.InteractionCreate {
+	return newInteraction(discordgo.InteractionApplicationCommand, discordgo.ApplicationCommandInteractionData{
+		Name:    name,
+		Options: options,
+	}, opts)
+}
+
+// 合成Autocomplete事件
+func NewAutocomplete(name string, options []*discordgo.ApplicationCommandInteractionDataOption, opts ...Option) *discordgo.InteractionCreate {
+	return newInteraction(discordgo.InteractionApplicationCommandAutocomplete, discordgo.ApplicationCommandInteractionData{
+		Name:    name,
+		Options: options,
+	}, opts)
+}
+
+// 合成按鈕點擊事件
+//
+// 會附上 Message，讓 InteractionRespondEditComplex 有訊息可以修改
+func NewButton(customID string, opts ...Option) *discordgo.InteractionCreate {
+	i := newInteraction(discordgo.InteractionMessageComponent, discordgo.MessageComponentInteractionData{
+		CustomID:      customID,
+		ComponentType: discordgo.ButtonComponent,
+	}, opts)
+	i.Message = &discordgo.Message{ID: DefaultMessageID, ChannelID: i.ChannelID}
+	return i
+}
+
+// 合成下拉選單選擇事件
+func NewSelectMenu(customID string, values []string, opts ...Option) *discordgo.InteractionCreate {
+	i := newInteraction(discordgo.InteractionMessageComponent, discordgo.MessageComponentInteractionData{
+		CustomID:      customID,
+		ComponentType: discordgo.SelectMenuComponent,
+		Values:        values,
+	}, opts)
+	i.Message = &discordgo.Message{ID: DefaultMessageID, ChannelID: i.ChannelID}
+	return i
+}
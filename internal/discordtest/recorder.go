@@ -0,0 +1,202 @@
+// discordtest 提供不需要連上Discord的測試工具
+//
+// Recorder 實作 utils.Responder，會把指令送出的每一個回應依序記錄下來，
+// 搭配 NewSlashCommand / NewButton / NewSelectMenu 等合成的 InteractionCreate，
+// 就可以透過 bot.DispatchInteraction(bot.OnInteraction 實際呼叫的分派函式)對指令做表格測試，
+// 範例見 internal/bot/interaction_test.go
+package discordtest
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 回應的種類
+type ResponseKind string
+
+const (
+	// s.InteractionRespond
+	KindRespond ResponseKind = "respond"
+	// s.InteractionResponseEdit
+	KindResponseEdit ResponseKind = "response_edit"
+	// s.ChannelMessageEditComplex
+	KindChannelMessageEdit ResponseKind = "channel_message_edit"
+)
+
+var ErrUserNotFound = errors.New("discordtest: user not found")
+
+// 單筆被記錄的回應
+//
+// 不同種類的回應只會填入對應的欄位，其餘欄位保持零值
+type Response struct {
+	Kind          ResponseKind
+	InteractionID string
+	// KindRespond 使用
+	Type discordgo.InteractionResponseType
+	// 不論種類都會整理到這裡，方便直接比對
+	Flags      discordgo.MessageFlags
+	Content    string
+	Components []discordgo.MessageComponent
+	Embeds     []*discordgo.MessageEmbed
+	Choices    []*discordgo.ApplicationCommandOptionChoice
+	// KindChannelMessageEdit 使用
+	MessageID string
+	ChannelID string
+}
+
+// 記錄所有回應的假 Responder
+type Recorder struct {
+	mu        sync.Mutex
+	responses []Response
+	notify    chan struct{}
+	// 可選：User() 查詢使用的假資料
+	Users map[string]*discordgo.User
+	// 可選：不為nil時所有回應方法都會回傳這個錯誤(仍會記錄)
+	Err error
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		notify: make(chan struct{}, 1),
+		Users:  make(map[string]*discordgo.User),
+	}
+}
+
+func (r *Recorder) record(resp Response) {
+	r.mu.Lock()
+	r.responses = append(r.responses, resp)
+	r.mu.Unlock()
+
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Recorder) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	rec := Response{
+		Kind:          KindRespond,
+		InteractionID: interaction.ID,
+		Type:          resp.Type,
+	}
+	if resp.Data != nil {
+		rec.Flags = resp.Data.Flags
+		rec.Content = resp.Data.Content
+		rec.Components = resp.Data.Components
+		rec.Embeds = resp.Data.Embeds
+		rec.Choices = resp.Data.Choices
+	}
+	r.record(rec)
+	return r.Err
+}
+
+func (r *Recorder) InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	rec := Response{
+		Kind:          KindResponseEdit,
+		InteractionID: interaction.ID,
+		Flags:         newresp.Flags,
+	}
+	if newresp.Content != nil {
+		rec.Content = *newresp.Content
+	}
+	if newresp.Components != nil {
+		rec.Components = *newresp.Components
+	}
+	if newresp.Embeds != nil {
+		rec.Embeds = *newresp.Embeds
+	}
+	r.record(rec)
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return &discordgo.Message{ID: interaction.ID}, nil
+}
+
+func (r *Recorder) ChannelMessageEditComplex(m *discordgo.MessageEdit, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	rec := Response{
+		Kind:      KindChannelMessageEdit,
+		MessageID: m.ID,
+		ChannelID: m.Channel,
+		Flags:     m.Flags,
+	}
+	if m.Content != nil {
+		rec.Content = *m.Content
+	}
+	if m.Components != nil {
+		rec.Components = *m.Components
+	}
+	if m.Embeds != nil {
+		rec.Embeds = *m.Embeds
+	}
+	r.record(rec)
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return &discordgo.Message{ID: m.ID, ChannelID: m.Channel}, nil
+}
+
+func (r *Recorder) User(userID string, options ...discordgo.RequestOption) (*discordgo.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.Users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+// 取得目前為止所有回應的複本
+func (r *Recorder) Responses() []Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Response, len(r.responses))
+	copy(out, r.responses)
+	return out
+}
+
+// 取得最後一筆回應，沒有任何回應時ok為false
+func (r *Recorder) Last() (resp Response, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.responses) == 0 {
+		return Response{}, false
+	}
+	return r.responses[len(r.responses)-1], true
+}
+
+// 清除所有紀錄
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.responses = nil
+}
+
+// 等待至少n筆回應(指令是在goroutine內執行)
+//
+// 逾時回傳false
+func (r *Recorder) Wait(n int, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		r.mu.Lock()
+		count := len(r.responses)
+		r.mu.Unlock()
+		if count >= n {
+			return true
+		}
+
+		select {
+		case <-r.notify:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
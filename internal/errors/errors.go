@@ -21,6 +21,14 @@ var (
 	ErrCIDGetParameterFailed = errors.New("cid: get parameter failed")
 	// cid behavior mismatch
 	ErrCIDBehaviorMismatch = errors.New("cid: behavior mismatch")
+	// cid signature mismatch (forged or tampered)
+	ErrCIDInvalidSignature = errors.New("cid: invalid signature")
+	// component clicked by someone other than the invoker
+	ErrCIDNotOwner = errors.New("cid: not the component owner")
+	// encoded cid exceeds the discord custom id length limit
+	ErrCIDTooLong = errors.New("cid: custom id too long")
+	// cache snapshots are enabled but no stable hmac key is configured
+	ErrCIDSecretRequired = errors.New("cid: CID_SECRET is required when CACHE_PERSIST_DIR is set")
 )
 
 // Utils error
@@ -29,6 +37,24 @@ var (
 	ErrTimeWrongFormat = errors.New("time: wrong format")
 	// date exceeds tomorrow error
 	ErrDateExceedsTomorrow = errors.New("time: date exceeds tomorrow")
+	// end date is earlier than start date
+	ErrEndDateBeforeStart = errors.New("time: end date before start date")
 	// target user has private game data enabled
 	ErrPrivateGameData = errors.New("user: private game data enabled")
+	// keyword matches more than one of the user's games
+	ErrAmbiguousKeyword = errors.New("user: keyword matches more than one game")
+	// uploaded import file is not a supported format
+	ErrImportUnsupportedFormat = errors.New("import: unsupported file format")
+	// uploaded import file exceeds the size limit
+	ErrImportFileTooLarge = errors.New("import: file too large")
+	// every random draw hit the user's blacklist
+	ErrRandomAllBlackListed = errors.New("random: every draw was blacklisted")
+)
+
+// Dispatch error
+var (
+	// worker pool or in-flight limit exceeded
+	ErrTooManyRequests = errors.New("dispatch: too many requests")
+	// command rate limit exceeded
+	ErrCommandCooldown = errors.New("dispatch: command cooldown")
 )
@@ -1,7 +1,10 @@
 package executor
 
 import (
+	"context"
+
 	"kurohelper/internal/cache"
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
 
 	"github.com/bwmarrin/discordgo"
@@ -16,7 +19,8 @@ import (
 //   - store: 存放列表資料的快取儲存
 //   - builder: 從快取資料建構訊息元件的函數。接收 (cacheValue, pageNumber, cacheID)，回傳列表第一頁的元件
 func BackToHome[T any](
-	s *discordgo.Session,
+	ctx context.Context,
+	s utils.Responder,
 	i *discordgo.InteractionCreate,
 	backToHomeCID *utils.BackToHomeCIDV2,
 	store *cache.CacheStoreV2[T],
@@ -27,17 +31,18 @@ func BackToHome[T any](
 		Type: discordgo.InteractionResponseDeferredMessageUpdate,
 	})
 
-	cidCacheValue, err := cache.CIDV2Store.Get(backToHomeCID.CacheID)
+	cidEntry, err := cache.CIDV2Store.Get(backToHomeCID.CacheID)
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
 
-	cacheValue, err := store.Get(cidCacheValue)
+	cacheValue, err := store.Get(cidEntry.Key)
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.InteractionRespondEditComplex)
 		return
 	}
+	tracing.FromContext(ctx).RecordCache(tracing.CacheHit)
 
 	components, err := builder(cacheValue, 1, backToHomeCID.CacheID)
 	if err != nil {
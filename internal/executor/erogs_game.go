@@ -0,0 +1,17 @@
+package executor
+
+import (
+	"context"
+	"strconv"
+
+	"kurohelper/internal/cache"
+	"kurohelper/internal/provider"
+	"kurohelperservice/provider/erogs"
+)
+
+// 以批評空間ID取得遊戲資料，結果與查詢遊戲共用 ErogsGameStore 快取
+func GetErogsGame(ctx context.Context, p provider.Erogs, id int) (*erogs.Game, error) {
+	return cache.ErogsGameStore.GetOrLoad(ctx, "e"+strconv.Itoa(id), func(ctx context.Context) (*erogs.Game, error) {
+		return p.SearchGameByID(ctx, id)
+	})
+}
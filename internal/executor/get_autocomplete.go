@@ -3,7 +3,9 @@ package executor
 import (
 	"errors"
 	"log/slog"
-	"strings"
+
+	"kurohelper/internal/autocomplete"
+	"kurohelper/internal/utils"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -12,15 +14,16 @@ var (
 	ErrFocusedOptionNotFound     = errors.New("focused option not found")
 	ErrAutocompleteQueryTooShort = errors.New("autocomplete query too short")
 	ErrSearchListNotInitialized  = errors.New("searchList has not been initialized")
-	ErrInvertedIndexNotFound     = errors.New("inverted index not found for first query rune")
 )
 
+// Discord Autocomplete 最多25個選項
+const autocompleteLimit = 20
+
 // Autocomplete 共用邏輯
 func GetAutocomplete(
-	s *discordgo.Session,
+	s utils.Responder,
 	i *discordgo.InteractionCreate,
-	searchList []string,
-	invertedIndex map[rune][]int) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	source *autocomplete.Source) ([]*discordgo.ApplicationCommandOptionChoice, error) {
 	data := i.ApplicationCommandData()
 
 	// 找出目前使用者正在打字的那個選項
@@ -38,38 +41,26 @@ func GetAutocomplete(
 
 	// 取得目前輸入的文字
 	userInput := focusedOption.StringValue()
-	userInput = strings.ToLower(userInput)
-	queryRunes := []rune(userInput)
-
-	if len(queryRunes) < 2 {
+	if len([]rune(autocomplete.Normalize(userInput))) < autocomplete.MinQueryLength {
 		return nil, ErrAutocompleteQueryTooShort
 	}
 
-	var choices []*discordgo.ApplicationCommandOptionChoice
-	if len(searchList) == 0 {
-		slog.Warn("searchList has not been initialized...")
+	if source.Len() == 0 {
+		// 只靠查詢結果學習的來源，還沒學到任何名稱時回傳空的選項
+		if !source.Loaded() {
+			return []*discordgo.ApplicationCommandOptionChoice{}, nil
+		}
+		slog.Warn("searchList has not been initialized...", "source", source.Name())
 		return nil, ErrSearchListNotInitialized
 	}
 
-	targetIndices, ok := invertedIndex[queryRunes[0]]
-	if !ok {
-		return nil, ErrInvertedIndexNotFound
-	}
-
-	limit := 20
-
-	for _, idx := range targetIndices {
-		name := searchList[idx]
-		if strings.Contains(strings.ToLower(name), userInput) {
-			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
-				Name:  name,
-				Value: name,
-			})
-		}
-
-		if len(choices) >= limit {
-			break
-		}
+	names := source.Search(userInput, autocompleteLimit)
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(names))
+	for _, name := range names {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  name,
+			Value: name,
+		})
 	}
 
 	return choices, nil
@@ -1,11 +1,13 @@
 package executor
 
 import (
+	"context"
+
 	"encoding/base64"
 	"fmt"
 	"kurohelper/internal/cache"
+	"kurohelper/internal/tracing"
 	"kurohelper/internal/utils"
-	"log/slog"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/uuid"
@@ -18,14 +20,15 @@ import (
 //   - i: 觸發搜尋的 interaction（需含 "keyword" 選項）
 //   - store: 存放查詢結果的快取儲存
 //   - logPrefix: 日誌前綴，如 "vndb查詢角色列表"
-//   - searcher: 執行實際查詢的函數，快取未命中時呼叫
+//   - searcher: 執行實際查詢的函數，快取未命中時呼叫(相同關鍵字同時只會執行一次)
 //   - builder: 從查詢結果建構訊息元件的函數。接收 (cacheValue, pageNumber, cacheID)，回傳列表第一頁的元件
 func SearchList[T any](
-	s *discordgo.Session,
+	ctx context.Context,
+	s utils.Responder,
 	i *discordgo.InteractionCreate,
 	store *cache.CacheStoreV2[T],
 	logPrefix string,
-	searcher func() (T, error),
+	searcher func(ctx context.Context) (T, error),
 	builder func(T, int, string) ([]discordgo.MessageComponent, error),
 ) {
 	keyword, err := utils.GetOptions(i, "keyword")
@@ -42,8 +45,10 @@ func SearchList[T any](
 	// 檢查快取是否存在
 	cacheValue, err := store.Get(cacheKey)
 	if err == nil {
+		tracing.FromContext(ctx).RecordCache(tracing.CacheHit)
+
 		// 存入CID與關鍵字的對應快取
-		cache.CIDV2Store.Set(idStr, cacheKey)
+		cache.CIDV2Store.Set(idStr, cache.CIDEntry{Key: cacheKey, OwnerID: utils.GetUserID(i)})
 
 		// 快取存在，直接使用，不需要延遲傳送
 		components, err := builder(cacheValue, 1, idStr)
@@ -61,19 +66,17 @@ func SearchList[T any](
 		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
 
-	slog.Info(fmt.Sprintf("%s: %s", logPrefix, keyword), "guildID", i.GuildID)
+	tracing.Logger(ctx).Info(fmt.Sprintf("%s: %s", logPrefix, keyword))
 
-	res, err := searcher()
+	// 同時有多人查詢相同關鍵字時只會呼叫一次searcher，結果會存入快取
+	res, err := store.GetOrLoad(ctx, cacheKey, searcher)
 	if err != nil {
 		utils.HandleErrorV2(err, s, i, utils.WebhookEditRespond)
 		return
 	}
 
-	// 將查詢結果存入快取
-	store.Set(cacheKey, res)
-
 	// 存入CID與關鍵字的對應快取
-	cache.CIDV2Store.Set(idStr, cacheKey)
+	cache.CIDV2Store.Set(idStr, cache.CIDEntry{Key: cacheKey, OwnerID: utils.GetUserID(i)})
 
 	components, err := builder(res, 1, idStr)
 	if err != nil {
@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"kurohelper/internal/cache"
+	"kurohelper/internal/provider"
+	"kurohelper/internal/tracing"
+	"kurohelperservice"
+)
+
+// 詳細資料畫面等待對應結果的時間上限，逾時就不顯示切換按鈕
+const erogsMapRenderTimeout = 3 * time.Second
+
+// 找出VNDB遊戲對應的批評空間遊戲，回傳 "e" + 遊戲ID，找不到時回傳空字串
+//
+// 用標題查詢批評空間，只有批評空間記錄的VNDB ID一致時才算對應成功，結果(包含找不到)會快取
+func ErogsKeyForVndb(ctx context.Context, p provider.Erogs, vnID string, titles ...string) string {
+	key, err := cache.VndbErogsMapStore.GetOrLoad(ctx, vnID, func(ctx context.Context) (string, error) {
+		keywords := make([]string, 0, len(titles))
+		for _, title := range titles {
+			if strings.TrimSpace(title) != "" {
+				keywords = append(keywords, title)
+			}
+		}
+		if len(keywords) == 0 {
+			return "", kurohelperservice.ErrSearchNoContent
+		}
+
+		game, err := p.SearchGameByKeyword(ctx, keywords)
+		if err != nil {
+			return "", err
+		}
+		if game == nil || game.VndbId != vnID {
+			return "", kurohelperservice.ErrSearchNoContent
+		}
+		return "e" + strconv.Itoa(game.ID), nil
+	})
+	if err != nil {
+		if !errors.Is(err, kurohelperservice.ErrSearchNoContent) {
+			tracing.Logger(ctx).Warn("erogs對應vndb遊戲失敗", "vnID", vnID, "error", err)
+		}
+		return ""
+	}
+	return key
+}
+
+// 只查快取的 ErogsKeyForVndb，沒有快取到對應時 ok 為 false
+func CachedErogsKeyForVndb(vnID string) (key string, ok bool) {
+	key, err := cache.VndbErogsMapStore.Get(vnID)
+	return key, err == nil && key != ""
+}
+
+// 畫面渲染用的 ErogsKeyForVndb，最多等待 erogsMapRenderTimeout
+//
+// 在deferred回應之後呼叫，快取命中時不會查詢上游；逾時的查詢不會寫入查無資料的快取，下次開啟會再試
+func ErogsKeyForVndbWithin(ctx context.Context, p provider.Erogs, vnID string, titles ...string) string {
+	ctx, cancel := context.WithTimeout(ctx, erogsMapRenderTimeout)
+	defer cancel()
+	return ErogsKeyForVndb(ctx, p, vnID, titles...)
+}
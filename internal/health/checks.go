@@ -0,0 +1,111 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+
+	"kurohelper/internal/autocomplete"
+	"kurohelperservice/provider/ymgal"
+)
+
+// discord預設約41秒送一次心跳，超過這個時間沒有收到ACK就視為斷線
+const gatewayAckTimeout = 2 * time.Minute
+
+// 資料庫連線
+func Database(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		if db == nil {
+			return errors.New("database has not been initialized")
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// Discord gateway 連線狀態
+func Gateway(s *discordgo.Session) Check {
+	return func(ctx context.Context) error {
+		s.RLock()
+		ready := s.DataReady
+		lastAck := s.LastHeartbeatAck
+		s.RUnlock()
+
+		if !ready {
+			return errors.New("gateway is not connected")
+		}
+		if since := time.Since(lastAck); since > gatewayAckTimeout {
+			return fmt.Errorf("no heartbeat ACK for %s", since.Truncate(time.Second))
+		}
+		return nil
+	}
+}
+
+// 批評空間Autocomplete索引是否已載入，只檢查有設定檔案的索引
+func ErogsAutocomplete(game, brand, music bool) Check {
+	return func(ctx context.Context) error {
+		var missing []error
+		if game && autocomplete.Games.Len() == 0 {
+			missing = append(missing, errors.New("game autocomplete index is empty"))
+		}
+		if brand && autocomplete.Brands.Len() == 0 {
+			missing = append(missing, errors.New("brand autocomplete index is empty"))
+		}
+		if music && autocomplete.Musics.Len() == 0 {
+			missing = append(missing, errors.New("music autocomplete index is empty"))
+		}
+		return errors.Join(missing...)
+	}
+}
+
+// 月幕token是否有效
+//
+// 重新取得token需要打上游，所以結果會保留 interval，避免每次探測都發出請求。
+// ymgal.GetToken 不接受ctx，因此在背景執行，探測逾時就先回傳ctx的錯誤，
+// 同一時間只會有一個請求，結束後的結果留給下一次探測使用
+func YmgalToken(interval time.Duration) Check {
+	var (
+		mu        sync.Mutex
+		checkedAt time.Time
+		lastErr   error
+		inflight  chan struct{}
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		if !checkedAt.IsZero() && time.Since(checkedAt) < interval {
+			err := lastErr
+			mu.Unlock()
+			return err
+		}
+		done := inflight
+		if done == nil {
+			done = make(chan struct{})
+			inflight = done
+			go func() {
+				err := ymgal.GetToken()
+				mu.Lock()
+				lastErr, checkedAt, inflight = err, time.Now(), nil
+				mu.Unlock()
+				close(done)
+			}()
+		}
+		mu.Unlock()
+
+		select {
+		case <-done:
+			mu.Lock()
+			defer mu.Unlock()
+			return lastErr
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// health 存活(/healthz)與就緒(/readyz)檢查
+//
+// 啟動時用 Register 註冊依賴檢查，/readyz 每次請求都會重新執行所有檢查，
+// 任一項失敗就回傳 503，方便部署環境在依賴異常時暫停導流
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 單項依賴檢查，回傳nil代表正常
+type Check func(ctx context.Context) error
+
+// 每項檢查的時間上限
+const checkTimeout = 3 * time.Second
+
+var (
+	mu     sync.Mutex
+	checks = map[string]Check{"lifecycle": checkLifecycle}
+	// 上一次的檢查結果，狀態改變時輸出log
+	lastOK = make(map[string]bool)
+)
+
+var (
+	started          atomic.Bool
+	stopping         atomic.Bool
+	errNotStartedYet = errors.New("initialization in progress")
+	errShuttingDown  = errors.New("shutting down")
+)
+
+// 初始化完成後呼叫，在這之前 /readyz 一律回傳503
+func MarkStarted() {
+	started.Store(true)
+}
+
+// 開始關閉時呼叫，之後 /readyz 一律回傳503
+func MarkStopping() {
+	stopping.Store(true)
+}
+
+func checkLifecycle(ctx context.Context) error {
+	switch {
+	case stopping.Load():
+		return errShuttingDown
+	case !started.Load():
+		return errNotStartedYet
+	}
+	return nil
+}
+
+// 註冊依賴檢查，名稱重複時覆蓋
+func Register(name string, check Check) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	checks[name] = check
+}
+
+// 單項檢查結果
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// /readyz 回傳內容
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// 同時執行所有檢查
+func Run(ctx context.Context) Report {
+	mu.Lock()
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	list := make([]Check, len(names))
+	for idx, name := range names {
+		list[idx] = checks[name]
+	}
+	mu.Unlock()
+
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for idx, check := range list {
+		wg.Go(func() {
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			errs[idx] = check(checkCtx)
+		})
+	}
+	wg.Wait()
+
+	report := Report{Status: "ok", Checks: make(map[string]CheckResult, len(names))}
+	for idx, name := range names {
+		result := CheckResult{Status: "ok"}
+		if errs[idx] != nil {
+			result = CheckResult{Status: "fail", Error: errs[idx].Error()}
+			report.Status = "unavailable"
+		}
+		report.Checks[name] = result
+		logTransition(name, errs[idx])
+	}
+	return report
+}
+
+func logTransition(name string, err error) {
+	mu.Lock()
+	prev, seen := lastOK[name]
+	lastOK[name] = err == nil
+	mu.Unlock()
+
+	switch {
+	case err != nil && (!seen || prev):
+		slog.Warn("health: "+name+" is unhealthy", "error", err.Error())
+	case err == nil && seen && !prev:
+		slog.Info("health: " + name + " recovered")
+	}
+}
+
+// 存活檢查，程式還能回應HTTP就代表存活
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("ok\n"))
+	})
+}
+
+// 就緒檢查，所有依賴都正常時回傳200，否則回傳503
+func ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := Run(r.Context())
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if report.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
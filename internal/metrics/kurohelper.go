@@ -0,0 +1,41 @@
+package metrics
+
+// 專案共用的指標
+//
+// 快取相關的指標由 cache 套件自行註冊，心跳延遲在建立discord連線後由main註冊
+var (
+	// 依照指令與Interaction種類(command/autocomplete/component)統計
+	Interactions = NewCounterVec(
+		"kurohelper_interactions_total",
+		"Number of handled interactions by command and type.",
+		"command", "type",
+	)
+	InteractionDuration = NewHistogramVec(
+		"kurohelper_interaction_duration_seconds",
+		"End-to-end interaction handling latency by command and type.",
+		DefaultBuckets,
+		"command", "type",
+	)
+
+	// utils.HandleError/HandleErrorV2 處理的錯誤分類(tracing.ErrorClass)
+	Errors = NewCounterVec(
+		"kurohelper_errors_total",
+		"Number of errors reported to users by error class.",
+		"class",
+	)
+
+	// 上游查詢耗時，result 為 ok 或錯誤分類
+	UpstreamDuration = NewHistogramVec(
+		"kurohelper_upstream_request_duration_seconds",
+		"Upstream provider call latency by backend, method and result.",
+		DefaultBuckets,
+		"backend", "method", "result",
+	)
+
+	// worker pool拒絕執行的次數，reason 為 user、guild、queue_full、cooldown(指令限流) 或 not_owner(操作別人的元件)
+	DispatchRejected = NewCounterVec(
+		"kurohelper_dispatch_rejected_total",
+		"Number of interactions rejected by the dispatcher by reason.",
+		"reason",
+	)
+)
@@ -0,0 +1,270 @@
+// metrics 輸出Prometheus文字格式(text/plain; version=0.0.4)的監控指標
+//
+// 只實作專案用到的 counter、histogram 與抓取時才計算的 collector，
+// 透過 Handler() 掛到HTTP服務後即可讓Prometheus抓取，也可以直接用 curl 查看
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 預設的histogram區間(秒)
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+type collector interface {
+	metricName() string
+	write(w *bufio.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, r := range registry {
+		if r.metricName() == c.metricName() {
+			panic("metrics: duplicate metric name " + c.metricName())
+		}
+	}
+	registry = append(registry, c)
+}
+
+// 輸出所有指標的HTTP handler
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registryMu.Lock()
+		list := slices.Clone(registry)
+		registryMu.Unlock()
+
+		slices.SortFunc(list, func(a, b collector) int {
+			return strings.Compare(a.metricName(), b.metricName())
+		})
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		for _, c := range list {
+			c.write(bw)
+		}
+		bw.Flush()
+	})
+}
+
+// 依照標籤區分的計數器
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*sample
+}
+
+type sample struct {
+	labelValues []string
+	value       float64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]*sample)}
+	register(c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	key := labelKey(c.labels, labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.values[key]
+	if !ok {
+		s = &sample{labelValues: slices.Clone(labelValues)}
+		c.values[key] = s
+	}
+	s.value += v
+}
+
+func (c *CounterVec) metricName() string { return c.name }
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		s := c.values[key]
+		writeSample(w, c.name, c.labels, s.labelValues, "", "", s.value)
+	}
+}
+
+// 依照標籤區分的histogram
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramSample
+}
+
+type histogramSample struct {
+	labelValues []string
+	// 每個區間的數量(不累加)，最後一格是 +Inf
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: slices.Sorted(slices.Values(buckets)),
+		values:  make(map[string]*histogramSample),
+	}
+	register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := labelKey(h.labels, labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.values[key]
+	if !ok {
+		s = &histogramSample{labelValues: slices.Clone(labelValues), counts: make([]uint64, len(h.buckets)+1)}
+		h.values[key] = s
+	}
+	idx, _ := slices.BinarySearch(h.buckets, v)
+	s.counts[idx]++
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) metricName() string { return h.name }
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		s := h.values[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			writeSample(w, h.name+"_bucket", h.labels, s.labelValues, "le", formatFloat(upper), float64(cumulative))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(w, h.name+"_sum", h.labels, s.labelValues, "", "", s.sum)
+		writeSample(w, h.name+"_count", h.labels, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// 抓取時才計算數值的指標(例如快取筆數、心跳延遲)
+type CollectorFunc struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	collect func(emit func(v float64, labelValues ...string))
+}
+
+// 建立抓取時才計算的指標，typ 為 counter 或 gauge
+func NewCollectorFunc(name, help, typ string, labels []string, collect func(emit func(v float64, labelValues ...string))) *CollectorFunc {
+	c := &CollectorFunc{name: name, help: help, typ: typ, labels: labels, collect: collect}
+	register(c)
+	return c
+}
+
+// 沒有標籤的gauge
+func NewGaugeFunc(name, help string, fn func() float64) *CollectorFunc {
+	return NewCollectorFunc(name, help, "gauge", nil, func(emit func(float64, ...string)) {
+		emit(fn())
+	})
+}
+
+func (c *CollectorFunc) metricName() string { return c.name }
+
+func (c *CollectorFunc) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, c.typ)
+	c.collect(func(v float64, labelValues ...string) {
+		writeSample(w, c.name, c.labels, labelValues, "", "", v)
+	})
+}
+
+func labelKey(labels, labelValues []string) string {
+	if len(labels) != len(labelValues) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// extraName 不為空時額外輸出一個標籤(histogram的le)
+func writeSample(w *bufio.Writer, name string, labels, labelValues []string, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, l, labelEscaper.Replace(labelValues[i]))
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
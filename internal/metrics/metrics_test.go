@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// 一行樣本：名稱{標籤} 數值
+var sampleLine = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*(\{([a-zA-Z_][a-zA-Z0-9_]*="(\\.|[^"\\])*",?)*\})? \S+$`)
+
+func TestHandlerScrape(t *testing.T) {
+	requests := NewCounterVec("test_scrape_requests_total", "Requests by method.", "method")
+	duration := NewHistogramVec("test_scrape_duration_seconds", "Request latency.", []float64{1, 0.1}, "path")
+
+	requests.Inc("get")
+	requests.Inc("get")
+	requests.Add(3, `say "hi"`)
+	duration.Observe(0.25, "/a")
+	duration.Observe(0.5, "/a")
+	duration.Observe(2, "/a")
+
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("content type = %q", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(body)
+
+	// 每一行都要是註解或合法的樣本
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if strings.HasPrefix(line, "# HELP ") || strings.HasPrefix(line, "# TYPE ") {
+			continue
+		}
+		if !sampleLine.MatchString(line) {
+			t.Errorf("invalid exposition line: %q", line)
+		}
+	}
+
+	// 同一個指標的HELP、TYPE與樣本要連續出現
+	wantBlocks := []string{
+		"# HELP test_scrape_requests_total Requests by method.\n" +
+			"# TYPE test_scrape_requests_total counter\n" +
+			"test_scrape_requests_total{method=\"get\"} 2\n" +
+			"test_scrape_requests_total{method=\"say \\\"hi\\\"\"} 3\n",
+		"# HELP test_scrape_duration_seconds Request latency.\n" +
+			"# TYPE test_scrape_duration_seconds histogram\n" +
+			"test_scrape_duration_seconds_bucket{path=\"/a\",le=\"0.1\"} 0\n" +
+			"test_scrape_duration_seconds_bucket{path=\"/a\",le=\"1\"} 2\n" +
+			"test_scrape_duration_seconds_bucket{path=\"/a\",le=\"+Inf\"} 3\n" +
+			"test_scrape_duration_seconds_sum{path=\"/a\"} 2.75\n" +
+			"test_scrape_duration_seconds_count{path=\"/a\"} 3\n",
+	}
+	for _, want := range wantBlocks {
+		if !strings.Contains(text, want) {
+			t.Errorf("scrape output missing block:\n%s\ngot:\n%s", want, text)
+		}
+	}
+
+	// 專案共用的指標在沒有資料時也要輸出HELP與TYPE
+	if !strings.Contains(text, "# TYPE kurohelper_interactions_total counter\n") {
+		t.Error("missing kurohelper_interactions_total")
+	}
+}
@@ -0,0 +1,284 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"kurohelperservice"
+	"kurohelperservice/provider/bangumi"
+	"kurohelperservice/provider/erogs"
+	"kurohelperservice/provider/vndb"
+	"kurohelperservice/provider/ymgal"
+)
+
+// 萬用key，找不到對應關鍵字時會使用這筆資料
+const fixtureWildcard = "*"
+
+// 以關鍵字或ID為key的假資料
+//
+// key一律轉小寫後比對
+type fixtureMap[T any] map[string]T
+
+// 依序嘗試每個key，全部找不到時回傳 ErrSearchNoContent
+func (m fixtureMap[T]) lookup(keys ...string) (T, error) {
+	for _, k := range keys {
+		if v, ok := m[strings.ToLower(strings.TrimSpace(k))]; ok {
+			return v, nil
+		}
+	}
+	if v, ok := m[fixtureWildcard]; ok {
+		return v, nil
+	}
+	var zero T
+	return zero, kurohelperservice.ErrSearchNoContent
+}
+
+func (m fixtureMap[T]) normalize() fixtureMap[T] {
+	out := make(fixtureMap[T], len(m))
+	for k, v := range m {
+		out[strings.ToLower(strings.TrimSpace(k))] = v
+	}
+	return out
+}
+
+func lookupPtr[T any](m fixtureMap[T], keys ...string) (*T, error) {
+	v, err := m.lookup(keys...)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func pickRandom[T any](list []T) (*T, error) {
+	if len(list) == 0 {
+		return nil, kurohelperservice.ErrSearchNoContent
+	}
+	return &list[rand.IntN(len(list))], nil
+}
+
+// erogs.json
+type FixtureErogs struct {
+	GameList      fixtureMap[[]erogs.GameList]    `json:"game_list"`
+	Game          fixtureMap[erogs.Game]          `json:"game"`
+	GameByKeyword fixtureMap[erogs.Game]          `json:"game_by_keyword"`
+	Brand         fixtureMap[erogs.Brand]         `json:"brand"`
+	CreatorList   fixtureMap[[]erogs.CreatorList] `json:"creator_list"`
+	Creator       fixtureMap[erogs.Creator]       `json:"creator"`
+	MusicList     fixtureMap[[]erogs.MusicList]   `json:"music_list"`
+	Music         fixtureMap[erogs.Music]         `json:"music"`
+	SingerList    fixtureMap[[]erogs.CreatorList] `json:"singer_list"`
+	Singer        fixtureMap[erogs.Singer]        `json:"singer"`
+}
+
+func (f *FixtureErogs) normalize() {
+	f.GameList = f.GameList.normalize()
+	f.Game = f.Game.normalize()
+	f.GameByKeyword = f.GameByKeyword.normalize()
+	f.Brand = f.Brand.normalize()
+	f.CreatorList = f.CreatorList.normalize()
+	f.Creator = f.Creator.normalize()
+	f.MusicList = f.MusicList.normalize()
+	f.Music = f.Music.normalize()
+	f.SingerList = f.SingerList.normalize()
+	f.Singer = f.Singer.normalize()
+}
+
+func (f *FixtureErogs) SearchGameListByKeyword(_ context.Context, keywords []string) ([]erogs.GameList, error) {
+	return f.GameList.lookup(keywords...)
+}
+
+func (f *FixtureErogs) SearchGameByID(_ context.Context, id int) (*erogs.Game, error) {
+	return lookupPtr(f.Game, strconv.Itoa(id))
+}
+
+func (f *FixtureErogs) SearchGameByKeyword(_ context.Context, keywords []string) (*erogs.Game, error) {
+	return lookupPtr(f.GameByKeyword, keywords...)
+}
+
+func (f *FixtureErogs) SearchBrandByKeyword(_ context.Context, keywords []string) (*erogs.Brand, error) {
+	return lookupPtr(f.Brand, keywords...)
+}
+
+func (f *FixtureErogs) SearchCreatorListByKeyword(_ context.Context, keywords []string) ([]erogs.CreatorList, error) {
+	return f.CreatorList.lookup(keywords...)
+}
+
+func (f *FixtureErogs) SearchCreatorByID(_ context.Context, id int) (*erogs.Creator, error) {
+	return lookupPtr(f.Creator, strconv.Itoa(id))
+}
+
+func (f *FixtureErogs) SearchMusicListByKeyword(_ context.Context, keywords []string) ([]erogs.MusicList, error) {
+	return f.MusicList.lookup(keywords...)
+}
+
+func (f *FixtureErogs) SearchMusicByID(_ context.Context, id int) (*erogs.Music, error) {
+	return lookupPtr(f.Music, strconv.Itoa(id))
+}
+
+func (f *FixtureErogs) SearchSingerListByKeyword(_ context.Context, keywords []string) ([]erogs.CreatorList, error) {
+	return f.SingerList.lookup(keywords...)
+}
+
+func (f *FixtureErogs) SearchSingerByKeyword(_ context.Context, id int) (*erogs.Singer, error) {
+	return lookupPtr(f.Singer, strconv.Itoa(id))
+}
+
+// vndb.json
+type FixtureVndb struct {
+	VnList          fixtureMap[[]vndb.GetVnIDUseListResponse]               `json:"vn_list"`
+	Vn              fixtureMap[vndb.BasicResponse[vndb.GetVnUseIDResponse]] `json:"vn"`
+	VnByFuzzy       fixtureMap[vndb.BasicResponse[vndb.GetVnUseIDResponse]] `json:"vn_by_fuzzy"`
+	Producer        fixtureMap[vndb.ProducerSearchResponse]                 `json:"producer"`
+	CharacterList   fixtureMap[[]vndb.CharacterSearchResponse]              `json:"character_list"`
+	Character       fixtureMap[vndb.CharacterSearchResponse]                `json:"character"`
+	RandomVn        []vndb.BasicResponse[vndb.GetVnUseIDResponse]           `json:"random_vn"`
+	RandomCharacter fixtureMap[[]vndb.CharacterSearchResponse]              `json:"random_character"`
+	UList           fixtureMap[[]VndbUListItem]                             `json:"ulist"`
+}
+
+func (f *FixtureVndb) normalize() {
+	f.VnList = f.VnList.normalize()
+	f.Vn = f.Vn.normalize()
+	f.VnByFuzzy = f.VnByFuzzy.normalize()
+	f.Producer = f.Producer.normalize()
+	f.CharacterList = f.CharacterList.normalize()
+	f.Character = f.Character.normalize()
+	f.RandomCharacter = f.RandomCharacter.normalize()
+	f.UList = f.UList.normalize()
+}
+
+func (f *FixtureVndb) GetVnID(_ context.Context, keyword string) ([]vndb.GetVnIDUseListResponse, error) {
+	return f.VnList.lookup(keyword)
+}
+
+func (f *FixtureVndb) GetVNByID(_ context.Context, id string) (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error) {
+	return lookupPtr(f.Vn, id)
+}
+
+func (f *FixtureVndb) GetVNByFuzzy(_ context.Context, keyword string) (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error) {
+	return lookupPtr(f.VnByFuzzy, keyword)
+}
+
+func (f *FixtureVndb) GetProducerByFuzzy(_ context.Context, keyword string, companyType string) (*vndb.ProducerSearchResponse, error) {
+	return lookupPtr(f.Producer, keyword)
+}
+
+func (f *FixtureVndb) GetCharacterListByFuzzy(_ context.Context, keyword string) ([]vndb.CharacterSearchResponse, error) {
+	return f.CharacterList.lookup(keyword)
+}
+
+func (f *FixtureVndb) GetCharacterByID(_ context.Context, id string) (*vndb.CharacterSearchResponse, error) {
+	return lookupPtr(f.Character, id)
+}
+
+func (f *FixtureVndb) GetRandomVN(_ context.Context) (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error) {
+	return pickRandom(f.RandomVn)
+}
+
+// role為空字串時使用萬用key
+func (f *FixtureVndb) GetRandomCharacter(_ context.Context, role string) (*vndb.CharacterSearchResponse, error) {
+	list, err := f.RandomCharacter.lookup(role)
+	if err != nil {
+		return nil, err
+	}
+	return pickRandom(list)
+}
+
+func (f *FixtureVndb) GetUserList(_ context.Context, user string) ([]VndbUListItem, error) {
+	return f.UList.lookup(user)
+}
+
+// bangumi.json
+type FixtureBangumi struct {
+	Character fixtureMap[bangumi.Character] `json:"character"`
+}
+
+func (f *FixtureBangumi) normalize() {
+	f.Character = f.Character.normalize()
+}
+
+func (f *FixtureBangumi) GetCharacterByFuzzy(_ context.Context, keyword string) (*bangumi.Character, error) {
+	return lookupPtr(f.Character, keyword)
+}
+
+// ymgal.json
+type FixtureYmgal struct {
+	Game fixtureMap[ymgal.SearchGameResp] `json:"game"`
+	// 每次隨機挑一組回傳
+	RandomGame [][]ymgal.RandomGameResp `json:"random_game"`
+}
+
+func (f *FixtureYmgal) normalize() {
+	f.Game = f.Game.normalize()
+}
+
+func (f *FixtureYmgal) SearchGame(_ context.Context, keyword string) (*ymgal.SearchGameResp, error) {
+	return lookupPtr(f.Game, keyword)
+}
+
+func (f *FixtureYmgal) GetRandomGame(_ context.Context) ([]ymgal.RandomGameResp, error) {
+	res, err := pickRandom(f.RandomGame)
+	if err != nil {
+		return nil, err
+	}
+	return *res, nil
+}
+
+// 從資料夾讀取JSON假資料(離線模式使用)
+//
+// 資料夾內需要有 erogs.json、vndb.json、bangumi.json、ymgal.json，
+// 不存在的檔案視為沒有任何資料(所有查詢都回傳 ErrSearchNoContent)
+func LoadFixtures(dir string) (*Providers, error) {
+	e := &FixtureErogs{}
+	v := &FixtureVndb{}
+	b := &FixtureBangumi{}
+	y := &FixtureYmgal{}
+
+	files := []struct {
+		name string
+		dst  any
+	}{
+		{"erogs.json", e},
+		{"vndb.json", v},
+		{"bangumi.json", b},
+		{"ymgal.json", y},
+	}
+	for _, f := range files {
+		if err := loadFixtureFile(filepath.Join(dir, f.name), f.dst); err != nil {
+			return nil, err
+		}
+	}
+
+	e.normalize()
+	v.normalize()
+	b.normalize()
+	y.normalize()
+
+	return &Providers{
+		Erogs:   e,
+		Vndb:    v,
+		Bangumi: b,
+		Ymgal:   y,
+	}, nil
+}
+
+func loadFixtureFile(path string, dst any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("provider: parse fixture %s: %w", path, err)
+	}
+	return nil
+}
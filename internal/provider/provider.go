@@ -0,0 +1,61 @@
+// provider 把外部資料來源(erogs、vndb、bangumi、ymgal)包成介面
+//
+// 指令只依賴這裡的介面，正式環境使用 Upstream() 直接呼叫 kurohelperservice，
+// 離線模式則改用 LoadFixtures() 讀取本地JSON，不需要連線到任何上游服務
+package provider
+
+import (
+	"context"
+
+	"kurohelperservice/provider/bangumi"
+	"kurohelperservice/provider/erogs"
+	"kurohelperservice/provider/vndb"
+	"kurohelperservice/provider/ymgal"
+)
+
+// 批評空間
+type Erogs interface {
+	SearchGameListByKeyword(ctx context.Context, keywords []string) ([]erogs.GameList, error)
+	SearchGameByID(ctx context.Context, id int) (*erogs.Game, error)
+	SearchGameByKeyword(ctx context.Context, keywords []string) (*erogs.Game, error)
+	SearchBrandByKeyword(ctx context.Context, keywords []string) (*erogs.Brand, error)
+	SearchCreatorListByKeyword(ctx context.Context, keywords []string) ([]erogs.CreatorList, error)
+	SearchCreatorByID(ctx context.Context, id int) (*erogs.Creator, error)
+	SearchMusicListByKeyword(ctx context.Context, keywords []string) ([]erogs.MusicList, error)
+	SearchMusicByID(ctx context.Context, id int) (*erogs.Music, error)
+	SearchSingerListByKeyword(ctx context.Context, keywords []string) ([]erogs.CreatorList, error)
+	SearchSingerByKeyword(ctx context.Context, id int) (*erogs.Singer, error)
+}
+
+// VNDB
+type Vndb interface {
+	GetVnID(ctx context.Context, keyword string) ([]vndb.GetVnIDUseListResponse, error)
+	GetVNByID(ctx context.Context, id string) (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error)
+	GetVNByFuzzy(ctx context.Context, keyword string) (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error)
+	GetProducerByFuzzy(ctx context.Context, keyword string, companyType string) (*vndb.ProducerSearchResponse, error)
+	GetCharacterListByFuzzy(ctx context.Context, keyword string) ([]vndb.CharacterSearchResponse, error)
+	GetCharacterByID(ctx context.Context, id string) (*vndb.CharacterSearchResponse, error)
+	GetRandomVN(ctx context.Context) (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error)
+	GetRandomCharacter(ctx context.Context, role string) (*vndb.CharacterSearchResponse, error)
+	// user可以是ID(u+數字)或使用者名稱
+	GetUserList(ctx context.Context, user string) ([]VndbUListItem, error)
+}
+
+// Bangumi
+type Bangumi interface {
+	GetCharacterByFuzzy(ctx context.Context, keyword string) (*bangumi.Character, error)
+}
+
+// 月幕
+type Ymgal interface {
+	SearchGame(ctx context.Context, keyword string) (*ymgal.SearchGameResp, error)
+	GetRandomGame(ctx context.Context) ([]ymgal.RandomGameResp, error)
+}
+
+// 指令使用的所有資料來源
+type Providers struct {
+	Erogs   Erogs
+	Vndb    Vndb
+	Bangumi Bangumi
+	Ymgal   Ymgal
+}
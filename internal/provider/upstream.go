@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"kurohelper/internal/metrics"
+	"kurohelper/internal/tracing"
+	"kurohelperservice/provider/bangumi"
+	"kurohelperservice/provider/erogs"
+	"kurohelperservice/provider/vndb"
+	"kurohelperservice/provider/ymgal"
+)
+
+// 直接呼叫 kurohelperservice 的實作(正式環境使用)
+func Upstream() *Providers {
+	return &Providers{
+		Erogs:   upstreamErogs{},
+		Vndb:    upstreamVndb{},
+		Bangumi: upstreamBangumi{},
+		Ymgal:   upstreamYmgal{},
+	}
+}
+
+// 呼叫上游並把耗時記錄到Interaction的Trace與監控指標
+//
+// 上游函式不支援ctx，只能在呼叫前檢查Interaction是否已經取消或逾時
+func observe[T any](ctx context.Context, backend, method string, fn func() (T, error)) (T, error) {
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	start := time.Now()
+	v, err := fn()
+	elapsed := time.Since(start)
+
+	result := "ok"
+	if err != nil {
+		result = tracing.ErrorClass(err)
+	}
+	metrics.UpstreamDuration.Observe(elapsed.Seconds(), backend, method, result)
+
+	tracing.FromContext(ctx).RecordUpstream(elapsed)
+	logger := tracing.Logger(ctx).With("provider", backend+"."+method, "latency", elapsed)
+	if err != nil {
+		logger.Debug("upstream call failed", "error", err.Error())
+	} else {
+		logger.Debug("upstream call")
+	}
+	return v, err
+}
+
+type upstreamErogs struct{}
+
+func (upstreamErogs) SearchGameListByKeyword(ctx context.Context, keywords []string) ([]erogs.GameList, error) {
+	return observe(ctx, "erogs", "SearchGameListByKeyword", func() ([]erogs.GameList, error) {
+		return erogs.SearchGameListByKeyword(keywords)
+	})
+}
+
+func (upstreamErogs) SearchGameByID(ctx context.Context, id int) (*erogs.Game, error) {
+	return observe(ctx, "erogs", "SearchGameByID", func() (*erogs.Game, error) {
+		return erogs.SearchGameByID(id)
+	})
+}
+
+func (upstreamErogs) SearchGameByKeyword(ctx context.Context, keywords []string) (*erogs.Game, error) {
+	return observe(ctx, "erogs", "SearchGameByKeyword", func() (*erogs.Game, error) {
+		return erogs.SearchGameByKeyword(keywords)
+	})
+}
+
+func (upstreamErogs) SearchBrandByKeyword(ctx context.Context, keywords []string) (*erogs.Brand, error) {
+	return observe(ctx, "erogs", "SearchBrandByKeyword", func() (*erogs.Brand, error) {
+		return erogs.SearchBrandByKeyword(keywords)
+	})
+}
+
+func (upstreamErogs) SearchCreatorListByKeyword(ctx context.Context, keywords []string) ([]erogs.CreatorList, error) {
+	return observe(ctx, "erogs", "SearchCreatorListByKeyword", func() ([]erogs.CreatorList, error) {
+		return erogs.SearchCreatorListByKeyword(keywords)
+	})
+}
+
+func (upstreamErogs) SearchCreatorByID(ctx context.Context, id int) (*erogs.Creator, error) {
+	return observe(ctx, "erogs", "SearchCreatorByID", func() (*erogs.Creator, error) {
+		return erogs.SearchCreatorByID(id)
+	})
+}
+
+func (upstreamErogs) SearchMusicListByKeyword(ctx context.Context, keywords []string) ([]erogs.MusicList, error) {
+	return observe(ctx, "erogs", "SearchMusicListByKeyword", func() ([]erogs.MusicList, error) {
+		return erogs.SearchMusicListByKeyword(keywords)
+	})
+}
+
+func (upstreamErogs) SearchMusicByID(ctx context.Context, id int) (*erogs.Music, error) {
+	return observe(ctx, "erogs", "SearchMusicByID", func() (*erogs.Music, error) {
+		return erogs.SearchMusicByID(id)
+	})
+}
+
+func (upstreamErogs) SearchSingerListByKeyword(ctx context.Context, keywords []string) ([]erogs.CreatorList, error) {
+	return observe(ctx, "erogs", "SearchSingerListByKeyword", func() ([]erogs.CreatorList, error) {
+		return erogs.SearchSingerListByKeyword(keywords)
+	})
+}
+
+func (upstreamErogs) SearchSingerByKeyword(ctx context.Context, id int) (*erogs.Singer, error) {
+	return observe(ctx, "erogs", "SearchSingerByKeyword", func() (*erogs.Singer, error) {
+		return erogs.SearchSingerByKeyword(id)
+	})
+}
+
+type upstreamVndb struct{}
+
+func (upstreamVndb) GetVnID(ctx context.Context, keyword string) ([]vndb.GetVnIDUseListResponse, error) {
+	return observe(ctx, "vndb", "GetVnID", func() ([]vndb.GetVnIDUseListResponse, error) {
+		return vndb.GetVnID(keyword)
+	})
+}
+
+func (upstreamVndb) GetVNByID(ctx context.Context, id string) (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error) {
+	return observe(ctx, "vndb", "GetVNByID", func() (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error) {
+		return vndb.GetVNByID(id)
+	})
+}
+
+func (upstreamVndb) GetVNByFuzzy(ctx context.Context, keyword string) (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error) {
+	return observe(ctx, "vndb", "GetVNByFuzzy", func() (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error) {
+		return vndb.GetVNByFuzzy(keyword)
+	})
+}
+
+func (upstreamVndb) GetProducerByFuzzy(ctx context.Context, keyword string, companyType string) (*vndb.ProducerSearchResponse, error) {
+	return observe(ctx, "vndb", "GetProducerByFuzzy", func() (*vndb.ProducerSearchResponse, error) {
+		return vndb.GetProducerByFuzzy(keyword, companyType)
+	})
+}
+
+func (upstreamVndb) GetCharacterListByFuzzy(ctx context.Context, keyword string) ([]vndb.CharacterSearchResponse, error) {
+	return observe(ctx, "vndb", "GetCharacterListByFuzzy", func() ([]vndb.CharacterSearchResponse, error) {
+		return vndb.GetCharacterListByFuzzy(keyword)
+	})
+}
+
+func (upstreamVndb) GetCharacterByID(ctx context.Context, id string) (*vndb.CharacterSearchResponse, error) {
+	return observe(ctx, "vndb", "GetCharacterByID", func() (*vndb.CharacterSearchResponse, error) {
+		return vndb.GetCharacterByID(id)
+	})
+}
+
+func (upstreamVndb) GetRandomVN(ctx context.Context) (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error) {
+	return observe(ctx, "vndb", "GetRandomVN", func() (*vndb.BasicResponse[vndb.GetVnUseIDResponse], error) {
+		return vndb.GetRandomVN()
+	})
+}
+
+func (upstreamVndb) GetRandomCharacter(ctx context.Context, role string) (*vndb.CharacterSearchResponse, error) {
+	return observe(ctx, "vndb", "GetRandomCharacter", func() (*vndb.CharacterSearchResponse, error) {
+		return vndb.GetRandomCharacter(role)
+	})
+}
+
+type upstreamBangumi struct{}
+
+func (upstreamBangumi) GetCharacterByFuzzy(ctx context.Context, keyword string) (*bangumi.Character, error) {
+	return observe(ctx, "bangumi", "GetCharacterByFuzzy", func() (*bangumi.Character, error) {
+		return bangumi.GetCharacterByFuzzy(keyword)
+	})
+}
+
+type upstreamYmgal struct{}
+
+func (upstreamYmgal) SearchGame(ctx context.Context, keyword string) (*ymgal.SearchGameResp, error) {
+	return observe(ctx, "ymgal", "SearchGame", func() (*ymgal.SearchGameResp, error) {
+		return ymgal.SearchGame(keyword)
+	})
+}
+
+func (upstreamYmgal) GetRandomGame(ctx context.Context) ([]ymgal.RandomGameResp, error) {
+	return observe(ctx, "ymgal", "GetRandomGame", func() ([]ymgal.RandomGameResp, error) {
+		return ymgal.GetRandomGame()
+	})
+}
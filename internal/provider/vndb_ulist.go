@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"kurohelperservice"
+)
+
+// VNDB使用者清單(ulist)的一筆資料，欄位與 Kana API 的回傳格式相同
+type VndbUListItem struct {
+	ID string `json:"id"`
+	// 10~100，沒有投票時為0
+	Vote     int    `json:"vote"`
+	Started  string `json:"started"`
+	Finished string `json:"finished"`
+	Notes    string `json:"notes"`
+	Labels   []struct {
+		ID    int    `json:"id"`
+		Label string `json:"label"`
+	} `json:"labels"`
+	VN struct {
+		Title    string `json:"title"`
+		Alttitle string `json:"alttitle"`
+	} `json:"vn"`
+}
+
+const (
+	// VNDB_ENDPOINT 沒有設定時使用的API位置
+	defaultVndbEndpoint = "https://api.vndb.org/kana"
+	// 每頁筆數(API上限100)
+	vndbUListPageSize = 100
+	// 最多讀取幾頁，避免清單太大時佔用太久
+	vndbUListMaxPages = 20
+)
+
+var vndbUserIDRegex = regexp.MustCompile(`^u\d+$`)
+
+var vndbHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// kurohelperservice 沒有 ulist 的查詢，直接呼叫 Kana API
+func (upstreamVndb) GetUserList(ctx context.Context, user string) ([]VndbUListItem, error) {
+	return observe(ctx, "vndb", "GetUserList", func() ([]VndbUListItem, error) {
+		endpoint := strings.TrimRight(os.Getenv("VNDB_ENDPOINT"), "/")
+		if endpoint == "" {
+			endpoint = defaultVndbEndpoint
+		}
+
+		userID, err := resolveVndbUserID(ctx, endpoint, user)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []VndbUListItem
+		for page := 1; page <= vndbUListMaxPages; page++ {
+			var res struct {
+				Results []VndbUListItem `json:"results"`
+				More    bool            `json:"more"`
+			}
+			err := vndbPost(ctx, endpoint+"/ulist", map[string]any{
+				"user":    userID,
+				"fields":  "id,vote,started,finished,notes,labels.id,labels.label,vn.title,vn.alttitle",
+				"results": vndbUListPageSize,
+				"page":    page,
+				"sort":    "vote",
+				"reverse": true,
+			}, &res)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, res.Results...)
+			if !res.More {
+				break
+			}
+		}
+		return items, nil
+	})
+}
+
+// 使用者可以輸入ID(u+數字)或名稱，名稱需要先查出ID
+func resolveVndbUserID(ctx context.Context, endpoint, user string) (string, error) {
+	user = strings.TrimSpace(user)
+	if vndbUserIDRegex.MatchString(user) {
+		return user, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/user?q="+url.QueryEscape(user), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := vndbHTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vndb: user lookup status %d", resp.StatusCode)
+	}
+
+	// 找不到時對應的值為null
+	var res map[string]*struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+	if u := res[user]; u != nil && u.ID != "" {
+		return u.ID, nil
+	}
+	return "", kurohelperservice.ErrSearchNoContent
+}
+
+func vndbPost(ctx context.Context, url string, body any, dst any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := vndbHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(dst)
+	case http.StatusNotFound:
+		return kurohelperservice.ErrSearchNoContent
+	case http.StatusTooManyRequests:
+		return kurohelperservice.ErrRateLimit
+	default:
+		return fmt.Errorf("vndb: %s status %d", url, resp.StatusCode)
+	}
+}
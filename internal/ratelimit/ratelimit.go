@@ -0,0 +1,141 @@
+// ratelimit token bucket限流
+//
+// 每個key一個桶子，桶子容量為 Rule.Burst，每經過 Rule.Per 補滿 Burst 個token，
+// 補滿的桶子會被定期清掉，所以閒置的使用者不會佔用記憶體
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 限流規則：每 Per 最多 Burst 次
+type Rule struct {
+	Burst int
+	Per   time.Duration
+}
+
+// 是否有設定限流
+func (r Rule) Enabled() bool {
+	return r.Burst > 0 && r.Per > 0
+}
+
+// 把次數放大 factor 倍(白名單伺服器、伺服器共用額度使用)
+func (r Rule) Scale(factor int) Rule {
+	if factor <= 1 {
+		return r
+	}
+	return Rule{Burst: r.Burst * factor, Per: r.Per}
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%d/%s", r.Burst, r.Per)
+}
+
+// 解析 "次數/秒數" 格式，例如 "3/60" 代表每60秒3次
+func ParseRule(s string) (Rule, error) {
+	burstStr, perStr, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("ratelimit: rule %q must be <burst>/<seconds>", s)
+	}
+	burst, err := strconv.Atoi(strings.TrimSpace(burstStr))
+	if err != nil {
+		return Rule{}, fmt.Errorf("ratelimit: rule %q: %w", s, err)
+	}
+	seconds, err := strconv.Atoi(strings.TrimSpace(perStr))
+	if err != nil {
+		return Rule{}, fmt.Errorf("ratelimit: rule %q: %w", s, err)
+	}
+	return Rule{Burst: burst, Per: time.Duration(seconds) * time.Second}, nil
+}
+
+// 單次檢查的桶子與規則
+type Check struct {
+	Key  string
+	Rule Rule
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// 這個時間之後桶子已經補滿，可以直接刪掉
+	fullAt time.Time
+}
+
+// 沒有超過這個數量時不清理桶子
+const sweepThreshold = 1024
+
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket)}
+}
+
+// 所有桶子都有token時才一起扣除，否則都不扣並回傳最長需要等待的時間
+func (l *Limiter) Allow(now time.Time, checks ...Check) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	var wait time.Duration
+	for _, c := range checks {
+		if !c.Rule.Enabled() {
+			continue
+		}
+		tokens := l.refill(c, now)
+		if tokens < 1 {
+			rate := float64(c.Rule.Burst) / c.Rule.Per.Seconds()
+			wait = max(wait, time.Duration((1-tokens)/rate*float64(time.Second)))
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+
+	for _, c := range checks {
+		if !c.Rule.Enabled() {
+			continue
+		}
+		b := l.buckets[c.Key]
+		b.tokens--
+		rate := float64(c.Rule.Burst) / c.Rule.Per.Seconds()
+		b.fullAt = now.Add(time.Duration((float64(c.Rule.Burst) - b.tokens) / rate * float64(time.Second)))
+	}
+	return true, 0
+}
+
+// 依照經過時間補充token，回傳目前的token數
+func (l *Limiter) refill(c Check, now time.Time) float64 {
+	b, ok := l.buckets[c.Key]
+	if !ok {
+		b = &bucket{tokens: float64(c.Rule.Burst), last: now, fullAt: now}
+		l.buckets[c.Key] = b
+		return b.tokens
+	}
+
+	rate := float64(c.Rule.Burst) / c.Rule.Per.Seconds()
+	b.tokens = min(float64(c.Rule.Burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	return b.tokens
+}
+
+// 刪除已經補滿的桶子(每分鐘最多一次)
+func (l *Limiter) sweep(now time.Time) {
+	if len(l.buckets) < sweepThreshold || now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.After(b.fullAt) {
+			delete(l.buckets, key)
+		}
+	}
+}
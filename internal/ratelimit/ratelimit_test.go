@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rule
+		wantErr bool
+	}{
+		{in: "3/60", want: Rule{Burst: 3, Per: time.Minute}},
+		{in: " 10 / 5 ", want: Rule{Burst: 10, Per: 5 * time.Second}},
+		{in: "0/60", want: Rule{Burst: 0, Per: time.Minute}},
+		{in: "3", wantErr: true},
+		{in: "a/60", wantErr: true},
+		{in: "3/b", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRule(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRule(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRule(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRuleScale(t *testing.T) {
+	r := Rule{Burst: 3, Per: time.Minute}
+	tests := []struct {
+		factor int
+		want   Rule
+	}{
+		{factor: 0, want: r},
+		{factor: 1, want: r},
+		{factor: 10, want: Rule{Burst: 30, Per: time.Minute}},
+	}
+	for _, tt := range tests {
+		if got := r.Scale(tt.factor); got != tt.want {
+			t.Errorf("Scale(%d) = %v, want %v", tt.factor, got, tt.want)
+		}
+	}
+	if (Rule{Burst: 0, Per: time.Minute}).Enabled() || (Rule{Burst: 1}).Enabled() {
+		t.Error("rule without burst or period should be disabled")
+	}
+}
+
+// 每60秒3次：一開始可以連續3次，之後每20秒補1次
+func TestLimiterBurstAndRefill(t *testing.T) {
+	l := NewLimiter()
+	check := Check{Key: "k", Rule: Rule{Burst: 3, Per: time.Minute}}
+	t0 := time.Unix(1_700_000_000, 0)
+
+	steps := []struct {
+		at       time.Duration
+		want     bool
+		wantWait time.Duration
+	}{
+		{at: 0, want: true},
+		{at: 0, want: true},
+		{at: 0, want: true},
+		{at: 0, want: false, wantWait: 20 * time.Second},
+		// 冷卻時間隨經過時間減少
+		{at: 5 * time.Second, want: false, wantWait: 15 * time.Second},
+		{at: 20 * time.Second, want: true},
+		{at: 20 * time.Second, want: false, wantWait: 20 * time.Second},
+		// 閒置很久也只補滿到Burst
+		{at: time.Hour, want: true},
+		{at: time.Hour, want: true},
+		{at: time.Hour, want: true},
+		{at: time.Hour, want: false, wantWait: 20 * time.Second},
+	}
+	for idx, step := range steps {
+		ok, wait := l.Allow(t0.Add(step.at), check)
+		if ok != step.want || wait.Round(time.Millisecond) != step.wantWait {
+			t.Errorf("step %d (+%s): Allow() = %v, %s, want %v, %s", idx, step.at, ok, wait, step.want, step.wantWait)
+		}
+	}
+}
+
+// 多個桶子時全部都有token才會一起扣除
+func TestLimiterAllChecks(t *testing.T) {
+	l := NewLimiter()
+	t0 := time.Unix(1_700_000_000, 0)
+	user := Check{Key: "user", Rule: Rule{Burst: 2, Per: time.Minute}}
+	guild := Check{Key: "guild", Rule: Rule{Burst: 1, Per: time.Minute}}
+
+	if ok, _ := l.Allow(t0, user, guild); !ok {
+		t.Fatal("first call should be allowed")
+	}
+	// 伺服器額度用完，使用者的token不會被扣
+	ok, wait := l.Allow(t0, user, guild)
+	if ok || wait != time.Minute {
+		t.Errorf("Allow() = %v, %s, want false, 1m", ok, wait)
+	}
+	if ok, _ := l.Allow(t0, user); !ok {
+		t.Error("user bucket was charged by a rejected call")
+	}
+
+	// 沒有設定規則的檢查直接略過
+	if ok, _ := l.Allow(t0, Check{Key: "none"}); !ok {
+		t.Error("disabled rule should always allow")
+	}
+}
+
+// 桶子數量超過門檻時，已經補滿的桶子會被清掉
+func TestLimiterSweep(t *testing.T) {
+	l := NewLimiter()
+	t0 := time.Unix(1_700_000_000, 0)
+	rule := Rule{Burst: 1, Per: time.Second}
+	for n := range sweepThreshold {
+		l.Allow(t0, Check{Key: fmt.Sprint(n), Rule: rule})
+	}
+
+	l.Allow(t0.Add(2*time.Minute), Check{Key: "new", Rule: rule})
+	if len(l.buckets) != 1 {
+		t.Errorf("buckets after sweep = %d, want 1", len(l.buckets))
+	}
+}
@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelperservice"
+	kurohelperdb "kurohelperservice/db"
+)
+
+// 把錯誤歸類，方便在log中統計
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, kurohelperservice.ErrCacheLost):
+		return "cache_lost"
+	case errors.Is(err, kurohelperservice.ErrSearchNoContent), errors.Is(err, kurohelpererrors.ErrRandomAllBlackListed):
+		return "no_content"
+	case errors.Is(err, kurohelperservice.ErrRateLimit):
+		return "rate_limit"
+	case errors.Is(err, kurohelperservice.ErrStatusCodeAbnormal):
+		return "upstream"
+	case errors.Is(err, kurohelperdb.ErrUniqueViolation), errors.Is(err, gorm.ErrRecordNotFound):
+		return "db"
+	case errors.Is(err, kurohelpererrors.ErrCIDWrongFormat),
+		errors.Is(err, kurohelpererrors.ErrCIDGetParameterFailed),
+		errors.Is(err, kurohelpererrors.ErrCIDBehaviorMismatch),
+		errors.Is(err, kurohelpererrors.ErrCIDInvalidSignature),
+		errors.Is(err, kurohelpererrors.ErrCIDTooLong):
+		return "cid"
+	case errors.Is(err, kurohelpererrors.ErrCIDNotOwner):
+		return "not_owner"
+	case errors.Is(err, kurohelpererrors.ErrTooManyRequests), errors.Is(err, kurohelpererrors.ErrCommandCooldown):
+		return "throttled"
+	case errors.Is(err, kurohelpererrors.ErrOptionNotFound),
+		errors.Is(err, kurohelpererrors.ErrOptionTranslateFail),
+		errors.Is(err, kurohelpererrors.ErrTimeWrongFormat),
+		errors.Is(err, kurohelpererrors.ErrDateExceedsTomorrow),
+		errors.Is(err, kurohelpererrors.ErrEndDateBeforeStart),
+		errors.Is(err, kurohelpererrors.ErrAmbiguousKeyword),
+		errors.Is(err, kurohelpererrors.ErrImportUnsupportedFormat),
+		errors.Is(err, kurohelpererrors.ErrImportFileTooLarge):
+		return "invalid_input"
+	default:
+		return "internal"
+	}
+}
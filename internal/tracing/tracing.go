@@ -0,0 +1,209 @@
+// tracing 每一個Interaction的追蹤資訊
+//
+// bot.DispatchInteraction 會為每個Interaction建立一個 Trace 並放進 context.Context，
+// 指令、executor、provider 透過 Logger(ctx) 取得帶有interactionID、指令名稱、CID、
+// 使用者與伺服器的 logger，結束時 Finish 會輸出一行包含總耗時、快取狀態與錯誤分類的摘要
+package tracing
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"kurohelper/internal/metrics"
+)
+
+// Interaction的種類
+type Kind string
+
+const (
+	KindCommand      Kind = "command"
+	KindAutocomplete Kind = "autocomplete"
+	KindComponent    Kind = "component"
+)
+
+// 快取狀態，同一個Interaction多次查詢快取時保留最差的結果(miss > stale > hit)
+type CacheStatus int
+
+const (
+	CacheNone CacheStatus = iota
+	CacheHit
+	CacheStale
+	CacheMiss
+)
+
+func (c CacheStatus) String() string {
+	switch c {
+	case CacheHit:
+		return "hit"
+	case CacheStale:
+		return "stale"
+	case CacheMiss:
+		return "miss"
+	default:
+		return "none"
+	}
+}
+
+type Trace struct {
+	InteractionID string
+	Kind          Kind
+	Command       string
+	CID           string
+	UserID        string
+	GuildID       string
+
+	start  time.Time
+	logger *slog.Logger
+
+	mu               sync.Mutex
+	cache            CacheStatus
+	err              error
+	upstreamCalls    int
+	upstreamDuration time.Duration
+}
+
+type ctxKey struct{}
+
+// 進行中的Trace，讓拿不到ctx的地方(例如 utils.HandleError)也能記錄錯誤
+var active sync.Map
+
+// 建立Interaction的Trace並放進ctx
+func Start(parent context.Context, i *discordgo.InteractionCreate, kind Kind, command, cid string) (context.Context, *Trace) {
+	t := &Trace{
+		InteractionID: i.ID,
+		Kind:          kind,
+		Command:       command,
+		CID:           cid,
+		UserID:        userID(i),
+		GuildID:       i.GuildID,
+		start:         time.Now(),
+	}
+	t.logger = slog.Default().With(
+		"interactionID", t.InteractionID,
+		"command", t.Command,
+		"cid", t.CID,
+		"userID", t.UserID,
+		"guildID", t.GuildID,
+	)
+
+	active.Store(t.InteractionID, t)
+	return context.WithValue(parent, ctxKey{}, t), t
+}
+
+// 從ctx取得Trace，沒有時回傳nil(Trace的方法都可以安全地對nil呼叫)
+func FromContext(ctx context.Context) *Trace {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(ctxKey{}).(*Trace)
+	return t
+}
+
+// 依照Interaction ID取得進行中的Trace
+func ForInteraction(i *discordgo.InteractionCreate) *Trace {
+	if i == nil || i.Interaction == nil {
+		return nil
+	}
+	v, ok := active.Load(i.ID)
+	if !ok {
+		return nil
+	}
+	return v.(*Trace)
+}
+
+// 取得綁定Interaction資訊的logger，沒有Trace時使用預設logger
+func Logger(ctx context.Context) *slog.Logger {
+	return FromContext(ctx).Logger()
+}
+
+func (t *Trace) Logger() *slog.Logger {
+	if t == nil {
+		return slog.Default()
+	}
+	return t.logger
+}
+
+// 記錄快取狀態
+func (t *Trace) RecordCache(status CacheStatus) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if status > t.cache {
+		t.cache = status
+	}
+}
+
+// 記錄錯誤(只保留第一個)
+func (t *Trace) RecordError(err error) {
+	if t == nil || err == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+// 記錄一次上游查詢
+func (t *Trace) RecordUpstream(d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.upstreamCalls++
+	t.upstreamDuration += d
+}
+
+// 結束Trace並輸出摘要
+//
+// Autocomplete數量很多，摘要使用Debug等級
+func (t *Trace) Finish() {
+	if t == nil {
+		return
+	}
+	active.Delete(t.InteractionID)
+
+	latency := time.Since(t.start)
+	metrics.Interactions.Inc(t.Command, string(t.Kind))
+	metrics.InteractionDuration.Observe(latency.Seconds(), t.Command, string(t.Kind))
+
+	t.mu.Lock()
+	attrs := []any{
+		"kind", string(t.Kind),
+		"latency", latency,
+		"cache", t.cache.String(),
+		"upstreamCalls", t.upstreamCalls,
+		"upstreamLatency", t.upstreamDuration,
+	}
+	level := slog.LevelInfo
+	if t.err != nil {
+		attrs = append(attrs, "errorClass", ErrorClass(t.err), "error", t.err.Error())
+		level = slog.LevelWarn
+	} else if t.Kind == KindAutocomplete {
+		level = slog.LevelDebug
+	}
+	t.mu.Unlock()
+
+	t.logger.Log(context.Background(), level, "interaction finished", attrs...)
+}
+
+func userID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
@@ -0,0 +1,14 @@
+package userdata
+
+import (
+	"gorm.io/gorm"
+
+	kurohelperdb "kurohelperservice/db"
+)
+
+// 更新黑名單標記，kurohelperdb 只有願望清單的更新函式
+func UpdateUserGameBlackListMark(db *gorm.DB, userID string, gameErogsID int, mark bool) error {
+	return db.Model(&kurohelperdb.UserGame{}).
+		Where("user_id = ? AND game_erogs_id = ?", userID, gameErogsID).
+		Update("black_list_mark", mark).Error
+}
@@ -0,0 +1,24 @@
+package userdata
+
+import (
+	"gorm.io/gorm"
+
+	kurohelperdb "kurohelperservice/db"
+)
+
+// 取得品牌名稱，key為品牌ID
+func GetBrandNames(db *gorm.DB, brandIDs []int) (map[int]string, error) {
+	names := make(map[int]string, len(brandIDs))
+	if len(brandIDs) == 0 {
+		return names, nil
+	}
+
+	var brands []kurohelperdb.BrandErogs
+	if err := db.Where("id IN ?", brandIDs).Find(&brands).Error; err != nil {
+		return nil, err
+	}
+	for _, b := range brands {
+		names[b.ID] = b.Name
+	}
+	return names, nil
+}
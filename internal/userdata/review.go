@@ -0,0 +1,71 @@
+package userdata
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	MinRating = 1
+	MaxRating = 100
+	// 心得長度上限(字數)，Discord 單一欄位最多1024字
+	MaxNotesLength = 1000
+	// 遊玩時數上限
+	MaxPlayHours = 10000
+)
+
+var spoilerTagRegex = regexp.MustCompile(`(?is)\[spoiler\](.*?)\[/spoiler\]`)
+
+// 把 [spoiler]文字[/spoiler] 轉成 Discord 的 ||文字||
+func NormalizeNotes(notes string) string {
+	notes = strings.TrimSpace(notes)
+	return spoilerTagRegex.ReplaceAllString(notes, "||$1||")
+}
+
+// 是否有評分、心得或遊玩時數
+func (d UserGameDetail) HasReview() bool {
+	return d.Rating > 0 || d.PlayHours > 0 || d.Notes != ""
+}
+
+// 評分與遊玩時數的顯示圖示，例如 "💯85 🕒12.5h"
+func (d UserGameDetail) FormatReview() string {
+	parts := make([]string, 0, 2)
+	if d.Rating > 0 {
+		parts = append(parts, fmt.Sprintf("💯%d", d.Rating))
+	}
+	if d.PlayHours > 0 {
+		parts = append(parts, fmt.Sprintf("🕒%sh", FormatHours(d.PlayHours)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// 遊玩時數最多顯示到小數點後一位
+func FormatHours(hours float64) string {
+	return strconv.FormatFloat(math.Round(hours*10)/10, 'f', -1, 64)
+}
+
+// 使用者所有遊戲的評分與時數統計
+type ReviewSummary struct {
+	RatedCount    int
+	AverageRating float64
+	TotalHours    float64
+}
+
+func SummarizeReviews(details map[int]UserGameDetail) ReviewSummary {
+	var summary ReviewSummary
+	ratingSum := 0
+	for _, d := range details {
+		if d.Rating > 0 {
+			summary.RatedCount++
+			ratingSum += d.Rating
+		}
+		summary.TotalHours += d.PlayHours
+	}
+	if summary.RatedCount > 0 {
+		summary.AverageRating = float64(ratingSum) / float64(summary.RatedCount)
+	}
+	return summary
+}
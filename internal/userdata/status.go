@@ -0,0 +1,102 @@
+package userdata
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	kurohelpererrors "kurohelper/internal/errors"
+	kurohelperdb "kurohelperservice/db"
+)
+
+// 遊玩狀態變更紀錄
+type UserGameStatusHistory struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      string `gorm:"index:idx_user_game_status_history"`
+	GameErogsID int    `gorm:"index:idx_user_game_status_history"`
+	FromStatus  kurohelperdb.UserGameStatus
+	ToStatus    kurohelperdb.UserGameStatus
+	// 狀態對應的日期(開始或結束日期)，沒有指定時為變更當下
+	Date      time.Time
+	CreatedAt time.Time
+}
+
+// 各狀態的顯示名稱
+var StatusName = map[kurohelperdb.UserGameStatus]string{
+	kurohelperdb.UserGameStatusNone:     "無",
+	kurohelperdb.UserGameStatusFinished: "已完成",
+	kurohelperdb.UserGameStatusPlaying:  "遊玩中",
+	kurohelperdb.UserGameStatusStalled:  "擱置",
+	kurohelperdb.UserGameStatusDropped:  "棄坑",
+}
+
+// 記錄一次狀態變更，狀態沒有改變時不記錄
+func RecordStatusChange(db *gorm.DB, userID string, gameErogsID int, from, to kurohelperdb.UserGameStatus, date time.Time) error {
+	if from == to {
+		return nil
+	}
+	if date.IsZero() {
+		date = time.Now()
+	}
+	return db.Create(&UserGameStatusHistory{
+		UserID:      userID,
+		GameErogsID: gameErogsID,
+		FromStatus:  from,
+		ToStatus:    to,
+		Date:        date,
+	}).Error
+}
+
+// 取得遊戲最近的狀態變更紀錄(新到舊)，limit <= 0 代表全部
+func GetStatusHistory(db *gorm.DB, userID string, gameErogsID int, limit int) ([]UserGameStatusHistory, error) {
+	var history []UserGameStatusHistory
+	q := db.Where("user_id = ? AND game_erogs_id = ?", userID, gameErogsID).Order("created_at DESC, id DESC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	if err := q.Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// 依照新的狀態更新開始/結束日期，回傳這次狀態變更要記錄的日期
+//
+// start、end 為使用者指定的日期(可為nil)；沒有指定時:
+// 遊玩中 開始日期預設為今天並清除結束日期，已完成/棄坑 結束日期預設為今天，
+// 狀態沒有改變且已經有日期時保留原本的日期
+func (d *UserGameDetail) ApplyStatus(from, to kurohelperdb.UserGameStatus, start, end *time.Time, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if start != nil {
+		d.StartedDate = start
+	}
+
+	date := now
+	switch to {
+	case kurohelperdb.UserGameStatusPlaying:
+		// 從擱置恢復時沿用原本的開始日期
+		if d.StartedDate == nil || (start == nil && from != to && from != kurohelperdb.UserGameStatusStalled) {
+			d.StartedDate = &today
+		}
+		d.EndedDate = nil
+		if start != nil || from != kurohelperdb.UserGameStatusStalled {
+			date = *d.StartedDate
+		}
+	case kurohelperdb.UserGameStatusFinished, kurohelperdb.UserGameStatusDropped:
+		if end != nil {
+			d.EndedDate = end
+		} else if d.EndedDate == nil || from != to {
+			d.EndedDate = &today
+		}
+		date = *d.EndedDate
+	default:
+		if end != nil {
+			d.EndedDate = end
+		}
+	}
+
+	if d.StartedDate != nil && d.EndedDate != nil && d.EndedDate.Before(*d.StartedDate) {
+		return time.Time{}, kurohelpererrors.ErrEndDateBeforeStart
+	}
+	return date, nil
+}
@@ -0,0 +1,158 @@
+package userdata
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	kurohelpererrors "kurohelper/internal/errors"
+	kurohelperdb "kurohelperservice/db"
+)
+
+func date(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestApplyStatus(t *testing.T) {
+	now := time.Date(2024, 5, 10, 15, 30, 0, 0, time.UTC)
+	today := date(2024, 5, 10)
+
+	const (
+		none     = kurohelperdb.UserGameStatusNone
+		finished = kurohelperdb.UserGameStatusFinished
+		playing  = kurohelperdb.UserGameStatusPlaying
+		stalled  = kurohelperdb.UserGameStatusStalled
+		dropped  = kurohelperdb.UserGameStatusDropped
+	)
+
+	tests := []struct {
+		name     string
+		from, to kurohelperdb.UserGameStatus
+		// 原本的日期
+		started, ended *time.Time
+		// 使用者指定的日期
+		start, end  *time.Time
+		wantStarted *time.Time
+		wantEnded   *time.Time
+		wantDate    time.Time
+		wantErr     error
+	}{
+		{name: "start playing", from: none, to: playing, wantStarted: today, wantDate: *today},
+		{name: "start playing with date", from: none, to: playing, start: date(2024, 5, 1), wantStarted: date(2024, 5, 1), wantDate: *date(2024, 5, 1)},
+		{
+			name: "re-enter playing after finished", from: finished, to: playing,
+			started: date(2024, 4, 1), ended: date(2024, 4, 20),
+			wantStarted: today, wantDate: *today,
+		},
+		{
+			name: "re-enter playing with date clears end", from: dropped, to: playing,
+			started: date(2024, 4, 1), ended: date(2024, 4, 20), start: date(2024, 5, 2),
+			wantStarted: date(2024, 5, 2), wantDate: *date(2024, 5, 2),
+		},
+		{
+			name: "resume from stalled keeps start", from: stalled, to: playing,
+			started:     date(2024, 4, 1),
+			wantStarted: date(2024, 4, 1), wantDate: now,
+		},
+		{
+			name: "playing again keeps start", from: playing, to: playing,
+			started:     date(2024, 4, 1),
+			wantStarted: date(2024, 4, 1), wantDate: *date(2024, 4, 1),
+		},
+		{
+			name: "finished without date", from: playing, to: finished,
+			started:     date(2024, 4, 1),
+			wantStarted: date(2024, 4, 1), wantEnded: today, wantDate: *today,
+		},
+		{
+			name: "finished with date", from: playing, to: finished,
+			started: date(2024, 4, 1), end: date(2024, 5, 5),
+			wantStarted: date(2024, 4, 1), wantEnded: date(2024, 5, 5), wantDate: *date(2024, 5, 5),
+		},
+		{
+			name: "finished again keeps end", from: finished, to: finished,
+			ended:     date(2024, 4, 20),
+			wantEnded: date(2024, 4, 20), wantDate: *date(2024, 4, 20),
+		},
+		{
+			name: "dropped after finished resets end", from: finished, to: dropped,
+			ended:     date(2024, 4, 20),
+			wantEnded: today, wantDate: *today,
+		},
+		{
+			name: "stalled with end date", from: playing, to: stalled,
+			started: date(2024, 4, 1), end: date(2024, 4, 15),
+			wantStarted: date(2024, 4, 1), wantEnded: date(2024, 4, 15), wantDate: now,
+		},
+		{
+			name: "end before start", from: playing, to: finished,
+			started: date(2024, 5, 1), end: date(2024, 4, 1),
+			wantErr: kurohelpererrors.ErrEndDateBeforeStart,
+		},
+		{
+			name: "start after existing end", from: finished, to: finished,
+			ended: date(2024, 5, 1), start: date(2024, 5, 5),
+			wantErr: kurohelpererrors.ErrEndDateBeforeStart,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &UserGameDetail{StartedDate: tt.started, EndedDate: tt.ended}
+			got, err := d.ApplyStatus(tt.from, tt.to, tt.start, tt.end, now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ApplyStatus() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.wantDate) {
+				t.Errorf("date = %v, want %v", got, tt.wantDate)
+			}
+			if !sameDate(d.StartedDate, tt.wantStarted) {
+				t.Errorf("StartedDate = %v, want %v", d.StartedDate, tt.wantStarted)
+			}
+			if !sameDate(d.EndedDate, tt.wantEnded) {
+				t.Errorf("EndedDate = %v, want %v", d.EndedDate, tt.wantEnded)
+			}
+		})
+	}
+}
+
+// 每個狀態都要有不重複的顯示名稱(刪除、變更狀態的訊息與匯入都依賴這份對照)
+func TestStatusName(t *testing.T) {
+	tests := []struct {
+		status kurohelperdb.UserGameStatus
+		want   string
+	}{
+		{status: kurohelperdb.UserGameStatusNone, want: "無"},
+		{status: kurohelperdb.UserGameStatusFinished, want: "已完成"},
+		{status: kurohelperdb.UserGameStatusPlaying, want: "遊玩中"},
+		{status: kurohelperdb.UserGameStatusStalled, want: "擱置"},
+		{status: kurohelperdb.UserGameStatusDropped, want: "棄坑"},
+	}
+	if len(StatusName) != len(tests) {
+		t.Errorf("len(StatusName) = %d, want %d", len(StatusName), len(tests))
+	}
+	seen := make(map[string]kurohelperdb.UserGameStatus)
+	for _, tt := range tests {
+		got, ok := StatusName[tt.status]
+		if !ok || got != tt.want {
+			t.Errorf("StatusName[%d] = %q, %v, want %q", tt.status, got, ok, tt.want)
+		}
+		if prev, dup := seen[got]; dup {
+			t.Errorf("StatusName[%d] and StatusName[%d] are both %q", prev, tt.status, got)
+		}
+		seen[got] = tt.status
+	}
+}
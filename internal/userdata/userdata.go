@@ -0,0 +1,64 @@
+// userdata 使用者遊戲的延伸資料
+//
+// kurohelperdb.UserGame 只有狀態、願望清單與完成日期，其他欄位(開始日期、評分、心得、狀態變更紀錄等)
+// 存在這個套件自己的資料表，以 (UserID, GameErogsID) 對應到同一筆 UserGame
+package userdata
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 使用者遊戲的延伸資料
+type UserGameDetail struct {
+	UserID      string `gorm:"primaryKey"`
+	GameErogsID int    `gorm:"primaryKey"`
+	// 開始遊玩日期
+	StartedDate *time.Time
+	// 結束日期(完成或棄坑)
+	EndedDate *time.Time
+	// 個人評分(1~100)，0代表未評分
+	Rating int
+	// 心得，可用 ||文字|| 標記劇透
+	Notes string
+	// 遊玩時數(小時)
+	PlayHours float64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// 建立或更新資料表，需要在 kurohelperdb.Migration 之後呼叫
+func Migration(db *gorm.DB) error {
+	return db.AutoMigrate(&UserGameDetail{}, &UserGameStatusHistory{})
+}
+
+// 取得使用者遊戲的延伸資料，沒有資料時回傳空的 UserGameDetail
+func GetUserGameDetail(db *gorm.DB, userID string, gameErogsID int) (UserGameDetail, error) {
+	detail := UserGameDetail{UserID: userID, GameErogsID: gameErogsID}
+	err := db.Where("user_id = ? AND game_erogs_id = ?", userID, gameErogsID).Take(&detail).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return detail, nil
+	}
+	return detail, err
+}
+
+// 取得使用者所有遊戲的延伸資料，以 GameErogsID 建立索引
+func GetUserGameDetails(db *gorm.DB, userID string) (map[int]UserGameDetail, error) {
+	var details []UserGameDetail
+	if err := db.Where("user_id = ?", userID).Find(&details).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[int]UserGameDetail, len(details))
+	for _, d := range details {
+		out[d.GameErogsID] = d
+	}
+	return out, nil
+}
+
+// 新增或整筆覆蓋延伸資料
+func SaveUserGameDetail(db *gorm.DB, detail *UserGameDetail) error {
+	return db.Clauses(clause.OnConflict{UpdateAll: true}).Create(detail).Error
+}
@@ -0,0 +1,247 @@
+// userstats 使用者遊玩統計
+//
+// 只依賴資料庫的紀錄與已取得的批評空間遊戲資料，查詢與快取由呼叫端負責
+package userstats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	kurohelperdb "kurohelperservice/db"
+	"kurohelperservice/provider/erogs"
+
+	"kurohelper/internal/userdata"
+)
+
+const (
+	// 分布統計只保留前幾名
+	TopLimit = 10
+	// 每月完成數顯示最近幾個月
+	MonthlyRange = 12
+
+	// CreatorShubetu 的職種
+	shubetuArtist = 1
+	shubetuWriter = 2
+)
+
+// 統計的一列
+type Count struct {
+	Name  string
+	Count int
+	// 相關遊戲的遊玩時數合計
+	Hours float64
+}
+
+type Statistics struct {
+	StatusCounts   map[kurohelperdb.UserGameStatus]int
+	WishCount      int
+	BlackListCount int
+	// 待玩數量：收藏中尚未完成的遊戲 + 遊玩中 + 擱置
+	Backlog int
+	// 有遊玩狀態的遊戲數量
+	Played int
+	// 有批評空間資料可以統計的數量(類型、分數、創作者)
+	Covered int
+	// 已遊玩遊戲的批評空間中位數平均
+	MedianAverage float64
+	MedianCount   int
+	Review        userdata.ReviewSummary
+
+	Yearly  []Count
+	Monthly []Count
+	Genres  []Count
+	Brands  []Count
+	Writers []Count
+	Artists []Count
+}
+
+// 計算統計資料
+//
+// games 為批評空間遊戲資料(key為遊戲ID)，沒有資料的遊戲不列入類型、分數與創作者統計；
+// brandNames 為資料庫的品牌名稱，沒有時使用批評空間資料的品牌名稱
+func Compute(userGames []kurohelperdb.UserGame, details map[int]userdata.UserGameDetail, games map[int]*erogs.Game, brandNames map[int]string, now time.Time) Statistics {
+	stats := Statistics{
+		StatusCounts: make(map[kurohelperdb.UserGameStatus]int),
+		Review:       userdata.SummarizeReviews(details),
+	}
+
+	yearly := make(map[string]*Count)
+	monthly := make(map[string]*Count)
+	genres := make(map[string]*Count)
+	brands := make(map[string]*Count)
+	writers := make(map[string]*Count)
+	artists := make(map[string]*Count)
+	var medianSum float64
+
+	for _, ug := range userGames {
+		detail := details[ug.GameErogsID]
+		if ug.WishListMark {
+			stats.WishCount++
+		}
+		if ug.BlackListMark {
+			stats.BlackListCount++
+		}
+		switch ug.Status {
+		case kurohelperdb.UserGameStatusPlaying, kurohelperdb.UserGameStatusStalled:
+			stats.Backlog++
+		case kurohelperdb.UserGameStatusNone:
+			if ug.WishListMark {
+				stats.Backlog++
+			}
+			continue
+		}
+		stats.StatusCounts[ug.Status]++
+		stats.Played++
+
+		if ug.Status == kurohelperdb.UserGameStatusFinished {
+			date := detail.EndedDate
+			if date == nil {
+				date = ug.FinishedDate
+			}
+			if date != nil {
+				add(yearly, strconv.Itoa(date.Year()), detail.PlayHours)
+				add(monthly, date.Format("2006-01"), detail.PlayHours)
+			}
+		}
+
+		game := games[ug.GameErogsID]
+		// 以資料庫的品牌名稱為準，避免同一個品牌因來源不同分成兩列
+		brand := brandNames[ug.GameErogs.BrandID]
+		if brand == "" && game != nil {
+			brand = game.BrandName
+		}
+		if brand != "" {
+			add(brands, brand, detail.PlayHours)
+		}
+		if game == nil {
+			continue
+		}
+		stats.Covered++
+
+		if genre := strings.TrimSpace(game.Genre); genre != "" {
+			add(genres, genre, detail.PlayHours)
+		}
+		if median, err := strconv.ParseFloat(strings.TrimSpace(game.Median), 64); err == nil && median > 0 {
+			medianSum += median
+			stats.MedianCount++
+		}
+		// 同一款遊戲同一個人有多個細項時只算一次
+		seen := make(map[string]bool)
+		for _, c := range game.CreatorShubetu {
+			key := strconv.Itoa(c.ShubetuType) + c.CreatorName
+			if c.CreatorName == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			switch c.ShubetuType {
+			case shubetuWriter:
+				add(writers, c.CreatorName, detail.PlayHours)
+			case shubetuArtist:
+				add(artists, c.CreatorName, detail.PlayHours)
+			}
+		}
+	}
+
+	if stats.MedianCount > 0 {
+		stats.MedianAverage = medianSum / float64(stats.MedianCount)
+	}
+
+	stats.Yearly = sortByName(yearly)
+	stats.Monthly = make([]Count, 0, MonthlyRange)
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -(MonthlyRange - 1), 0)
+	for m := 0; m < MonthlyRange; m++ {
+		key := first.AddDate(0, m, 0).Format("2006-01")
+		c := Count{Name: key}
+		if v, ok := monthly[key]; ok {
+			c = *v
+		}
+		stats.Monthly = append(stats.Monthly, c)
+	}
+	stats.Genres = top(genres)
+	stats.Brands = top(brands)
+	// 創作者看的是投入的時間，數量只用來排相同時數的順序
+	stats.Writers = topByHours(writers)
+	stats.Artists = topByHours(artists)
+	return stats
+}
+
+func add(m map[string]*Count, name string, hours float64) {
+	c, ok := m[name]
+	if !ok {
+		c = &Count{Name: name}
+		m[name] = c
+	}
+	c.Count++
+	c.Hours += hours
+}
+
+func sortByName(m map[string]*Count) []Count {
+	out := make([]Count, 0, len(m))
+	for _, c := range m {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// 依數量排序(相同時依時數)，只保留前 TopLimit 名
+func top(m map[string]*Count) []Count {
+	return topSorted(m, false)
+}
+
+// 依遊玩時數排序(相同時依數量)，只保留前 TopLimit 名
+func topByHours(m map[string]*Count) []Count {
+	return topSorted(m, true)
+}
+
+func topSorted(m map[string]*Count, hoursFirst bool) []Count {
+	out := make([]Count, 0, len(m))
+	for _, c := range m {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if hoursFirst && out[i].Hours != out[j].Hours {
+			return out[i].Hours > out[j].Hours
+		}
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		if out[i].Hours != out[j].Hours {
+			return out[i].Hours > out[j].Hours
+		}
+		return out[i].Name < out[j].Name
+	})
+	if len(out) > TopLimit {
+		out = out[:TopLimit]
+	}
+	return out
+}
+
+// 文字長條圖，每一列為 "▇▇▇▇░░ 數量 名稱"，長度依最大值等比例縮放
+func BarChart(counts []Count, width int) string {
+	maxCount := 0
+	for _, c := range counts {
+		maxCount = max(maxCount, c.Count)
+	}
+	if maxCount == 0 {
+		return ""
+	}
+
+	lines := make([]string, 0, len(counts))
+	for _, c := range counts {
+		filled := int(math.Round(float64(c.Count) / float64(maxCount) * float64(width)))
+		if c.Count > 0 && filled == 0 {
+			filled = 1
+		}
+		line := fmt.Sprintf("`%s%s` **%d** %s", strings.Repeat("▇", filled), strings.Repeat("░", width-filled), c.Count, c.Name)
+		if c.Hours > 0 {
+			line += fmt.Sprintf("（🕒%sh）", userdata.FormatHours(c.Hours))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
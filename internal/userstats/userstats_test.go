@@ -0,0 +1,213 @@
+package userstats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	kurohelperdb "kurohelperservice/db"
+	"kurohelperservice/provider/erogs"
+
+	"kurohelper/internal/userdata"
+)
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		userGames []kurohelperdb.UserGame
+		details   map[int]userdata.UserGameDetail
+		games     map[int]*erogs.Game
+		check     func(t *testing.T, stats Statistics)
+	}{
+		{
+			name: "backlog",
+			userGames: []kurohelperdb.UserGame{
+				{GameErogsID: 1, Status: kurohelperdb.UserGameStatusPlaying},
+				{GameErogsID: 2, Status: kurohelperdb.UserGameStatusStalled},
+				// 收藏但還沒開始
+				{GameErogsID: 3, WishListMark: true},
+				// 收藏但已經完成，不算待玩
+				{GameErogsID: 4, Status: kurohelperdb.UserGameStatusFinished, WishListMark: true},
+				{GameErogsID: 5, BlackListMark: true},
+				{GameErogsID: 6, Status: kurohelperdb.UserGameStatusDropped},
+			},
+			check: func(t *testing.T, stats Statistics) {
+				if stats.Backlog != 3 {
+					t.Errorf("Backlog = %d, want 3", stats.Backlog)
+				}
+				if stats.Played != 4 {
+					t.Errorf("Played = %d, want 4", stats.Played)
+				}
+				if stats.WishCount != 2 || stats.BlackListCount != 1 {
+					t.Errorf("WishCount, BlackListCount = %d, %d, want 2, 1", stats.WishCount, stats.BlackListCount)
+				}
+			},
+		},
+		{
+			name: "monthly window",
+			userGames: []kurohelperdb.UserGame{
+				// 延伸資料的結束日期優先
+				{GameErogsID: 1, Status: kurohelperdb.UserGameStatusFinished, FinishedDate: date(2020, 1, 1)},
+				{GameErogsID: 2, Status: kurohelperdb.UserGameStatusFinished, FinishedDate: date(2023, 7, 31)},
+				// 超過12個月，只算在每年完成數
+				{GameErogsID: 3, Status: kurohelperdb.UserGameStatusFinished, FinishedDate: date(2023, 6, 30)},
+				// 沒有日期不列入完成紀錄
+				{GameErogsID: 4, Status: kurohelperdb.UserGameStatusFinished},
+			},
+			details: map[int]userdata.UserGameDetail{
+				1: {EndedDate: date(2024, 6, 1), PlayHours: 20},
+			},
+			check: func(t *testing.T, stats Statistics) {
+				if len(stats.Monthly) != MonthlyRange {
+					t.Fatalf("len(Monthly) = %d, want %d", len(stats.Monthly), MonthlyRange)
+				}
+				if got := stats.Monthly[0]; got.Name != "2023-07" || got.Count != 1 {
+					t.Errorf("Monthly[0] = %+v, want 2023-07 with 1", got)
+				}
+				if got := stats.Monthly[MonthlyRange-1]; got.Name != "2024-06" || got.Count != 1 || got.Hours != 20 {
+					t.Errorf("Monthly[last] = %+v, want 2024-06 with 1 and 20h", got)
+				}
+				total := 0
+				for _, c := range stats.Monthly {
+					total += c.Count
+				}
+				if total != 2 {
+					t.Errorf("monthly total = %d, want 2", total)
+				}
+				want := []Count{{Name: "2023", Count: 2}, {Name: "2024", Count: 1, Hours: 20}}
+				if !reflect.DeepEqual(stats.Yearly, want) {
+					t.Errorf("Yearly = %+v, want %+v", stats.Yearly, want)
+				}
+			},
+		},
+		{
+			name: "creator dedupe",
+			userGames: []kurohelperdb.UserGame{
+				{GameErogsID: 1, Status: kurohelperdb.UserGameStatusFinished},
+				{GameErogsID: 2, Status: kurohelperdb.UserGameStatusPlaying},
+				// 沒有批評空間資料，不列入創作者統計
+				{GameErogsID: 3, Status: kurohelperdb.UserGameStatusFinished},
+			},
+			details: map[int]userdata.UserGameDetail{
+				1: {PlayHours: 10},
+				2: {PlayHours: 30},
+				3: {PlayHours: 100},
+			},
+			games: map[int]*erogs.Game{
+				1: {CreatorShubetu: []erogs.CreatorShubetu{
+					// 同一個人負責多個細項
+					{CreatorName: "麻枝准", ShubetuType: shubetuWriter},
+					{CreatorName: "麻枝准", ShubetuType: shubetuWriter},
+					{CreatorName: "麻枝准", ShubetuType: shubetuArtist},
+					{CreatorName: "都乃河勇人", ShubetuType: shubetuWriter},
+					{CreatorName: "", ShubetuType: shubetuWriter},
+				}},
+				2: {CreatorShubetu: []erogs.CreatorShubetu{
+					{CreatorName: "都乃河勇人", ShubetuType: shubetuWriter},
+				}},
+			},
+			check: func(t *testing.T, stats Statistics) {
+				if stats.Covered != 2 {
+					t.Errorf("Covered = %d, want 2", stats.Covered)
+				}
+				wantWriters := []Count{{Name: "都乃河勇人", Count: 2, Hours: 40}, {Name: "麻枝准", Count: 1, Hours: 10}}
+				if !reflect.DeepEqual(stats.Writers, wantWriters) {
+					t.Errorf("Writers = %+v, want %+v", stats.Writers, wantWriters)
+				}
+				wantArtists := []Count{{Name: "麻枝准", Count: 1, Hours: 10}}
+				if !reflect.DeepEqual(stats.Artists, wantArtists) {
+					t.Errorf("Artists = %+v, want %+v", stats.Artists, wantArtists)
+				}
+			},
+		},
+		{
+			name: "creators sorted by hours",
+			userGames: []kurohelperdb.UserGame{
+				{GameErogsID: 1, Status: kurohelperdb.UserGameStatusFinished},
+				{GameErogsID: 2, Status: kurohelperdb.UserGameStatusFinished},
+				{GameErogsID: 3, Status: kurohelperdb.UserGameStatusFinished},
+			},
+			details: map[int]userdata.UserGameDetail{
+				1: {PlayHours: 1},
+				2: {PlayHours: 1},
+				3: {PlayHours: 50},
+			},
+			games: map[int]*erogs.Game{
+				1: {Genre: "ADV", CreatorShubetu: []erogs.CreatorShubetu{{CreatorName: "A", ShubetuType: shubetuWriter}}},
+				2: {Genre: "ADV", CreatorShubetu: []erogs.CreatorShubetu{{CreatorName: "A", ShubetuType: shubetuWriter}}},
+				3: {Genre: "RPG", CreatorShubetu: []erogs.CreatorShubetu{{CreatorName: "B", ShubetuType: shubetuWriter}}},
+			},
+			check: func(t *testing.T, stats Statistics) {
+				wantWriters := []Count{{Name: "B", Count: 1, Hours: 50}, {Name: "A", Count: 2, Hours: 2}}
+				if !reflect.DeepEqual(stats.Writers, wantWriters) {
+					t.Errorf("Writers = %+v, want %+v", stats.Writers, wantWriters)
+				}
+				// 類型仍然依數量排序
+				wantGenres := []Count{{Name: "ADV", Count: 2, Hours: 2}, {Name: "RPG", Count: 1, Hours: 50}}
+				if !reflect.DeepEqual(stats.Genres, wantGenres) {
+					t.Errorf("Genres = %+v, want %+v", stats.Genres, wantGenres)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, Compute(tt.userGames, tt.details, tt.games, nil, now))
+		})
+	}
+}
+
+func TestBarChart(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts []Count
+		width  int
+		want   string
+	}{
+		{
+			name:   "zero count bars",
+			counts: []Count{{Name: "2024-01", Count: 4}, {Name: "2024-02"}, {Name: "2024-03", Count: 2}},
+			width:  4,
+			want:   "`▇▇▇▇` **4** 2024-01\n`░░░░` **0** 2024-02\n`▇▇░░` **2** 2024-03",
+		},
+		{
+			name:   "small counts keep one block",
+			counts: []Count{{Name: "a", Count: 10}, {Name: "b", Count: 1}},
+			width:  4,
+			want:   "`▇▇▇▇` **10** a\n`▇░░░` **1** b",
+		},
+		{
+			name:   "hours",
+			counts: []Count{{Name: "a", Count: 1, Hours: 12.5}},
+			width:  2,
+			want:   "`▇▇` **1** a（🕒12.5h）",
+		},
+		{
+			name:   "all zero",
+			counts: []Count{{Name: "a"}, {Name: "b"}},
+			width:  4,
+			want:   "",
+		},
+		{
+			name:  "empty",
+			width: 4,
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BarChart(tt.counts, tt.width); got != tt.want {
+				t.Errorf("BarChart() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func date(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
@@ -0,0 +1,108 @@
+package usertransfer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+
+	kurohelpererrors "kurohelper/internal/errors"
+	kurohelperdb "kurohelperservice/db"
+)
+
+// CSV欄位名稱的別名，key為 Record 的欄位名稱
+var csvColumnAliases = map[string][]string{
+	"game_id":    {"game_id", "game", "erogs_id", "id"},
+	"vndb_id":    {"vndb_id", "vndb", "vid"},
+	"title":      {"title", "gamename", "name"},
+	"status":     {"status"},
+	"wish":       {"wish"},
+	"blacklist":  {"blacklist"},
+	"rating":     {"rating", "tokuten", "vote"},
+	"play_hours": {"play_hours", "total_play_time", "hours"},
+	"started":    {"started", "start_date"},
+	"finished":   {"finished", "tourokubi", "end_date"},
+	"notes":      {"notes", "memo", "hitokoto"},
+}
+
+func parseCSV(data []byte) ([]Entry, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	// ErogameScape 複製下來的結果常常是Tab分隔
+	if firstLine, _, _ := bytes.Cut(data, []byte("\n")); bytes.Count(firstLine, []byte("\t")) > bytes.Count(firstLine, []byte(",")) {
+		r.Comma = '\t'
+	}
+
+	// 只有欄位名稱的檔案也是合法的(匯出時沒有任何資料)
+	rows, err := r.ReadAll()
+	if err != nil || len(rows) == 0 {
+		return nil, kurohelpererrors.ErrImportUnsupportedFormat
+	}
+
+	columns := make(map[string]int)
+	for idx, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for field, aliases := range csvColumnAliases {
+			if _, ok := columns[field]; ok {
+				continue
+			}
+			for _, alias := range aliases {
+				if name == alias {
+					columns[field] = idx
+				}
+			}
+		}
+	}
+	_, hasGameID := columns["game_id"]
+	_, hasVndbID := columns["vndb_id"]
+	if !hasGameID && !hasVndbID {
+		return nil, kurohelpererrors.ErrImportUnsupportedFormat
+	}
+	_, hasStatus := columns["status"]
+
+	entries := make([]Entry, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		get := func(field string) string {
+			idx, ok := columns[field]
+			if !ok || idx >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[idx])
+		}
+
+		gameID, _ := strconv.Atoi(strings.TrimPrefix(strings.ToLower(get("game_id")), "e"))
+		rec := Record{
+			GameID:    gameID,
+			VndbID:    get("vndb_id"),
+			Title:     get("title"),
+			Status:    get("status"),
+			Wish:      parseBool(get("wish")),
+			BlackList: parseBool(get("blacklist")),
+			Started:   get("started"),
+			Finished:  get("finished"),
+			Notes:     get("notes"),
+		}
+		rec.Rating, _ = strconv.Atoi(get("rating"))
+		rec.PlayHours, _ = strconv.ParseFloat(get("play_hours"), 64)
+		if rec.GameID <= 0 && normalizeVndbID(rec.VndbID) == "" {
+			continue
+		}
+
+		e := rec.Entry()
+		// 沒有狀態欄位的CSV(ErogameScape)每一列都是已遊玩的紀錄
+		if !hasStatus {
+			e.Status = kurohelperdb.UserGameStatusFinished
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func parseBool(s string) bool {
+	switch strings.ToLower(s) {
+	case "1", "true", "t", "yes", "y", "o", "v":
+		return true
+	}
+	return false
+}
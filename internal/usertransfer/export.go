@@ -0,0 +1,80 @@
+package usertransfer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"kurohelper/internal/userdata"
+	kurohelperdb "kurohelperservice/db"
+)
+
+// 匯出JSON的最外層
+type Export struct {
+	ExportedAt time.Time `json:"exported_at"`
+	Games      []Record  `json:"games"`
+}
+
+// CSV欄位順序，與 Record 的json名稱相同
+var csvHeader = []string{"game_id", "vndb_id", "title", "brand", "status", "wish", "blacklist", "rating", "play_hours", "started", "finished", "notes"}
+
+// 把資料庫的紀錄轉成 Record，結束日期沒有延伸資料時使用 UserGame 的完成日期
+func NewRecord(ug kurohelperdb.UserGame, detail userdata.UserGameDetail, brand string) Record {
+	finished := detail.EndedDate
+	if finished == nil {
+		finished = ug.FinishedDate
+	}
+	return Record{
+		GameID:    ug.GameErogsID,
+		Title:     ug.GameErogs.Name,
+		Brand:     brand,
+		Status:    StatusCode[ug.Status],
+		Wish:      ug.WishListMark,
+		BlackList: ug.BlackListMark,
+		Rating:    detail.Rating,
+		PlayHours: detail.PlayHours,
+		Started:   FormatDate(detail.StartedDate),
+		Finished:  FormatDate(finished),
+		Notes:     detail.Notes,
+	}
+}
+
+func WriteJSON(w io.Writer, records []Record) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Export{ExportedAt: time.Now(), Games: records})
+}
+
+// 開頭加上BOM，Excel開啟時才不會亂碼
+func WriteCSV(w io.Writer, records []Record) error {
+	if _, err := io.WriteString(w, "\xef\xbb\xbf"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		row := []string{
+			strconv.Itoa(r.GameID),
+			r.VndbID,
+			r.Title,
+			r.Brand,
+			r.Status,
+			strconv.FormatBool(r.Wish),
+			strconv.FormatBool(r.BlackList),
+			strconv.Itoa(r.Rating),
+			strconv.FormatFloat(r.PlayHours, 'f', -1, 64),
+			r.Started,
+			r.Finished,
+			r.Notes,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,295 @@
+// usertransfer 使用者遊戲資料的匯入匯出格式
+//
+// 支援的匯入格式(依副檔名與內容自動判斷)：
+//   - VNDB 匯出的XML(<vndb-export><vns><vn id="v17">...)
+//   - VNDB ulist 的JSON(Kana API 的 {"results":[...]} 或直接是陣列)
+//   - CSV，第一列為欄位名稱，可辨識 ErogameScape 的欄位名稱(game、gamename、tokuten、tourokubi、hitokoto)
+//   - 本程式匯出的JSON/CSV(Record)
+//
+// Record 的欄位：
+//
+//	game_id     ErogameScape 遊戲ID
+//	vndb_id     VNDB ID(v+數字)
+//	title       遊戲名稱
+//	brand       品牌名稱(只供閱讀，匯入時不使用)
+//	status      finished、playing、stalled、dropped，空字串代表沒有遊玩狀態
+//	wish        是否收藏
+//	blacklist   是否在黑名單
+//	rating      評分(1~100)，0代表未評分
+//	play_hours  遊玩時數
+//	started     開始日期(YYYY-MM-DD)
+//	finished    結束日期(YYYY-MM-DD)
+//	notes       心得
+//
+// 匯出的JSON為 {"exported_at": ..., "games": [Record...]}，CSV第一列為上面的欄位名稱。
+// CSV沒有 status 欄位時(例如 ErogameScape 的CSV)，每一列都當作已完成
+package usertransfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/userdata"
+	kurohelperdb "kurohelperservice/db"
+)
+
+const (
+	// 上傳檔案大小上限
+	MaxFileSize = 2 << 20
+	// 單次匯入的筆數上限
+	MaxEntries = 1000
+	// 日期格式
+	DateLayout = "2006-01-02"
+)
+
+// 匯出檔案的一筆資料，匯入時也接受相同格式
+type Record struct {
+	GameID    int     `json:"game_id"`
+	VndbID    string  `json:"vndb_id,omitempty"`
+	Title     string  `json:"title"`
+	Brand     string  `json:"brand,omitempty"`
+	Status    string  `json:"status"`
+	Wish      bool    `json:"wish"`
+	BlackList bool    `json:"blacklist"`
+	Rating    int     `json:"rating,omitempty"`
+	PlayHours float64 `json:"play_hours,omitempty"`
+	Started   string  `json:"started,omitempty"`
+	Finished  string  `json:"finished,omitempty"`
+	Notes     string  `json:"notes,omitempty"`
+}
+
+// 解析後的一筆匯入資料
+//
+// ErogsID 與 VndbID 至少會有一個，對應不到時用 Titles 搜尋
+type Entry struct {
+	ErogsID   int
+	VndbID    string
+	Titles    []string
+	Status    kurohelperdb.UserGameStatus
+	Wish      bool
+	BlackList bool
+	Rating    int
+	PlayHours float64
+	Started   *time.Time
+	Finished  *time.Time
+	Notes     string
+}
+
+// 顯示用名稱
+func (e Entry) Name() string {
+	if len(e.Titles) > 0 {
+		return e.Titles[0]
+	}
+	if e.VndbID != "" {
+		return e.VndbID
+	}
+	return "e" + strconv.Itoa(e.ErogsID)
+}
+
+// 是否有任何可以匯入的狀態或標記
+func (e Entry) HasMark() bool {
+	return e.Status != kurohelperdb.UserGameStatusNone || e.Wish || e.BlackList
+}
+
+// 狀態在檔案中的代碼
+var StatusCode = map[kurohelperdb.UserGameStatus]string{
+	kurohelperdb.UserGameStatusNone:     "",
+	kurohelperdb.UserGameStatusFinished: "finished",
+	kurohelperdb.UserGameStatusPlaying:  "playing",
+	kurohelperdb.UserGameStatusStalled:  "stalled",
+	kurohelperdb.UserGameStatusDropped:  "dropped",
+}
+
+// 解析狀態，接受代碼、中文名稱與數字，無法辨識時回傳 UserGameStatusNone
+func ParseStatus(s string) kurohelperdb.UserGameStatus {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return kurohelperdb.UserGameStatusNone
+	}
+	for status, code := range StatusCode {
+		if code != "" && (s == code || s == userdata.StatusName[status]) {
+			return status
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if _, ok := StatusCode[kurohelperdb.UserGameStatus(n)]; ok {
+			return kurohelperdb.UserGameStatus(n)
+		}
+	}
+	return kurohelperdb.UserGameStatusNone
+}
+
+// 解析日期，接受 2006-01-02、2006/01/02、20060102，後面帶時間時只取日期
+func ParseDate(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if len(s) >= 10 && (s[4] == '-' || s[4] == '/') {
+		s = strings.ReplaceAll(s[:10], "/", "-")
+	}
+	for _, layout := range []string{DateLayout, "20060102"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// 格式化日期，nil時回傳空字串
+func FormatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(DateLayout)
+}
+
+// 轉成 Entry，欄位超出範圍時視為沒有填寫
+func (r Record) Entry() Entry {
+	e := Entry{
+		ErogsID:   r.GameID,
+		VndbID:    normalizeVndbID(r.VndbID),
+		Titles:    appendTitles(nil, r.Title),
+		Status:    ParseStatus(r.Status),
+		Wish:      r.Wish,
+		BlackList: r.BlackList,
+		Rating:    r.Rating,
+		PlayHours: r.PlayHours,
+		Started:   ParseDate(r.Started),
+		Finished:  ParseDate(r.Finished),
+		Notes:     r.Notes,
+	}
+	e.sanitize()
+	return e
+}
+
+func (e *Entry) sanitize() {
+	if e.Rating < userdata.MinRating || e.Rating > userdata.MaxRating {
+		e.Rating = 0
+	}
+	if e.PlayHours < 0 || e.PlayHours > userdata.MaxPlayHours {
+		e.PlayHours = 0
+	}
+	e.Notes = strings.TrimSpace(userdata.NormalizeNotes(e.Notes))
+	if utf8.RuneCountInString(e.Notes) > userdata.MaxNotesLength {
+		e.Notes = string([]rune(e.Notes)[:userdata.MaxNotesLength])
+	}
+	// 黑名單與收藏不會同時存在
+	if e.BlackList {
+		e.Wish = false
+	}
+}
+
+// 解析上傳的檔案，依副檔名判斷格式，副檔名無法判斷時看內容
+//
+// 可以辨識格式但沒有任何遊戲時(例如匯出時沒有資料)回傳空的結果
+func Parse(filename string, data []byte) ([]Entry, error) {
+	if len(data) > MaxFileSize {
+		return nil, kurohelpererrors.ErrImportFileTooLarge
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var (
+		entries []Entry
+		err     error
+	)
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xml":
+		entries, err = parseVndbXML(data)
+	case ".json":
+		entries, err = parseJSON(data)
+	case ".csv", ".tsv":
+		entries, err = parseCSV(data)
+	default:
+		trimmed := bytes.TrimSpace(data)
+		switch {
+		case bytes.HasPrefix(trimmed, []byte("<")):
+			entries, err = parseVndbXML(data)
+		case bytes.HasPrefix(trimmed, []byte("[")), bytes.HasPrefix(trimmed, []byte("{")):
+			entries, err = parseJSON(data)
+		default:
+			entries, err = parseCSV(data)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > MaxEntries {
+		entries = entries[:MaxEntries]
+	}
+	return entries, nil
+}
+
+// JSON可以是本程式匯出的 Record 陣列，或VNDB ulist(外層可包在 results 或 vns 裡)
+func parseJSON(data []byte) ([]Entry, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err != nil {
+		var wrapper map[string]json.RawMessage
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, kurohelpererrors.ErrImportUnsupportedFormat
+		}
+		found := false
+		for _, key := range []string{"games", "results", "vns"} {
+			if raw, ok := wrapper[key]; ok {
+				if err := json.Unmarshal(raw, &list); err != nil {
+					return nil, kurohelpererrors.ErrImportUnsupportedFormat
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, kurohelpererrors.ErrImportUnsupportedFormat
+		}
+	}
+	if len(list) == 0 {
+		return []Entry{}, nil
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(list[0], &probe); err != nil {
+		return nil, kurohelpererrors.ErrImportUnsupportedFormat
+	}
+	if _, ok := probe["game_id"]; ok {
+		entries := make([]Entry, 0, len(list))
+		for _, raw := range list {
+			var r Record
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return nil, kurohelpererrors.ErrImportUnsupportedFormat
+			}
+			if r.GameID <= 0 && r.VndbID == "" {
+				continue
+			}
+			entries = append(entries, r.Entry())
+		}
+		return entries, nil
+	}
+	return parseVndbJSON(list)
+}
+
+func appendTitles(dst []string, titles ...string) []string {
+	for _, t := range titles {
+		if t = strings.TrimSpace(t); t != "" {
+			dst = append(dst, t)
+		}
+	}
+	return dst
+}
+
+// VNDB ID統一成 v+數字，格式不對時回傳空字串
+func normalizeVndbID(id string) string {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id == "" {
+		return ""
+	}
+	if !strings.HasPrefix(id, "v") {
+		id = "v" + id
+	}
+	if _, err := strconv.Atoi(id[1:]); err != nil {
+		return ""
+	}
+	return id
+}
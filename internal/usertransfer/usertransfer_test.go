@@ -0,0 +1,159 @@
+package usertransfer
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	kurohelperdb "kurohelperservice/db"
+)
+
+// 匯出後再匯入，Entry 的內容要和原本的紀錄一致
+func TestExportRoundTrip(t *testing.T) {
+	records := []Record{
+		{
+			GameID:    27263,
+			Title:     "Summer Pockets",
+			Brand:     "Key",
+			Status:    "finished",
+			Rating:    92,
+			PlayHours: 35.5,
+			Started:   "2024-01-02",
+			Finished:  "2024-02-10",
+			Notes:     "結局是 ||しろは的夏天||，\"真的\"很感人\n第二行",
+		},
+		{
+			GameID: 4013,
+			Title:  "CLANNAD",
+			Wish:   true,
+		},
+		{
+			GameID:    9990,
+			Title:     "リトルバスターズ！",
+			Status:    "dropped",
+			BlackList: true,
+			Started:   "2023-12-31",
+		},
+		{
+			GameID:    18765,
+			VndbID:    "v4",
+			Title:     "Rewrite",
+			Status:    "playing",
+			PlayHours: 3,
+			Started:   "2024-03-01",
+		},
+	}
+	want := []Entry{
+		{
+			ErogsID:   27263,
+			Titles:    []string{"Summer Pockets"},
+			Status:    kurohelperdb.UserGameStatusFinished,
+			Rating:    92,
+			PlayHours: 35.5,
+			Started:   ParseDate("2024-01-02"),
+			Finished:  ParseDate("2024-02-10"),
+			Notes:     "結局是 ||しろは的夏天||，\"真的\"很感人\n第二行",
+		},
+		{
+			ErogsID: 4013,
+			Titles:  []string{"CLANNAD"},
+			Wish:    true,
+		},
+		{
+			ErogsID:   9990,
+			Titles:    []string{"リトルバスターズ！"},
+			Status:    kurohelperdb.UserGameStatusDropped,
+			BlackList: true,
+			Started:   ParseDate("2023-12-31"),
+		},
+		{
+			ErogsID:   18765,
+			VndbID:    "v4",
+			Titles:    []string{"Rewrite"},
+			Status:    kurohelperdb.UserGameStatusPlaying,
+			PlayHours: 3,
+			Started:   ParseDate("2024-03-01"),
+		},
+	}
+
+	formats := []struct {
+		name     string
+		filename string
+		write    func(io.Writer, []Record) error
+	}{
+		{name: "json", filename: "export.json", write: WriteJSON},
+		{name: "csv", filename: "export.csv", write: WriteCSV},
+		// 沒有副檔名時依內容判斷
+		{name: "json without extension", filename: "export", write: WriteJSON},
+		{name: "csv without extension", filename: "export", write: WriteCSV},
+	}
+
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			got := roundTrip(t, f.filename, f.write, records)
+			if len(got) != len(want) {
+				t.Fatalf("got %d entries, want %d", len(got), len(want))
+			}
+			for idx := range want {
+				if !reflect.DeepEqual(entryFields(got[idx]), entryFields(want[idx])) {
+					t.Errorf("entry %d:\ngot  %+v\nwant %+v", idx, entryFields(got[idx]), entryFields(want[idx]))
+				}
+			}
+		})
+
+		// 沒有任何資料的匯出檔案也要能匯入(結果是空的)
+		t.Run(f.name+" empty", func(t *testing.T) {
+			for _, records := range [][]Record{nil, {}} {
+				if got := roundTrip(t, f.filename, f.write, records); len(got) != 0 {
+					t.Errorf("got %d entries, want 0", len(got))
+				}
+			}
+		})
+	}
+}
+
+func TestParseUnsupportedFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		data     string
+	}{
+		{name: "json without games", filename: "a.json", data: `{"exported_at":"2024-01-01T00:00:00Z"}`},
+		{name: "csv without id column", filename: "a.csv", data: "title,status\nCLANNAD,finished\n"},
+		{name: "empty csv", filename: "a.csv", data: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.filename, []byte(tt.data)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func roundTrip(t *testing.T, filename string, write func(io.Writer, []Record) error, records []Record) []Entry {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := write(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Parse(filename, buf.Bytes())
+	if err != nil {
+		t.Fatalf("parse: %v\n%s", err, buf.String())
+	}
+	return entries
+}
+
+// 日期換成字串再比較，避免時區內部表示不同
+type comparableEntry struct {
+	Entry
+	Started, Finished string
+}
+
+func entryFields(e Entry) comparableEntry {
+	c := comparableEntry{Entry: e, Started: FormatDate(e.Started), Finished: FormatDate(e.Finished)}
+	c.Entry.Started, c.Entry.Finished = nil, nil
+	return c
+}
@@ -0,0 +1,138 @@
+package usertransfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"slices"
+	"strconv"
+	"strings"
+
+	kurohelpererrors "kurohelper/internal/errors"
+	"kurohelper/internal/provider"
+	kurohelperdb "kurohelperservice/db"
+)
+
+// VNDB預設標籤ID，7(Voted)與自訂標籤不使用
+const (
+	vndbLabelPlaying   = 1
+	vndbLabelFinished  = 2
+	vndbLabelStalled   = 3
+	vndbLabelDropped   = 4
+	vndbLabelWishlist  = 5
+	vndbLabelBlacklist = 6
+)
+
+// 同時有多個狀態標籤時的優先順序
+var vndbStatusLabels = []struct {
+	id     int
+	status kurohelperdb.UserGameStatus
+}{
+	{vndbLabelFinished, kurohelperdb.UserGameStatusFinished},
+	{vndbLabelPlaying, kurohelperdb.UserGameStatusPlaying},
+	{vndbLabelStalled, kurohelperdb.UserGameStatusStalled},
+	{vndbLabelDropped, kurohelperdb.UserGameStatusDropped},
+}
+
+// 把VNDB ulist轉成 Entry，沒有任何狀態標籤的項目略過
+func FromVndbUList(items []provider.VndbUListItem) []Entry {
+	entries := make([]Entry, 0, len(items))
+	for _, item := range items {
+		labels := make([]int, 0, len(item.Labels))
+		for _, l := range item.Labels {
+			labels = append(labels, l.ID)
+		}
+		e := vndbEntry(item.ID, []string{item.VN.Alttitle, item.VN.Title}, labels, item.Vote, item.Started, item.Finished, item.Notes)
+		if e.VndbID == "" || !e.HasMark() {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func vndbEntry(id string, titles []string, labels []int, vote int, started, finished, notes string) Entry {
+	e := Entry{
+		VndbID:   normalizeVndbID(id),
+		Started:  ParseDate(started),
+		Finished: ParseDate(finished),
+		Notes:    notes,
+		// ulist的投票是10~100，與評分相同
+		Rating: vote,
+		Titles: appendTitles(nil, titles...),
+	}
+	for _, sl := range vndbStatusLabels {
+		if slices.Contains(labels, sl.id) {
+			e.Status = sl.status
+			break
+		}
+	}
+	e.Wish = slices.Contains(labels, vndbLabelWishlist)
+	e.BlackList = slices.Contains(labels, vndbLabelBlacklist)
+	e.sanitize()
+	return e
+}
+
+func parseVndbJSON(list []json.RawMessage) ([]Entry, error) {
+	items := make([]provider.VndbUListItem, 0, len(list))
+	for _, raw := range list {
+		var item provider.VndbUListItem
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, kurohelpererrors.ErrImportUnsupportedFormat
+		}
+		items = append(items, item)
+	}
+	return FromVndbUList(items), nil
+}
+
+// VNDB匯出的XML
+type vndbExport struct {
+	VNs []struct {
+		ID    string `xml:"id,attr"`
+		Title struct {
+			Text     string `xml:",chardata"`
+			Original string `xml:"original,attr"`
+		} `xml:"title"`
+		Labels []struct {
+			ID int `xml:"id,attr"`
+		} `xml:"label"`
+		Vote     string `xml:"vote"`
+		Started  string `xml:"started"`
+		Finished string `xml:"finished"`
+		Notes    string `xml:"notes"`
+	} `xml:"vns>vn"`
+}
+
+func parseVndbXML(data []byte) ([]Entry, error) {
+	var export vndbExport
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&export); err != nil {
+		return nil, kurohelpererrors.ErrImportUnsupportedFormat
+	}
+
+	entries := make([]Entry, 0, len(export.VNs))
+	for _, vn := range export.VNs {
+		labels := make([]int, 0, len(vn.Labels))
+		for _, l := range vn.Labels {
+			labels = append(labels, l.ID)
+		}
+		e := vndbEntry(vn.ID, []string{vn.Title.Original, vn.Title.Text}, labels, parseVndbVote(vn.Vote), vn.Started, vn.Finished, vn.Notes)
+		if e.VndbID == "" || !e.HasMark() {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// XML的投票是1~10(可以有小數)，轉成1~100
+func parseVndbVote(s string) int {
+	s = strings.TrimSpace(s)
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 {
+		return 0
+	}
+	if strings.Contains(s, ".") || v <= 10 {
+		v *= 10
+	}
+	return int(v + 0.5)
+}
@@ -2,9 +2,10 @@ package utils
 
 import (
 	"errors"
-	"fmt"
 	"strconv"
 	"strings"
+
+	"kurohelper/internal/cid"
 )
 
 type (
@@ -78,17 +79,30 @@ type (
 
 const (
 	// PageBehavior Value會是int
-	PageBehavior BehaviorID = "P"
+	PageBehavior BehaviorID = BehaviorID(rune(cid.PageBehavior))
 	// SelectMenuBehavior Value會是string(選擇後從Discord API獲得)
-	SelectMenuBehavior BehaviorID = "S"
+	SelectMenuBehavior BehaviorID = BehaviorID(rune(cid.SelectMenuBehavior))
 	// BackToHomeBehavior 不會有Value
-	BackToHomeBehavior BehaviorID = "H"
+	BackToHomeBehavior BehaviorID = BehaviorID(rune(cid.BackToHomeBehavior))
+
+	DetailBtnBehavior BehaviorID = BehaviorID(rune(cid.DetailBtnBehavior))
 
-	DetailBtnBehavior BehaviorID = "D"
+	SwitchSourceBehavior BehaviorID = BehaviorID(rune(cid.SwitchSourceBehavior))
 
-	SwitchSourceBehavior BehaviorID = "W"
+	UserDataOperationBehavior BehaviorID = BehaviorID(rune(cid.UserDataOperationBehavior))
 
-	UserDataOperationBehavior BehaviorID = "U"
+	// V3Behavior 只帶CacheID，交給 ComponentV3Handler 處理
+	V3Behavior BehaviorID = BehaviorID(rune(cid.V3Behavior))
+)
+
+// 元件(按鈕、選單)的使用權限
+type ComponentAccess int
+
+const (
+	// 只有執行指令的人可以操作(預設)
+	ComponentOwnerOnly ComponentAccess = iota
+	// 任何人都可以操作
+	ComponentShared
 )
 
 var (
@@ -96,7 +110,30 @@ var (
 	ErrCIDV2ParseValueFailed = errors.New("utils: cidv2 parse value failed")
 )
 
-// 將字串嘗試轉型成CIDV2原型格式
+// 將CustomID轉成CIDV2原型格式
+//
+// 支援V4格式，CID_ACCEPT_LEGACY 開啟時也接受舊版冒號分隔的CIDV2
+func ParseCID(target string) (*CIDV2, error) {
+	if cid.IsV4(target) {
+		p, err := cid.DecodeV4(target)
+		if err != nil {
+			return nil, err
+		}
+		return &CIDV2{
+			commandName: p.Command,
+			routeKey:    p.Route,
+			cacheID:     p.CacheID,
+			behaviorID:  BehaviorID(rune(p.Behavior)),
+			value:       p.Value,
+		}, nil
+	}
+	if !cid.AcceptLegacy() {
+		return nil, ErrCIDV2ParseFailed
+	}
+	return ParseCIDV2(target)
+}
+
+// 將舊版冒號分隔的字串嘗試轉型成CIDV2原型格式
 //
 // 檢查CIDV2的格式是否正確
 func ParseCIDV2(target string) (*CIDV2, error) {
@@ -129,6 +166,11 @@ func (c CIDV2) GetRouteKey() string {
 	return c.routeKey
 }
 
+// 從CIDV2獲取cacheID
+func (c CIDV2) GetCacheID() string {
+	return c.cacheID
+}
+
 func (c CIDV2) ToPageCIDV2() (*PageCIDV2, error) {
 	v, err := strconv.Atoi(c.value)
 	if err != nil {
@@ -204,16 +246,19 @@ func (c *CIDV2) ChangeValue(value string) {
 
 /*
  * CID產生相關
+ *
+ * 全部輸出V4格式(見 cid.EncodeV4)，Value可以包含冒號；
+ * 編碼後超過Discord長度上限時回傳 ErrCIDTooLong
  */
 
 // 產生page的CID
 //
 // CID標示符是P
-func MakePageCIDV2(commandName, routeKey string, index int, cacheID string, disable bool) string {
+func MakePageCIDV2(commandName, routeKey string, index int, cacheID string, disable bool) (string, error) {
 	if disable {
-		return fmt.Sprintf("%s:%s:%s:P:-99", commandName, routeKey, cacheID)
+		index = -99
 	}
-	return fmt.Sprintf("%s:%s:%s:P:%d", commandName, routeKey, cacheID, index)
+	return cid.EncodeV4(cid.NewPagePayload(commandName, routeKey, cacheID, index))
 }
 
 // 產生select menu的CID
@@ -221,24 +266,31 @@ func MakePageCIDV2(commandName, routeKey string, index int, cacheID string, disa
 // 產生select menu的CID時不需要先預留Value，Value會在選單選擇時才設定(Discord會自動設定)
 //
 // CID標示符是S
-func MakeSelectMenuCIDV2(commandName, routeKey, cacheID string) string {
-	return fmt.Sprintf("%s:%s:%s:S:", commandName, routeKey, cacheID)
+func MakeSelectMenuCIDV2(commandName, routeKey, cacheID string) (string, error) {
+	return cid.EncodeV4(cid.NewSelectMenuPayload(commandName, routeKey, cacheID))
+}
+
+func MakeDetailBtnCIDV2(commandName, routeKey, cacheID, searchID string) (string, error) {
+	return cid.EncodeV4(cid.NewDetailBtnPayload(commandName, routeKey, cacheID, searchID))
 }
 
-func MakeDetailBtnCIDV2(commandName, routeKey, cacheID, searchID string) string {
-	return fmt.Sprintf("%s:%s:%s:D:%s", commandName, routeKey, cacheID, searchID)
+// 產生切換來源的CID
+//
+// CID標示符是W，Value放另一個資料來源的ID
+func MakeSwitchSourceCIDV2(commandName, routeKey, cacheID, targetID string) (string, error) {
+	return cid.EncodeV4(cid.NewSwitchSourcePayload(commandName, routeKey, cacheID, targetID))
 }
 
 // 產生回到主頁的CID
 //
 // CID標示符是H
-func MakeBackToHomeCIDV2(commandName, routeKey, cacheID string) string {
-	return fmt.Sprintf("%s:%s:%s:H:", commandName, routeKey, cacheID)
+func MakeBackToHomeCIDV2(commandName, routeKey, cacheID string) (string, error) {
+	return cid.EncodeV4(cid.NewBackToHomePayload(commandName, routeKey, cacheID))
 }
 
 // 產生使用者資料操作CID
 //
 // CID標示符是U，Value固定放目標資料ID(例如 gameID)
-func MakeUserDataOperationCIDV2(commandName, cacheID string, targetID int) string {
-	return fmt.Sprintf("%s::%s:U:%d", commandName, cacheID, targetID)
+func MakeUserDataOperationCIDV2(commandName, cacheID string, targetID int) (string, error) {
+	return cid.EncodeV4(cid.NewUserDataOperationPayload(commandName, cacheID, targetID))
 }
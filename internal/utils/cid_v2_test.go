@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"kurohelper/internal/cid"
+	kurohelpererrors "kurohelper/internal/errors"
+)
+
+const testCacheID = "0b6f5c3e-8a51-4f0e-9b8e-2f4a1c7d9e10"
+
+func TestParseCID(t *testing.T) {
+	t.Cleanup(func() { cid.Init(cid.Config{Secret: "test-secret", AcceptLegacy: true}) })
+
+	if err := cid.Init(cid.Config{Secret: "test-secret", AcceptLegacy: true}); err != nil {
+		t.Fatal(err)
+	}
+	pageCID, err := MakePageCIDV2("查詢遊戲", "erogs", 2, testCacheID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opCID, err := MakeUserDataOperationCIDV2("加黑名單", testCacheID, 27263)
+	if err != nil {
+		t.Fatal(err)
+	}
+	detailCID, err := MakeDetailBtnCIDV2("查詢遊戲", "vndb", testCacheID, "a:b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		legacy    bool
+		customID  string
+		wantCmd   string
+		wantRoute string
+		wantBehav BehaviorID
+		wantCache string
+		wantValue string
+		wantErr   error
+	}{
+		{name: "v4 page", legacy: true, customID: pageCID, wantCmd: "查詢遊戲", wantRoute: "erogs", wantBehav: PageBehavior, wantCache: testCacheID, wantValue: "2"},
+		{name: "v4 with legacy off", legacy: false, customID: opCID, wantCmd: "加黑名單", wantBehav: UserDataOperationBehavior, wantCache: testCacheID, wantValue: "27263"},
+		{name: "v4 value with colon", legacy: false, customID: detailCID, wantCmd: "查詢遊戲", wantRoute: "vndb", wantBehav: DetailBtnBehavior, wantCache: testCacheID, wantValue: "a:b"},
+		{name: "v2 with legacy on", legacy: true, customID: "查詢遊戲:erogs:" + testCacheID + ":P:3", wantCmd: "查詢遊戲", wantRoute: "erogs", wantBehav: PageBehavior, wantCache: testCacheID, wantValue: "3"},
+		{name: "v2 with legacy off", legacy: false, customID: "查詢遊戲:erogs:" + testCacheID + ":P:3", wantErr: ErrCIDV2ParseFailed},
+		{name: "v2 wrong parts", legacy: true, customID: "查詢遊戲:erogs:P:3", wantErr: ErrCIDV2ParseFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := cid.Init(cid.Config{Secret: "test-secret", AcceptLegacy: tt.legacy}); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseCID(tt.customID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ParseCID() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.GetCommandName() != tt.wantCmd || got.GetRouteKey() != tt.wantRoute || got.GetBehaviorID() != tt.wantBehav || got.GetCacheID() != tt.wantCache || got.value != tt.wantValue {
+				t.Errorf("ParseCID() = %+v", *got)
+			}
+		})
+	}
+}
+
+// 簽章用的是另一把金鑰時回傳 ErrCIDInvalidSignature
+func TestParseCIDInvalidSignature(t *testing.T) {
+	t.Cleanup(func() { cid.Init(cid.Config{Secret: "test-secret", AcceptLegacy: true}) })
+
+	if err := cid.Init(cid.Config{Secret: "test-secret", AcceptLegacy: true}); err != nil {
+		t.Fatal(err)
+	}
+	customID, err := MakeBackToHomeCIDV2("查詢遊戲", "erogs", testCacheID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cid.Init(cid.Config{Secret: "another-secret", AcceptLegacy: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseCID(customID); !errors.Is(err, kurohelpererrors.ErrCIDInvalidSignature) {
+		t.Errorf("ParseCID() error = %v, want ErrCIDInvalidSignature", err)
+	}
+}
@@ -16,7 +16,12 @@ var (
 	ErrMakeChangePageComponentIndexZero = errors.New("utils: make change page component page index parameters can not be zero")
 )
 
-func MakeSelectMenuComponent(gameData []SelectMenuItem, commandName, routeKey, cacheID, placeholder string) *discordgo.ActionsRow {
+func MakeSelectMenuComponent(gameData []SelectMenuItem, commandName, routeKey, cacheID, placeholder string) (*discordgo.ActionsRow, error) {
+	customID, err := MakeSelectMenuCIDV2(commandName, routeKey, cacheID)
+	if err != nil {
+		return nil, err
+	}
+
 	menuOptions := []discordgo.SelectMenuOption{}
 
 	for _, gd := range gameData {
@@ -29,25 +34,42 @@ func MakeSelectMenuComponent(gameData []SelectMenuItem, commandName, routeKey, c
 	return &discordgo.ActionsRow{
 		Components: []discordgo.MessageComponent{
 			discordgo.SelectMenu{
-				CustomID:    MakeSelectMenuCIDV2(commandName, routeKey, cacheID),
+				CustomID:    customID,
 				Placeholder: placeholder,
 				Options:     menuOptions,
 			},
 		},
-	}
+	}, nil
 }
 
 // 製作回到主頁的Component
-func MakeBackToHomeComponent(commandName, routeKey, cacheID string) *discordgo.ActionsRow {
+func MakeBackToHomeComponent(commandName, routeKey, cacheID string) (*discordgo.ActionsRow, error) {
+	customID, err := MakeBackToHomeCIDV2(commandName, routeKey, cacheID)
+	if err != nil {
+		return nil, err
+	}
 	return &discordgo.ActionsRow{
 		Components: []discordgo.MessageComponent{
 			discordgo.Button{
 				Label:    "🏠回到主頁",
 				Style:    discordgo.PrimaryButton,
-				CustomID: MakeBackToHomeCIDV2(commandName, routeKey, cacheID),
+				CustomID: customID,
 			},
 		},
+	}, nil
+}
+
+// 製作切換資料來源的按鈕，targetID為另一個資料來源的ID
+func MakeSwitchSourceButton(label, commandName, routeKey, cacheID, targetID string) (discordgo.Button, error) {
+	customID, err := MakeSwitchSourceCIDV2(commandName, routeKey, cacheID, targetID)
+	if err != nil {
+		return discordgo.Button{}, err
 	}
+	return discordgo.Button{
+		Label:    label,
+		Style:    discordgo.SecondaryButton,
+		CustomID: customID,
+	}, nil
 }
 
 // 製作翻頁Component
@@ -56,12 +78,29 @@ func MakeChangePageComponent(commandName, routeKey string, currentPage int, tota
 		return nil, ErrMakeChangePageComponentIndexZero
 	}
 
+	tabCID, err := MakePageCIDV2(commandName, routeKey, 0, cacheID, true)
+	if err != nil {
+		return nil, err
+	}
+	// 翻頁按鈕的CID，key為頁數(-1與99是停用的第一頁與最後一頁)
+	pageCIDs := make(map[int]string, 6)
+	for _, page := range []int{currentPage - 1, currentPage + 1, 1, -1, totalPage, 99} {
+		if _, ok := pageCIDs[page]; ok {
+			continue
+		}
+		customID, err := MakePageCIDV2(commandName, routeKey, page, cacheID, false)
+		if err != nil {
+			return nil, err
+		}
+		pageCIDs[page] = customID
+	}
+
 	// 中間的顯示頁數按鈕(不可點擊)
 	tabButton := discordgo.Button{
 		Label:    fmt.Sprintf("%d/%d", currentPage, totalPage),
 		Style:    discordgo.SecondaryButton,
 		Disabled: true,
-		CustomID: MakePageCIDV2(commandName, routeKey, 0, cacheID, true),
+		CustomID: tabCID,
 	}
 
 	previousDisabled := false
@@ -80,7 +119,7 @@ func MakeChangePageComponent(commandName, routeKey string, currentPage int, tota
 		Label:    "◀️",
 		Style:    discordgo.SecondaryButton,
 		Disabled: previousDisabled,
-		CustomID: MakePageCIDV2(commandName, routeKey, currentPage-1, cacheID, false),
+		CustomID: pageCIDs[currentPage-1],
 	}
 
 	// 下一頁按鈕
@@ -88,7 +127,7 @@ func MakeChangePageComponent(commandName, routeKey string, currentPage int, tota
 		Label:    "▶️",
 		Style:    discordgo.SecondaryButton,
 		Disabled: nextDisabled,
-		CustomID: MakePageCIDV2(commandName, routeKey, currentPage+1, cacheID, false),
+		CustomID: pageCIDs[currentPage+1],
 	}
 
 	if totalPage != 1 {
@@ -96,24 +135,24 @@ func MakeChangePageComponent(commandName, routeKey string, currentPage int, tota
 			Label:    "⏪",
 			Style:    discordgo.SecondaryButton,
 			Disabled: previousDisabled,
-			CustomID: MakePageCIDV2(commandName, routeKey, 1, cacheID, false),
+			CustomID: pageCIDs[1],
 		}
 
 		if firstButton.CustomID == previousButton.CustomID {
 			firstButton.Disabled = true
-			firstButton.CustomID = MakePageCIDV2(commandName, routeKey, -1, cacheID, false)
+			firstButton.CustomID = pageCIDs[-1]
 		}
 
 		lastButton := discordgo.Button{
 			Label:    "⏩",
 			Style:    discordgo.SecondaryButton,
 			Disabled: nextDisabled,
-			CustomID: MakePageCIDV2(commandName, routeKey, totalPage, cacheID, false),
+			CustomID: pageCIDs[totalPage],
 		}
 
 		if lastButton.CustomID == nextButton.CustomID {
 			lastButton.Disabled = true
-			lastButton.CustomID = MakePageCIDV2(commandName, routeKey, 99, cacheID, false)
+			lastButton.CustomID = pageCIDs[99]
 		}
 
 		return &discordgo.ActionsRow{
@@ -12,7 +12,7 @@ import (
 // handle interaction command common respond
 //
 // 這邊用來當作如果嵌入式訊息發送失敗的最後發送手段
-func InteractionRespond(s *discordgo.Session, i *discordgo.InteractionCreate, msg string) {
+func InteractionRespond(s Responder, i *discordgo.InteractionCreate, msg string) {
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -24,7 +24,7 @@ func InteractionRespond(s *discordgo.Session, i *discordgo.InteractionCreate, ms
 // handle interaction command embed respond
 //
 // editFlag參數為有無需要修改因為defer而產生的interaction訊息(機器人正在思考...)
-func InteractionEmbedRespond(s *discordgo.Session, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed, components *discordgo.ActionsRow, editFlag bool) {
+func InteractionEmbedRespond(s Responder, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed, components *discordgo.ActionsRow, editFlag bool) {
 	var comps []discordgo.MessageComponent
 	if components != nil {
 		comps = []discordgo.MessageComponent{*components}
@@ -56,7 +56,7 @@ func InteractionEmbedRespond(s *discordgo.Session, i *discordgo.InteractionCreat
 // 管理員專用版本
 //
 // editFlag參數為有無需要修改因為defer而產生的interaction訊息(機器人正在思考...)
-func InteractionEmbedRespondForSelf(s *discordgo.Session, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed, components *discordgo.ActionsRow, editFlag bool) {
+func InteractionEmbedRespondForSelf(s Responder, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed, components *discordgo.ActionsRow, editFlag bool) {
 	var comps []discordgo.MessageComponent
 	if components != nil {
 		comps = []discordgo.MessageComponent{*components}
@@ -84,7 +84,7 @@ func InteractionEmbedRespondForSelf(s *discordgo.Session, i *discordgo.Interacti
 	}
 }
 
-func EditEmbedRespond(s *discordgo.Session, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed, components *discordgo.ActionsRow) {
+func EditEmbedRespond(s Responder, i *discordgo.InteractionCreate, embed *discordgo.MessageEmbed, components *discordgo.ActionsRow) {
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseDeferredMessageUpdate,
 	})
@@ -119,6 +119,20 @@ func GetOptions(i *discordgo.InteractionCreate, name string) (string, error) {
 	return "", kurohelpererrors.ErrOptionNotFound
 }
 
+// get slash command number/integer options (Discord回傳的數字都是float64)
+func GetNumberOptions(i *discordgo.InteractionCreate, name string) (float64, error) {
+	for _, v := range i.ApplicationCommandData().Options {
+		if v.Name == name {
+			value, ok := v.Value.(float64)
+			if !ok {
+				return 0, kurohelpererrors.ErrOptionTranslateFail
+			}
+			return value, nil
+		}
+	}
+	return 0, kurohelpererrors.ErrOptionNotFound
+}
+
 // Use discordgo.MessageComponent slice to make ActionsRow
 func MakeActionsRow(messageComponent []discordgo.MessageComponent) *discordgo.ActionsRow {
 	if len(messageComponent) != 0 {
@@ -182,7 +196,7 @@ func GetAvatarURL(user *discordgo.User) string {
 //
 
 // Interaction第一次觸發使用
-func InteractionRespondV2(s *discordgo.Session, i *discordgo.InteractionCreate, components []discordgo.MessageComponent) {
+func InteractionRespondV2(s Responder, i *discordgo.InteractionCreate, components []discordgo.MessageComponent) {
 	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -197,7 +211,7 @@ func InteractionRespondV2(s *discordgo.Session, i *discordgo.InteractionCreate,
 }
 
 // Interaction第一次觸發使用(延遲回應狀況)
-func WebhookEditRespond(s *discordgo.Session, i *discordgo.InteractionCreate, components []discordgo.MessageComponent) {
+func WebhookEditRespond(s Responder, i *discordgo.InteractionCreate, components []discordgo.MessageComponent) {
 	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 		Flags:      discordgo.MessageFlagsIsComponentsV2,
 		Components: &components,
@@ -215,7 +229,7 @@ func WebhookEditRespond(s *discordgo.Session, i *discordgo.InteractionCreate, co
 }
 
 // Interaction後續觸發使用(不管有沒有延遲)
-func InteractionRespondEditComplex(s *discordgo.Session, i *discordgo.InteractionCreate, components []discordgo.MessageComponent) {
+func InteractionRespondEditComplex(s Responder, i *discordgo.InteractionCreate, components []discordgo.MessageComponent) {
 	_, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
 		ID:         i.Message.ID,
 		Channel:    i.Message.ChannelID,
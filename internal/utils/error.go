@@ -3,18 +3,28 @@ package utils
 import (
 	"errors"
 	"kurohelperservice"
-	"log/slog"
 
 	"github.com/bwmarrin/discordgo"
 	"gorm.io/gorm"
 
 	kurohelpererror "kurohelper/internal/errors"
+	"kurohelper/internal/metrics"
+	"kurohelper/internal/tracing"
 	kurohelperdb "kurohelperservice/db"
 )
 
+// 記錄錯誤(trace、metrics、log)但不回應，需要自己組回應內容時使用
+func RecordError(err error, i *discordgo.InteractionCreate) {
+	trace := tracing.ForInteraction(i)
+	trace.RecordError(err)
+	class := tracing.ErrorClass(err)
+	metrics.Errors.Inc(class)
+	trace.Logger().Error(err.Error(), "errorClass", class)
+}
+
 // 錯誤統一處理方法
-func HandleError(err error, s *discordgo.Session, i *discordgo.InteractionCreate) {
-	slog.Error(err.Error(), "guildID", i.GuildID)
+func HandleError(err error, s Responder, i *discordgo.InteractionCreate) {
+	RecordError(err, i)
 	switch {
 	case errors.Is(err, kurohelperdb.ErrUniqueViolation):
 		InteractionEmbedRespond(s, i, MakeErrorEmbedMsg("資料已存在，此次操作無效"), nil, true)
@@ -28,8 +38,18 @@ func HandleError(err error, s *discordgo.Session, i *discordgo.InteractionCreate
 		InteractionEmbedRespond(s, i, MakeErrorEmbedMsg("日期格式錯誤，格式為YYYYMMDD"), nil, true)
 	case errors.Is(err, kurohelpererror.ErrDateExceedsTomorrow):
 		InteractionEmbedRespond(s, i, MakeErrorEmbedMsg("日期格式錯誤，完成日期不得超過今日加一天"), nil, true)
+	case errors.Is(err, kurohelpererror.ErrEndDateBeforeStart):
+		InteractionEmbedRespond(s, i, MakeErrorEmbedMsg("日期錯誤，結束日期不得早於開始日期"), nil, true)
 	case errors.Is(err, kurohelpererror.ErrPrivateGameData):
 		InteractionEmbedRespond(s, i, MakeErrorEmbedMsg("該使用者已開啟隱私遊戲資料，無法查看"), nil, true)
+	case errors.Is(err, kurohelpererror.ErrAmbiguousKeyword):
+		InteractionEmbedRespond(s, i, MakeErrorEmbedMsg("符合的遊戲不只一筆，請從自動完成的選項中選擇"), nil, true)
+	case errors.Is(err, kurohelpererror.ErrImportUnsupportedFormat):
+		InteractionEmbedRespond(s, i, MakeErrorEmbedMsg("無法辨識檔案格式，支援VNDB匯出的XML/JSON與CSV"), nil, true)
+	case errors.Is(err, kurohelpererror.ErrImportFileTooLarge):
+		InteractionEmbedRespond(s, i, MakeErrorEmbedMsg("檔案太大，請分批匯入"), nil, true)
+	case errors.Is(err, kurohelpererror.ErrRandomAllBlackListed):
+		InteractionEmbedRespond(s, i, MakeErrorEmbedMsg("連續抽到黑名單中的遊戲，請再試一次"), nil, true)
 	case errors.Is(err, kurohelperservice.ErrBangumiCharacterListSearchNotSupported):
 		InteractionEmbedRespond(s, i, MakeErrorEmbedMsg("目前不支援對Bangumi使用角色列表搜尋"), nil, true)
 	case errors.Is(err, kurohelpererror.ErrCIDGetParameterFailed):
@@ -56,10 +76,10 @@ func HandleError(err error, s *discordgo.Session, i *discordgo.InteractionCreate
 // - WebhookEditRespond
 func HandleErrorV2(
 	err error,
-	s *discordgo.Session,
+	s Responder,
 	i *discordgo.InteractionCreate,
-	responder func(*discordgo.Session, *discordgo.InteractionCreate, []discordgo.MessageComponent)) {
-	slog.Error(err.Error(), "guildID", i.GuildID)
+	responder func(Responder, *discordgo.InteractionCreate, []discordgo.MessageComponent)) {
+	RecordError(err, i)
 
 	errMsg := "該功能目前異常，請稍後再嘗試"
 	switch {
@@ -75,8 +95,18 @@ func HandleErrorV2(
 		errMsg = "日期格式錯誤，格式為YYYYMMDD"
 	case errors.Is(err, kurohelpererror.ErrDateExceedsTomorrow):
 		errMsg = "日期格式錯誤，完成日期不得超過今日加一天"
+	case errors.Is(err, kurohelpererror.ErrEndDateBeforeStart):
+		errMsg = "日期錯誤，結束日期不得早於開始日期"
 	case errors.Is(err, kurohelpererror.ErrPrivateGameData):
 		errMsg = "該使用者已開啟隱私遊戲資料，無法查看"
+	case errors.Is(err, kurohelpererror.ErrAmbiguousKeyword):
+		errMsg = "符合的遊戲不只一筆，請從自動完成的選項中選擇"
+	case errors.Is(err, kurohelpererror.ErrImportUnsupportedFormat):
+		errMsg = "無法辨識檔案格式，支援VNDB匯出的XML/JSON與CSV"
+	case errors.Is(err, kurohelpererror.ErrImportFileTooLarge):
+		errMsg = "檔案太大，請分批匯入"
+	case errors.Is(err, kurohelpererror.ErrRandomAllBlackListed):
+		errMsg = "連續抽到黑名單中的遊戲，請再試一次"
 	case errors.Is(err, kurohelperservice.ErrBangumiCharacterListSearchNotSupported):
 		errMsg = "目前不支援對Bangumi使用角色列表搜尋"
 	case errors.Is(err, kurohelperservice.ErrCacheLost):
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// Responder 指令回應Discord時所需要的最小介面
+//
+// *discordgo.Session 本身就滿足這個介面，測試時可以替換成 discordtest.Recorder，
+// 讓指令不需要真的連上Discord也能執行
+type Responder interface {
+	// 第一次回應Interaction(包含延遲回應)
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	// 修改延遲回應產生的訊息
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	// 修改Component所在的訊息
+	ChannelMessageEditComplex(m *discordgo.MessageEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	// 取得Discord使用者資料(個人資料頭像使用)
+	User(userID string, options ...discordgo.RequestOption) (*discordgo.User, error)
+}
+
+// 確保 *discordgo.Session 有實作 Responder
+var _ Responder = (*discordgo.Session)(nil)
@@ -10,24 +10,25 @@ import (
 	"gorm.io/gorm"
 )
 
-// LoadGameStateMaps 取得使用者的遊玩狀態與願望清單，並以 GameErogsID 建立索引
-func LoadGameStateMaps(discordID string) (statusMap map[int]kurohelperdb.UserGameStatus, inWishMap map[int]struct{}, err error) {
+// LoadGameStateMaps 取得使用者的遊玩狀態、願望清單與黑名單，並以 GameErogsID 建立索引
+func LoadGameStateMaps(discordID string) (statusMap map[int]kurohelperdb.UserGameStatus, inWishMap map[int]struct{}, blackListMap map[int]struct{}, err error) {
 	statusMap = make(map[int]kurohelperdb.UserGameStatus)
 	inWishMap = make(map[int]struct{})
+	blackListMap = make(map[int]struct{})
 
 	if strings.TrimSpace(discordID) == "" {
-		return statusMap, inWishMap, nil
+		return statusMap, inWishMap, blackListMap, nil
 	}
 	if _, ok := store.UserStore[discordID]; !ok {
-		return statusMap, inWishMap, nil
+		return statusMap, inWishMap, blackListMap, nil
 	}
 
 	userGames, err := kurohelperdb.GetUserGameByDiscordID(kurohelperdb.Dbs, discordID)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return statusMap, inWishMap, nil
+		return statusMap, inWishMap, blackListMap, nil
 	}
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	for _, item := range userGames {
@@ -37,14 +38,17 @@ func LoadGameStateMaps(discordID string) (statusMap map[int]kurohelperdb.UserGam
 		if item.WishListMark {
 			inWishMap[item.GameErogsID] = struct{}{}
 		}
+		if item.BlackListMark {
+			blackListMap[item.GameErogsID] = struct{}{}
+		}
 	}
 
-	return statusMap, inWishMap, nil
+	return statusMap, inWishMap, blackListMap, nil
 }
 
-// FormatGameFlags 將遊玩狀態與願望清單轉換成 Discord 顯示圖示
-func FormatGameFlags(status kurohelperdb.UserGameStatus, inWish bool) string {
-	flags := make([]string, 0, 2)
+// FormatGameFlags 將遊玩狀態、願望清單與黑名單轉換成 Discord 顯示圖示
+func FormatGameFlags(status kurohelperdb.UserGameStatus, inWish bool, blackListed bool) string {
+	flags := make([]string, 0, 3)
 	switch status {
 	case kurohelperdb.UserGameStatusFinished:
 		flags = append(flags, "✅")
@@ -58,5 +62,8 @@ func FormatGameFlags(status kurohelperdb.UserGameStatus, inWish bool) string {
 	if inWish {
 		flags = append(flags, "❤️")
 	}
+	if blackListed {
+		flags = append(flags, "🚫")
+	}
 	return strings.Join(flags, " ")
 }